@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mcalhoun/skunk/internal/affected"
+	"github.com/mcalhoun/skunk/internal/git"
+	"github.com/mcalhoun/skunk/internal/logger"
+	tablerender "github.com/mcalhoun/skunk/internal/table-render"
+	"github.com/mcalhoun/skunk/internal/validator"
+	"github.com/spf13/cobra"
+)
+
+// Only declare variables that are specific to this file
+var (
+	affectedBase string
+	affectedHead string
+)
+
+// affectedCmd represents the affected command
+var affectedCmd = &cobra.Command{
+	Use:   "affected",
+	Short: "List the stacks and components changed between two git revisions",
+	Long: `Read the files changed between two revisions of the git repository, merge
+every stack as it is at both revisions, and list the stacks and components
+whose merged contents differ. A change to a catalog file affects every stack
+that imports it or reaches one of its anchors, and only if the merged result
+changes.
+
+The JSON output lists every affected stack with its components, so it can
+drive a CI matrix.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runAffectedCmd(cmd, args)
+	},
+}
+
+// runAffectedCmd is the implementation of the affected command
+func runAffectedCmd(cmd *cobra.Command, args []string) {
+	if affectedBase == "" {
+		logger.Log.Fatalf("Error: --base is required")
+	}
+
+	stacks, err := findAffectedStacks(affectedBase, affectedHead)
+	if err != nil {
+		exitWithDiagnostics(err, "Error finding affected stacks")
+	}
+
+	if jsonOutput {
+		outputAffectedJSON(stacks)
+		return
+	}
+
+	if len(stacks) == 0 {
+		logger.Log.Infof("No stacks changed between %s and %s", affectedBase, affectedHead)
+		return
+	}
+
+	if noColor {
+		printAffectedStandardTable(stacks)
+		return
+	}
+	printAffectedBubblesTable(stacks)
+}
+
+// findAffectedStacks loads every stack at both revisions and returns the ones
+// whose merged documents differ
+func findAffectedStacks(base, head string) ([]affected.Stack, error) {
+	changed, err := git.ChangedFiles(".", base, head)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log.Debugf("%d files changed between %s and %s", len(changed), base, head)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	baseRevision, cleanupBase, err := loadRevision(base)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupBase()
+
+	headRevision, cleanupHead, err := loadRevision(head)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupHead()
+
+	// A broken stack at the head revision would be reported as emptied, so
+	// fail instead. Stacks broken at the base are compared as empty.
+	if diags := headRevision.Repo.ParseDiagnostics; len(diags) > 0 {
+		return nil, diags
+	}
+	if diags := baseRevision.Repo.ParseDiagnostics; len(diags) > 0 {
+		logger.Log.Warnf("%s failed to parse at %s and will be compared as empty", pluralize(len(diags), "stack"), base)
+	}
+
+	return affected.Find(baseRevision, headRevision, changed), nil
+}
+
+// loadRevision exports the stacks and catalog of a revision and loads every
+// stack. Diagnostics are positioned in the working tree paths of the files.
+func loadRevision(ref string) (affected.Revision, func(), error) {
+	tree, cleanup, err := exportRevision(ref)
+	if err != nil {
+		return affected.Revision{}, nil, err
+	}
+
+	opts, err := parseOptions(tree.StacksPath, tree.CatalogDir)
+	if err != nil {
+		cleanup()
+		return affected.Revision{}, nil, err
+	}
+
+	repo, err := validator.Load(tree.StacksPath, tree.CatalogDir, opts)
+	if err != nil {
+		cleanup()
+		return affected.Revision{}, nil, fmt.Errorf("loading stacks at %s: %w", ref, err)
+	}
+
+	for _, diag := range repo.ParseDiagnostics {
+		diag.LoadSnippet()
+		if rel, err := filepath.Rel(tree.Root, diag.File); err == nil && !strings.HasPrefix(rel, "..") {
+			diag.File = rel
+		}
+		diag.Message = fmt.Sprintf("%s (at %s)", diag.Message, ref)
+	}
+
+	return affected.Revision{Root: tree.Root, Repo: repo}, cleanup, nil
+}
+
+// outputAffectedJSON prints the affected stacks as a JSON array
+func outputAffectedJSON(stacks []affected.Stack) {
+	if stacks == nil {
+		stacks = []affected.Stack{}
+	}
+
+	jsonData, err := json.MarshalIndent(stacks, "", "  ")
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonData))
+}
+
+// affectedRows returns a row per affected stack with its status, changed
+// components and the changed files it is built from
+func affectedRows(stacks []affected.Stack) [][]string {
+	rows := make([][]string, 0, len(stacks))
+	for _, stack := range stacks {
+		components := make([]string, 0, len(stack.Components))
+		for _, component := range stack.Components {
+			components = append(components, fmt.Sprintf("%s/%s (%s)", component.Type, component.Name, component.Status))
+		}
+		rows = append(rows, []string{stack.Name, string(stack.Status), strings.Join(components, ", "), strings.Join(stack.Causes, ", ")})
+	}
+	return rows
+}
+
+// printAffectedBubblesTable prints the affected stacks using the tablerender package
+func printAffectedBubblesTable(stacks []affected.Stack) {
+	style := tablerender.DefaultTableStyle()
+	style.Title = fmt.Sprintf("AFFECTED STACKS (%s..%s)", affectedBase, affectedHead)
+	style.FirstColWidth = style.TotalWidth / 4
+
+	table := tablerender.RenderTable([]string{"STACK", "STATUS", "COMPONENTS", "CAUSES"}, affectedRows(stacks), style)
+	fmt.Println(table)
+}
+
+// printAffectedStandardTable prints the affected stacks as a plain text table
+func printAffectedStandardTable(stacks []affected.Stack) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Printf("Affected Stacks (%s..%s)\n", affectedBase, affectedHead)
+	fmt.Println()
+	fmt.Fprintln(w, "Stack\tStatus\tComponents\tCauses")
+	fmt.Fprintln(w, "-----\t------\t----------\t------")
+
+	for _, row := range affectedRows(stacks) {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(affectedCmd)
+
+	// Add flags
+	affectedCmd.Flags().StringVar(&affectedBase, "base", "", "git revision to compare from, such as main or the merge base of a pull request (required)")
+	affectedCmd.Flags().StringVar(&affectedHead, "head", "HEAD", "git revision to compare to")
+	affectedCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON instead of a table")
+	affectedCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/affected"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAffectedStacks(t *testing.T) {
+	stack := func(name, vars string) string {
+		return "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: " + name +
+			"\nspec:\n  components:\n    terraform:\n      vpc:\n        vars:\n" + vars
+	}
+	dir := initGitRepo(t, map[string]string{
+		"stacks/dev.yaml":  stack("dev", "          <<: *vpc-defaults\n"),
+		"stacks/prod.yaml": stack("prod", "          cidr: 10.1.0.0/16\n"),
+		"catalog/vpc.yaml": "vpc-defaults: &vpc-defaults\n  nat_gateway_enabled: true\n",
+	})
+	// Only the catalog used by dev changes, plus a comment in prod
+	commitFiles(t, dir, map[string]string{
+		"stacks/prod.yaml": "# production\n" + stack("prod", "          cidr: 10.1.0.0/16\n"),
+		"catalog/vpc.yaml": "vpc-defaults: &vpc-defaults\n  nat_gateway_enabled: false\n",
+	})
+
+	t.Chdir(dir)
+	useGitRepoConfig(t)
+
+	stacks, err := findAffectedStacks("HEAD~1", "HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, []affected.Stack{
+		{
+			Name:       "dev",
+			File:       "stacks/dev.yaml",
+			Status:     affected.Modified,
+			Components: []affected.Component{{Type: "terraform", Name: "vpc", Status: affected.Modified}},
+			Causes:     []string{"catalog/vpc.yaml (anchor vpc-defaults)"},
+		},
+	}, stacks)
+
+	// Nothing changed between a revision and itself
+	stacks, err = findAffectedStacks("HEAD", "HEAD")
+	assert.NoError(t, err)
+	assert.Empty(t, stacks)
+
+	_, err = findAffectedStacks("missing", "HEAD")
+	assert.Error(t, err)
+}
+
+func TestAffectedRows(t *testing.T) {
+	rows := affectedRows([]affected.Stack{
+		{
+			Name:   "dev",
+			Status: affected.Modified,
+			Components: []affected.Component{
+				{Type: "terraform", Name: "vpc", Status: affected.Modified},
+				{Type: "helm", Name: "nginx", Status: affected.Added},
+			},
+			Causes: []string{"catalog/vpc.yaml (anchor vpc-defaults)", "stacks/dev.yaml"},
+		},
+	})
+	assert.Equal(t, [][]string{
+		{"dev", "modified", "terraform/vpc (modified), helm/nginx (added)", "catalog/vpc.yaml (anchor vpc-defaults), stacks/dev.yaml"},
+	}, rows)
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mcalhoun/skunk/internal/logger"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	describePath string
+)
+
+// describeCmd represents the describe command
+var describeCmd = &cobra.Command{
+	Use:   "describe",
+	Short: "Describe fully resolved resources",
+	Long:  `Prints fully resolved documents for various resources.`,
+}
+
+// describeStackCmd represents the describe stack command
+var describeStackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Print the fully merged stack document",
+	Long: `Print the fully resolved stack document, with every anchor, alias and merge
+applied, as YAML or JSON. The output can be restricted to a single component
+with --component or to any part of the document with --path.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDescribeStackCmd(cmd, args, defaultStackFinder)
+	},
+}
+
+// runDescribeStackCmd is the implementation of the describe stack command logic
+// extracted to a separate function to make it testable with a mock stack finder
+func runDescribeStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
+	if stackName == "" {
+		logger.Log.Fatalf("Error: stack name is required. Use --stackName/-s")
+	}
+
+	if componentName != "" && describePath != "" {
+		logger.Log.Fatalf("Error: --component and --path cannot be used together")
+	}
+
+	// Get stacksPath from config
+	stacksPath := viper.GetString("stacksPath")
+	if stacksPath == "" {
+		logger.Log.Fatalf("Error: stacksPath not defined in config")
+	}
+
+	// Find all stacks
+	stacks, err := finder.FindStacks(stacksPath)
+	if err != nil {
+		logger.Log.Fatalf("Error finding stacks: %v", err)
+	}
+
+	// Check for duplicate stack names
+	exitOnDuplicateStacks(stacks)
+
+	targetStack := findStackByName(stacks, stackName)
+	if targetStack == nil {
+		logger.Log.Fatalf("Error: stack with name '%s' not found", stackName)
+		return
+	}
+
+	document, err := describeStack(targetStack.FilePath, componentName, describePath)
+	if err != nil {
+		exitWithDiagnostics(err, "Error describing stack '%s'", targetStack.Name)
+	}
+
+	output, err := marshalDocument(document, jsonOutput)
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling stack '%s': %v", targetStack.Name, err)
+	}
+
+	fmt.Print(output)
+}
+
+// describeStack returns the merged stack document, or the part of it selected by
+// a component name or a dot-separated path
+func describeStack(filePath, component, path string) (interface{}, error) {
+	catalogDir := catalogDirFromConfig()
+
+	// Get merge settings from config
+	opts, err := parseOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := yamlparser.ParseStackWithOptions(filePath, catalogDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge YAML: %w", err)
+	}
+
+	if component != "" {
+		return findComponent(stack, component)
+	}
+
+	if path != "" {
+		return lookupDocumentPath(stack, path)
+	}
+
+	return stack, nil
+}
+
+// findComponent returns the component with the given name from any component type
+func findComponent(stack map[string]interface{}, component string) (interface{}, error) {
+	componentType, value, err := lookupComponent(stack, component)
+	if err != nil {
+		return nil, fmt.Errorf("%w; use --path spec.components.<type>.%s", err, component)
+	}
+	if componentType == "" {
+		return nil, fmt.Errorf("component '%s' not found", component)
+	}
+	return value, nil
+}
+
+// lookupComponent returns the type and value of the component with the given
+// name from any component type, or an empty type if the stack does not have
+// it. A name defined for several types is an error.
+func lookupComponent(stack map[string]interface{}, component string) (string, interface{}, error) {
+	spec, _ := stack["spec"].(map[string]interface{})
+	componentTypes, _ := spec["components"].(map[string]interface{})
+
+	// Sort component types so an ambiguous name is reported consistently
+	typeNames := make([]string, 0, len(componentTypes))
+	for typeName := range componentTypes {
+		typeNames = append(typeNames, typeName)
+	}
+	sort.Strings(typeNames)
+
+	var foundTypes []string
+	var found interface{}
+	for _, typeName := range typeNames {
+		typeComponents, _ := componentTypes[typeName].(map[string]interface{})
+		if value, ok := typeComponents[component]; ok {
+			foundTypes = append(foundTypes, typeName)
+			found = value
+		}
+	}
+
+	switch len(foundTypes) {
+	case 0:
+		return "", nil, nil
+	case 1:
+		return foundTypes[0], found, nil
+	default:
+		return "", nil, fmt.Errorf("component '%s' is defined for several types (%s)", component, strings.Join(foundTypes, ", "))
+	}
+}
+
+// lookupDocumentPath follows a dot-separated path through maps and lists.
+// List elements are addressed by their numeric index.
+func lookupDocumentPath(document interface{}, path string) (interface{}, error) {
+	current := document
+	walked := make([]string, 0)
+
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid path '%s': empty segment", path)
+		}
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, fmt.Errorf("path '%s' not found", strings.Join(append(walked, segment), "."))
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("path '%s' not found: invalid list index '%s'", strings.Join(append(walked, segment), "."), segment)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path '%s' not found: '%s' is not a mapping or list", strings.Join(append(walked, segment), "."), strings.Join(walked, "."))
+		}
+
+		walked = append(walked, segment)
+	}
+
+	return current, nil
+}
+
+// marshalDocument renders a document as indented JSON or as YAML
+func marshalDocument(document interface{}, asJSON bool) (string, error) {
+	if asJSON {
+		jsonData, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(jsonData) + "\n", nil
+	}
+
+	yamlData, err := yaml.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(yamlData), nil
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+	describeCmd.AddCommand(describeStackCmd)
+
+	// Add flags
+	describeStackCmd.Flags().StringVarP(&stackName, "stackName", "s", "", "stack name (required)")
+	describeStackCmd.Flags().StringVarP(&componentName, "component", "c", "", "only describe the component with this name")
+	describeStackCmd.Flags().StringVar(&describePath, "path", "", "only describe the part of the stack at this dot-separated path (e.g. spec.components.terraform.vpc)")
+	describeStackCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON instead of YAML")
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeStack(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	testFilePath := filepath.Join("testdata", "test_stack.yaml")
+
+	// Whole document
+	document, err := describeStack(testFilePath, "", "")
+	assert.NoError(t, err)
+	stack, ok := document.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "Stack", stack["kind"])
+	assert.Contains(t, stack, "metadata")
+	assert.Contains(t, stack, "spec")
+
+	// Single component
+	document, err = describeStack(testFilePath, "nginx", "")
+	assert.NoError(t, err)
+	component, ok := document.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "web", component["vars"].(map[string]interface{})["namespace"])
+
+	// Path into the document
+	document, err = describeStack(testFilePath, "", "spec.components.terraform.vpc.vars.tags.Name")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-vpc", document)
+
+	document, err = describeStack(testFilePath, "", "metadata.labels")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"env": "test", "region": "us-test-1"}, document)
+
+	// Errors
+	_, err = describeStack(testFilePath, "nonexistent", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "component 'nonexistent' not found")
+
+	_, err = describeStack(testFilePath, "", "spec.missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "path 'spec.missing' not found")
+
+	_, err = describeStack("nonexistent.yaml", "", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to merge YAML")
+}
+
+func TestFindComponentAmbiguous(t *testing.T) {
+	stack := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"components": map[string]interface{}{
+				"terraform": map[string]interface{}{"app": map[string]interface{}{}},
+				"helm":      map[string]interface{}{"app": map[string]interface{}{}},
+			},
+		},
+	}
+
+	_, err := findComponent(stack, "app")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "defined for several types (helm, terraform); use --path")
+
+	// diff stack looks components up the same way, and handles a missing one itself
+	_, _, err = lookupComponent(stack, "app")
+	assert.EqualError(t, err, "component 'app' is defined for several types (helm, terraform)")
+	componentType, _, err := lookupComponent(stack, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, componentType)
+}
+
+func TestLookupDocumentPath(t *testing.T) {
+	document := map[string]interface{}{
+		"list": []interface{}{"a", map[string]interface{}{"key": "b"}},
+		"leaf": "value",
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "map key", path: "leaf", expected: "value"},
+		{name: "list index", path: "list.0", expected: "a"},
+		{name: "map inside list", path: "list.1.key", expected: "b"},
+		{name: "index out of range", path: "list.2", errMsg: "invalid list index '2'"},
+		{name: "non-numeric index", path: "list.x", errMsg: "invalid list index 'x'"},
+		{name: "through a scalar", path: "leaf.x", errMsg: "'leaf' is not a mapping or list"},
+		{name: "empty segment", path: "list..0", errMsg: "empty segment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := lookupDocumentPath(document, tt.path)
+			if tt.errMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestRunDescribeStackCmd(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockFinder := NewMockStackFinder(t)
+	cmd := setupTestCommand()
+
+	// YAML output
+	stackName = "test-stack"
+	componentName = ""
+	describePath = ""
+	jsonOutput = false
+	output := captureOutput(func() {
+		runDescribeStackCmd(cmd, []string{}, mockFinder)
+	})
+	assert.Contains(t, output, "name: test-stack")
+	assert.Contains(t, output, "cidr_block: 10.0.0.0/16")
+	assert.Contains(t, output, "namespace: web")
+
+	// JSON output restricted to a path
+	describePath = "spec.components.terraform.database.vars"
+	jsonOutput = true
+	output = captureOutput(func() {
+		runDescribeStackCmd(cmd, []string{}, mockFinder)
+	})
+	var vars map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(output), &vars))
+	assert.Equal(t, "postgres", vars["engine"])
+	assert.Equal(t, float64(20), vars["storage_gb"])
+
+	// Reset globals
+	describePath = ""
+	jsonOutput = false
+}
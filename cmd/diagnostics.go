@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mcalhoun/skunk/internal/logger"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// Styles used to render diagnostics
+var (
+	diagnosticErrorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	diagnosticWarningStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
+	diagnosticLocationStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	diagnosticSnippetStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// diagnosticsOutput is where diagnostics are written; a variable so tests can capture it
+var diagnosticsOutput io.Writer = os.Stderr
+
+// reportDiagnostics writes diagnostics as JSON if --json is set, or as text
+// with code frames otherwise
+func reportDiagnostics(diags yamlparser.Diagnostics) {
+	if len(diags) == 0 {
+		return
+	}
+
+	if jsonOutput {
+		if err := writeDiagnosticsJSON(diagnosticsOutput, diags); err != nil {
+			logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		return
+	}
+	writeDiagnostics(diagnosticsOutput, diags, !noColor)
+}
+
+// exitWithDiagnostics reports the diagnostics carried by err and exits. Errors
+// without diagnostics are logged with the given message instead.
+func exitWithDiagnostics(err error, format string, args ...interface{}) {
+	var diag *yamlparser.Diagnostic
+	var diags yamlparser.Diagnostics
+	if !errors.As(err, &diag) && !errors.As(err, &diags) {
+		logger.Log.Fatalf("%s: %v", fmt.Sprintf(format, args...), err)
+		return
+	}
+
+	logger.Log.Errorf(format, args...)
+	reportDiagnostics(yamlparser.AsDiagnostics(err, ""))
+	os.Exit(1)
+}
+
+// writeDiagnosticsJSON writes diagnostics as a JSON array
+func writeDiagnosticsJSON(w io.Writer, diags yamlparser.Diagnostics) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // code frames contain ">" markers
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diags)
+}
+
+// writeDiagnostics writes each diagnostic with its location and code frame,
+// followed by a summary line
+func writeDiagnostics(w io.Writer, diags yamlparser.Diagnostics, color bool) {
+	for _, diag := range diags {
+		writeDiagnostic(w, diag, "", color)
+	}
+	fmt.Fprintln(w, diagnosticsSummary(diags))
+}
+
+// writeDiagnostic writes a diagnostic with its location and code frame. A
+// non-empty label, such as the check that reported it, follows the severity.
+func writeDiagnostic(w io.Writer, diag *yamlparser.Diagnostic, label string, color bool) {
+	style := func(s lipgloss.Style, text string) string {
+		if !color {
+			return text
+		}
+		return s.Render(text)
+	}
+
+	severity := string(diag.Severity)
+	if label != "" {
+		severity += "[" + label + "]"
+	}
+	if diag.Severity == yamlparser.SeverityWarning {
+		severity = style(diagnosticWarningStyle, severity)
+	} else {
+		severity = style(diagnosticErrorStyle, severity)
+	}
+	fmt.Fprintf(w, "%s: %s\n", severity, diag.Message)
+
+	location := diag.File
+	if diag.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", diag.File, diag.Line, diag.Column)
+	}
+	if diag.Anchor != "" {
+		location += fmt.Sprintf(" (anchor %q)", diag.Anchor)
+	}
+	fmt.Fprintf(w, "  --> %s\n", style(diagnosticLocationStyle, location))
+
+	if diag.Snippet != "" {
+		for _, line := range strings.Split(strings.TrimRight(diag.Snippet, "\n"), "\n") {
+			fmt.Fprintf(w, "  %s\n", style(diagnosticSnippetStyle, line))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// diagnosticsSummary counts the errors and warnings and the files they are in
+func diagnosticsSummary(diags yamlparser.Diagnostics) string {
+	files := make(map[string]bool)
+	errorCount := 0
+	for _, diag := range diags {
+		files[diag.File] = true
+		if diag.Severity != yamlparser.SeverityWarning {
+			errorCount++
+		}
+	}
+
+	return fmt.Sprintf("%s, %s in %s",
+		pluralize(errorCount, "error"),
+		pluralize(len(diags)-errorCount, "warning"),
+		pluralize(len(files), "file"))
+}
+
+// pluralize formats a count with a noun, adding an "s" unless the count is one
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDiagnostics = yamlparser.Diagnostics{
+	{
+		Severity: yamlparser.SeverityError,
+		File:     "stacks/dev.yaml",
+		Line:     4,
+		Column:   7,
+		Anchor:   "missing",
+		Message:  `could not find alias "missing"`,
+		Snippet:  "> 4 |   <<: *missing\n    |       ^\n",
+	},
+	{
+		Severity: yamlparser.SeverityWarning,
+		File:     "stacks/prod.yaml",
+		Message:  "labels set through anchors or merges are ignored",
+	},
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	var buf bytes.Buffer
+	writeDiagnostics(&buf, testDiagnostics, false)
+
+	expected := `error: could not find alias "missing"
+  --> stacks/dev.yaml:4:7 (anchor "missing")
+  > 4 |   <<: *missing
+      |       ^
+
+warning: labels set through anchors or merges are ignored
+  --> stacks/prod.yaml
+
+1 error, 1 warning in 2 files
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestReportDiagnosticsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	oldOutput := diagnosticsOutput
+	diagnosticsOutput = &buf
+	jsonOutput = true
+	defer func() {
+		diagnosticsOutput = oldOutput
+		jsonOutput = false
+	}()
+
+	reportDiagnostics(testDiagnostics)
+
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 2, len(decoded))
+	assert.Equal(t, "missing", decoded[0]["anchor"])
+	assert.Equal(t, float64(4), decoded[0]["line"])
+	assert.Contains(t, buf.String(), `"> 4 |   <<: *missing`)
+	assert.NotContains(t, decoded[1], "line")
+}
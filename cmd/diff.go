@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mcalhoun/skunk/internal/diff"
+	"github.com/mcalhoun/skunk/internal/logger"
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	tablerender "github.com/mcalhoun/skunk/internal/table-render"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	diffRef string
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare resources",
+	Long:  `Compares the fully merged documents of resources.`,
+}
+
+// diffStackCmd represents the diff stack command
+var diffStackCmd = &cobra.Command{
+	Use:   "stack <stack> [<stack>]",
+	Short: "Compare the merged documents of two stacks",
+	Long: `Compare the fully merged documents of two stacks and print every path whose
+value was added, removed or changed in the second stack. With --component only
+that component is compared.
+
+With --ref a single stack is compared against the same stack file at a git
+revision of the repository, such as main or HEAD~1. The stacks and catalog
+are read as they were at that revision.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDiffStackCmd(cmd, args, defaultStackFinder)
+	},
+}
+
+// runDiffStackCmd is the implementation of the diff stack command logic
+// extracted to a separate function to make it testable with a mock stack finder
+func runDiffStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
+	if diffRef != "" && len(args) != 1 {
+		logger.Log.Fatalf("Error: --ref compares a single stack against a git revision, got %d stacks", len(args))
+	}
+	if diffRef == "" && len(args) != 2 {
+		logger.Log.Fatalf("Error: two stacks are required, or one stack and --ref")
+	}
+
+	// Get stacksPath from config
+	stacksPath := viper.GetString("stacksPath")
+	if stacksPath == "" {
+		logger.Log.Fatalf("Error: stacksPath not defined in config")
+	}
+
+	// Find all stacks
+	stacks, err := finder.FindStacks(stacksPath)
+	if err != nil {
+		logger.Log.Fatalf("Error finding stacks: %v", err)
+	}
+
+	// Check for duplicate stack names
+	exitOnDuplicateStacks(stacks)
+
+	var targets []*stackDocument
+	for _, name := range args {
+		stack := findStackByName(stacks, name)
+		if stack == nil {
+			logger.Log.Fatalf("Error: stack with name '%s' not found", name)
+			return
+		}
+
+		document, err := loadStackDocument(*stack)
+		if err != nil {
+			exitWithDiagnostics(err, "Error loading stack '%s'", name)
+		}
+		targets = append(targets, &stackDocument{
+			Label:    fmt.Sprintf("%s (%s)", stack.Name, stack.FilePath),
+			Document: document,
+		})
+	}
+
+	// Compare the stack at the revision with its current contents
+	if diffRef != "" {
+		document, err := loadStackAtRef(diffRef, findStackByName(stacks, args[0]))
+		if err != nil {
+			exitWithDiagnostics(err, "Error loading stack '%s' at %s", args[0], diffRef)
+		}
+		targets = append([]*stackDocument{{Label: fmt.Sprintf("%s@%s", args[0], diffRef), Document: document}}, targets...)
+	}
+
+	changes, err := diffStacks(targets[0].Document, targets[1].Document, componentName)
+	if err != nil {
+		logger.Log.Fatalf("Error: %v", err)
+	}
+
+	if jsonOutput {
+		outputChangesJSON(changes)
+		return
+	}
+
+	writeChanges(os.Stdout, targets[0].Label, targets[1].Label, changes, !noColor)
+}
+
+// stackDocument is a merged stack document and the label it is printed with
+type stackDocument struct {
+	Label    string
+	Document map[string]interface{}
+}
+
+// loadStackAtRef returns the merged document of a stack as it is at a git
+// revision. The stacks and catalog are read from the revision so that imports
+// and anchors resolve as they did then.
+func loadStackAtRef(ref string, stack *stackfinder.StackMetadata) (map[string]interface{}, error) {
+	stackFile, err := relativeToWorkingDir(stack.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, cleanup, err := exportRevision(ref)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	refStackFile := filepath.Join(tree.Root, stackFile)
+	if _, err := os.Stat(refStackFile); err != nil {
+		return nil, fmt.Errorf("%s does not exist at %s", stack.FilePath, ref)
+	}
+
+	opts, err := parseOptions(tree.StacksPath, tree.CatalogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := yamlparser.ParseStackWithOptions(refStackFile, tree.CatalogDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge YAML: %w", err)
+	}
+	return document, nil
+}
+
+// diffStacks compares two merged stacks, or only the component with the given
+// name if component is set. A component missing from one stack is reported as
+// added or removed as a whole.
+func diffStacks(old, new map[string]interface{}, component string) ([]diff.Change, error) {
+	if component == "" {
+		return diff.Compare(old, new), nil
+	}
+
+	oldType, oldComponent, err := lookupComponent(old, component)
+	if err != nil {
+		return nil, err
+	}
+	newType, newComponent, err := lookupComponent(new, component)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldType == "" && newType == "" {
+		return nil, fmt.Errorf("component '%s' not found in either stack", component)
+	}
+	if oldType != "" && newType != "" && oldType != newType {
+		return nil, fmt.Errorf("component '%s' is a %s component in one stack and a %s component in the other", component, oldType, newType)
+	}
+
+	// Compare the component within its parent so that a missing component is a single change
+	componentType := oldType
+	oldParent := map[string]interface{}{}
+	if oldType != "" {
+		oldParent[component] = oldComponent
+	}
+	newParent := map[string]interface{}{}
+	if newType != "" {
+		newParent[component] = newComponent
+		componentType = newType
+	}
+	return diff.Compare(oldParent, newParent, "spec", "components", componentType), nil
+}
+
+// outputChangesJSON prints changes as a JSON array
+func outputChangesJSON(changes []diff.Change) {
+	if changes == nil {
+		changes = []diff.Change{}
+	}
+
+	jsonData, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonData))
+}
+
+// writeChanges writes changes as one line per path, marked with + for added,
+// - for removed and ~ for changed values, followed by a summary
+func writeChanges(w io.Writer, oldLabel, newLabel string, changes []diff.Change, color bool) {
+	scheme := tablerender.DefaultColorScheme()
+	style := func(c lipgloss.Color, s string) string {
+		if !color {
+			return s
+		}
+		return lipgloss.NewStyle().Foreground(c).Render(s)
+	}
+
+	fmt.Fprintf(w, "%s\n", style(scheme.BoolFalseColor, "--- "+oldLabel))
+	fmt.Fprintf(w, "%s\n\n", style(scheme.BoolTrueColor, "+++ "+newLabel))
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+
+	for _, change := range changes {
+		switch change.Kind {
+		case diff.Added:
+			fmt.Fprintf(w, "%s\n", style(scheme.BoolTrueColor, fmt.Sprintf("+ %s: %s", change.Path, formatDiffValue(change.New))))
+		case diff.Removed:
+			fmt.Fprintf(w, "%s\n", style(scheme.BoolFalseColor, fmt.Sprintf("- %s: %s", change.Path, formatDiffValue(change.Old))))
+		case diff.Changed:
+			fmt.Fprintf(w, "%s %s: %s -> %s\n", style(scheme.NumberColor, "~"), change.Path,
+				style(scheme.BoolFalseColor, formatDiffValue(change.Old)), style(scheme.BoolTrueColor, formatDiffValue(change.New)))
+		}
+	}
+
+	summary := diff.Summary(changes)
+	kinds := []diff.Kind{diff.Added, diff.Removed, diff.Changed}
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", summary[kind], kind))
+	}
+	fmt.Fprintf(w, "\n%s\n", strings.Join(parts, ", "))
+}
+
+// formatDiffValue formats a value as compact JSON, so strings are quoted and
+// can be told apart from numbers and booleans
+func formatDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.AddCommand(diffStackCmd)
+
+	// Add flags
+	diffStackCmd.Flags().StringVarP(&componentName, "component", "c", "", "only compare the component with this name")
+	diffStackCmd.Flags().StringVar(&diffRef, "ref", "", "compare the stack against the same stack file at this git revision")
+	diffStackCmd.Flags().BoolVar(&jsonOutput, "json", false, "output the changes as JSON")
+	diffStackCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/diff"
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffStacks(t *testing.T) {
+	dev := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "dev"},
+		"spec": map[string]interface{}{
+			"components": map[string]interface{}{
+				"terraform": map[string]interface{}{
+					"vpc": map[string]interface{}{"vars": map[string]interface{}{"cidr": "10.0.0.0/16"}},
+				},
+			},
+		},
+	}
+	prod := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "prod"},
+		"spec": map[string]interface{}{
+			"components": map[string]interface{}{
+				"terraform": map[string]interface{}{
+					"vpc": map[string]interface{}{"vars": map[string]interface{}{"cidr": "10.1.0.0/16"}},
+					"dns": map[string]interface{}{"vars": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+
+	changes, err := diffStacks(dev, prod, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []diff.Change{
+		{Path: "metadata.name", Kind: diff.Changed, Old: "dev", New: "prod"},
+		{Path: "spec.components.terraform.dns", Kind: diff.Added, New: map[string]interface{}{"vars": map[string]interface{}{}}},
+		{Path: "spec.components.terraform.vpc.vars.cidr", Kind: diff.Changed, Old: "10.0.0.0/16", New: "10.1.0.0/16"},
+	}, changes)
+
+	// Only the component is compared
+	changes, err = diffStacks(dev, prod, "vpc")
+	assert.NoError(t, err)
+	assert.Equal(t, []diff.Change{
+		{Path: "spec.components.terraform.vpc.vars.cidr", Kind: diff.Changed, Old: "10.0.0.0/16", New: "10.1.0.0/16"},
+	}, changes)
+
+	// A component missing from one stack is removed as a whole
+	changes, err = diffStacks(prod, dev, "dns")
+	assert.NoError(t, err)
+	assert.Equal(t, []diff.Change{
+		{Path: "spec.components.terraform.dns", Kind: diff.Removed, Old: map[string]interface{}{"vars": map[string]interface{}{}}},
+	}, changes)
+
+	_, err = diffStacks(dev, prod, "missing")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found in either stack")
+}
+
+func TestWriteChanges(t *testing.T) {
+	changes := []diff.Change{
+		{Path: "a", Kind: diff.Added, New: map[string]interface{}{"x": 1}},
+		{Path: "b", Kind: diff.Removed, Old: "gone"},
+		{Path: "c", Kind: diff.Changed, Old: true, New: false},
+	}
+
+	var buf bytes.Buffer
+	writeChanges(&buf, "dev", "prod", changes, false)
+	assert.Equal(t, "--- dev\n+++ prod\n\n+ a: {\"x\":1}\n- b: \"gone\"\n~ c: true -> false\n\n1 added, 1 removed, 1 changed\n", buf.String())
+
+	buf.Reset()
+	writeChanges(&buf, "dev", "prod", nil, false)
+	assert.Equal(t, "--- dev\n+++ prod\n\nNo differences\n", buf.String())
+}
+
+func TestGlobBase(t *testing.T) {
+	testCases := map[string]string{
+		"fixtures/stacks/*.yaml":    "fixtures/stacks",
+		"stacks/**/*.yaml":          "stacks",
+		"*.yaml":                    ".",
+		"fixtures/stacks/dev.yaml":  "fixtures/stacks",
+		"fixtures/stacks/{a,b}.yml": "fixtures/stacks",
+	}
+	for pattern, expected := range testCases {
+		assert.Equal(t, filepath.FromSlash(expected), globBase(pattern), pattern)
+	}
+}
+
+func TestLoadStackAtRef(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		"stacks/dev.yaml":  "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: dev\nspec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          <<: *vpc-defaults\n",
+		"catalog/vpc.yaml": "vpc-defaults: &vpc-defaults\n  nat_gateway_enabled: true\n",
+	})
+
+	// Change the catalog after the commit
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "catalog", "vpc.yaml"), []byte("vpc-defaults: &vpc-defaults\n  nat_gateway_enabled: false\n"), 0644))
+
+	t.Chdir(dir)
+	useGitRepoConfig(t)
+
+	stack := &stackfinder.StackMetadata{Name: "dev", FilePath: filepath.Join("stacks", "dev.yaml")}
+	old, err := loadStackAtRef("HEAD", stack)
+	assert.NoError(t, err)
+
+	current, err := describeStack(stack.FilePath, "", "")
+	assert.NoError(t, err)
+
+	changes, err := diffStacks(old, current.(map[string]interface{}), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []diff.Change{
+		{Path: "spec.components.terraform.vpc.vars.nat_gateway_enabled", Kind: diff.Changed, Old: true, New: false},
+	}, changes)
+
+	// A stack added after the revision does not exist at it
+	_, err = loadStackAtRef("HEAD", &stackfinder.StackMetadata{Name: "new", FilePath: filepath.Join("stacks", "new.yaml")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist at HEAD")
+}
+
+// initGitRepo writes files to a new git repository and commits them
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	commitFiles(t, dir, files)
+	return dir
+}
+
+// commitFiles writes files to the git repository in dir and commits every change
+func commitFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "commit")
+}
+
+// runGit runs git in dir and fails the test if it fails
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	command := exec.Command("git", args...)
+	command.Dir = dir
+	output, err := command.CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
+
+// useGitRepoConfig points the config at the stacks and catalog of the
+// repository created by initGitRepo and restores it when the test ends
+func useGitRepoConfig(t *testing.T) {
+	stacksPath, catalogDir := viper.GetString("stacksPath"), viper.GetString("catalogDir")
+	viper.Set("stacksPath", "stacks/*.yaml")
+	viper.Set("catalogDir", "catalog")
+	t.Cleanup(func() {
+		viper.Set("stacksPath", stacksPath)
+		viper.Set("catalogDir", catalogDir)
+	})
+}
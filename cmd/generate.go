@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/renderer"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	generateOutDir string
+	generateFormat string
+	generateCheck  bool
+)
+
+// componentPlaceholder is replaced by the component name in --out-dir
+const componentPlaceholder = "{component}"
+
+// generatedFile is a file written by the generate commands
+type generatedFile struct {
+	Path    string
+	Content []byte
+}
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate files from stacks",
+	Long:  `Generates files used by other tools from the merged contents of stacks.`,
+}
+
+// generateTfvarsCmd represents the generate tfvars command
+var generateTfvarsCmd = &cobra.Command{
+	Use:   "tfvars",
+	Short: "Write tfvars and backend files for the Terraform components of a stack",
+	Long: `Write the vars of every Terraform component in a stack, or of the component
+given with --component, to <stack>-<component>.tfvars.json (or .tfvars with
+--format hcl) in the component's working directory. {component} in --out-dir is
+replaced by the component name.
+
+If the stack has a spec.backend section, a backend.tf.json configuring that
+backend is written next to the vars. A component's backend mapping overrides
+keys of spec.backend.config.
+
+With --check nothing is written, and the command fails if any file is missing
+or out of date.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runGenerateTfvarsCmd(cmd, args, defaultStackFinder)
+	},
+}
+
+// runGenerateTfvarsCmd is the implementation of the generate tfvars command logic
+// extracted to a separate function to make it testable with a mock stack finder
+func runGenerateTfvarsCmd(cmd *cobra.Command, args []string, finder StackFinder) {
+	if stackName == "" {
+		logger.Log.Fatalf("Error: stack name is required. Use --stack/-s")
+	}
+
+	if _, ok := tfvarsFormats[generateFormat]; !ok {
+		logger.Log.Fatalf("Error: invalid --format %q, expected json or hcl", generateFormat)
+	}
+
+	// Get stacksPath from config
+	stacksPath := viper.GetString("stacksPath")
+	if stacksPath == "" {
+		logger.Log.Fatalf("Error: stacksPath not defined in config")
+	}
+
+	// Find all stacks
+	stacks, err := finder.FindStacks(stacksPath)
+	if err != nil {
+		logger.Log.Fatalf("Error finding stacks: %v", err)
+	}
+
+	// Check for duplicate stack names
+	exitOnDuplicateStacks(stacks)
+
+	targetStack := findStackByName(stacks, stackName)
+	if targetStack == nil {
+		logger.Log.Fatalf("Error: stack with name '%s' not found", stackName)
+		return
+	}
+
+	files, err := generateTerraformFiles(targetStack.FilePath, targetStack.Name, componentName, generateOutDir, generateFormat)
+	if err != nil {
+		exitWithDiagnostics(err, "Error generating files for stack '%s'", targetStack.Name)
+	}
+
+	changed, err := writeGeneratedFiles(files, generateCheck)
+	if err != nil {
+		logger.Log.Fatalf("Error writing generated files: %v", err)
+	}
+
+	if generateCheck {
+		update := generateTfvarsCommandLine(cmd, targetStack.Name)
+		for _, path := range changed {
+			logger.Log.Errorf("%s is out of date, run \"%s\" to update it", path, update)
+		}
+		if len(changed) > 0 {
+			os.Exit(1)
+		}
+		logger.Log.Infof("%d generated files are up to date", len(files))
+		return
+	}
+
+	for _, path := range changed {
+		logger.Log.Infof("Wrote %s", path)
+	}
+	if len(changed) == 0 {
+		logger.Log.Infof("%d generated files are up to date", len(files))
+	}
+}
+
+// generateTfvarsCommandLine returns the generate tfvars command that writes the
+// files checked by cmd: the stack and every output flag that was set
+func generateTfvarsCommandLine(cmd *cobra.Command, stack string) string {
+	args := []string{"skunk", "generate", "tfvars", "--stack", shellQuote(stack)}
+	for _, name := range []string{"component", "out-dir", "format"} {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			args = append(args, "--"+name, shellQuote(flag.Value.String()))
+		}
+	}
+	return strings.Join(args, " ")
+}
+
+// shellSafePattern matches arguments that need no quoting in a shell
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9_./{}:=@%+-]+$`)
+
+// shellQuote returns an argument quoted for a POSIX shell if it needs quoting
+func shellQuote(arg string) string {
+	if shellSafePattern.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// generateTerraformFiles builds the vars file of every Terraform component in a
+// stack, or of a single component, and the backend file of each if the stack has
+// a backend. Files are returned sorted by path.
+func generateTerraformFiles(filePath, stackName, component, outDir, format string) ([]generatedFile, error) {
+	catalogDir := catalogDirFromConfig()
+
+	// Get merge settings from config
+	opts, err := parseOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	stack, err := yamlparser.ParseStackWithOptions(filePath, catalogDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge YAML: %w", err)
+	}
+
+	spec, _ := stack["spec"].(map[string]interface{})
+	components, _ := spec["components"].(map[string]interface{})
+	terraform, _ := components["terraform"].(map[string]interface{})
+
+	names := make([]string, 0, len(terraform))
+	for name := range terraform {
+		if component == "" || name == component {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if component != "" {
+			return nil, fmt.Errorf("terraform component '%s' not found", component)
+		}
+		return nil, fmt.Errorf("stack has no terraform components")
+	}
+	sort.Strings(names)
+
+	backend, err := parseBackend(spec["backend"])
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	add := func(path string, content []byte) error {
+		if existing, ok := files[path]; ok && !bytes.Equal(existing, content) {
+			return fmt.Errorf("several components generate different contents for %s; use %s in --out-dir", path, componentPlaceholder)
+		}
+		files[path] = content
+		return nil
+	}
+
+	for _, name := range names {
+		values, _ := terraform[name].(map[string]interface{})
+		dir := strings.ReplaceAll(outDir, componentPlaceholder, name)
+
+		vars, ok := values["vars"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("vars section not found for component '%s'", name)
+		}
+
+		varsFile, content, err := renderTfvars(vars, filepath.Base(filePath), stackName, name, format)
+		if err != nil {
+			return nil, fmt.Errorf("component '%s': %w", name, err)
+		}
+		if err := add(filepath.Join(dir, varsFile), content); err != nil {
+			return nil, err
+		}
+
+		if backend == nil {
+			continue
+		}
+		overrides, ok := values["backend"].(map[string]interface{})
+		if values["backend"] != nil && !ok {
+			return nil, fmt.Errorf("backend of component '%s' must be a mapping", name)
+		}
+		content, err = renderBackend(backend, overrides)
+		if err != nil {
+			return nil, fmt.Errorf("component '%s': %w", name, err)
+		}
+		if err := add(filepath.Join(dir, "backend.tf.json"), content); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]generatedFile, 0, len(files))
+	for path, content := range files {
+		result = append(result, generatedFile{Path: path, Content: content})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// tfvarsFormats maps the values of --format to the terraform renderer writing them
+var tfvarsFormats = map[string]string{
+	"json": "tfvars-json",
+	"hcl":  "tfvars",
+}
+
+// renderTfvars returns the name and contents of the vars file of a component
+func renderTfvars(vars map[string]interface{}, stackFile, stackName, component, format string) (string, []byte, error) {
+	r, err := componentRenderers.Lookup("terraform", tfvarsFormats[format])
+	if err != nil {
+		return "", nil, err
+	}
+
+	c := renderer.Component{Type: "terraform", Name: component, Stack: stackFile, Vars: vars}
+	var buf bytes.Buffer
+	if err := r.Render(&buf, c); err != nil {
+		return "", nil, err
+	}
+	return r.FileName(stackName, c), buf.Bytes(), nil
+}
+
+// stackBackend is the spec.backend section of a stack
+type stackBackend struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// parseBackend reads the spec.backend section of a stack, returning nil if there is none
+func parseBackend(value interface{}) (*stackBackend, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	section, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spec.backend must be a mapping")
+	}
+
+	backendType, _ := section["type"].(string)
+	if backendType == "" {
+		return nil, fmt.Errorf("spec.backend.type is required")
+	}
+
+	config, ok := section["config"].(map[string]interface{})
+	if section["config"] != nil && !ok {
+		return nil, fmt.Errorf("spec.backend.config must be a mapping")
+	}
+	return &stackBackend{Type: backendType, Config: config}, nil
+}
+
+// renderBackend returns the contents of a backend.tf.json for a backend, with
+// the keys of overrides replacing those of its config
+func renderBackend(backend *stackBackend, overrides map[string]interface{}) ([]byte, error) {
+	config := make(map[string]interface{}, len(backend.Config)+len(overrides))
+	for key, value := range backend.Config {
+		config[key] = value
+	}
+	for key, value := range overrides {
+		config[key] = value
+	}
+
+	return marshalGeneratedJSON(map[string]interface{}{
+		"terraform": map[string]interface{}{
+			"backend": map[string]interface{}{
+				backend.Type: config,
+			},
+		},
+	})
+}
+
+// marshalGeneratedJSON renders a value as indented JSON ending with a newline
+func marshalGeneratedJSON(value interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeGeneratedFiles writes every file whose contents changed and returns their
+// paths. With check set nothing is written, and the paths of the files that are
+// missing or out of date are returned.
+func writeGeneratedFiles(files []generatedFile, check bool) ([]string, error) {
+	var changed []string
+	for _, file := range files {
+		existing, err := os.ReadFile(file.Path)
+		if err == nil && bytes.Equal(existing, file.Content) {
+			logger.Log.Debugf("%s is up to date", file.Path)
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return changed, err
+		}
+
+		changed = append(changed, file.Path)
+		if check {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+			return changed, err
+		}
+		if err := os.WriteFile(file.Path, file.Content, 0644); err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.AddCommand(generateTfvarsCmd)
+
+	// Add flags
+	addGenerateTfvarsFlags(generateTfvarsCmd)
+}
+
+// addGenerateTfvarsFlags registers the flags of the generate tfvars command
+func addGenerateTfvarsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&stackName, "stack", "s", "", "stack name (required)")
+	cmd.Flags().StringVarP(&componentName, "component", "c", "", "only generate files for the Terraform component with this name")
+	cmd.Flags().StringVar(&generateOutDir, "out-dir", "components/terraform/"+componentPlaceholder, "directory to write each component's files to; "+componentPlaceholder+" is replaced by the component name")
+	cmd.Flags().StringVar(&generateFormat, "format", "json", "format of the vars files: json (.tfvars.json) or hcl (.tfvars)")
+	cmd.Flags().BoolVar(&generateCheck, "check", false, "write nothing and fail if any generated file is missing or out of date")
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const generateTestStack = `apiVersion: skunk.mattcalhoun.com/v1
+kind: Stack
+metadata:
+  name: dev
+spec:
+  backend:
+    type: s3
+    config:
+      bucket: tfstate
+      key: default.tfstate
+  components:
+    terraform:
+      vpc:
+        backend:
+          key: vpc.tfstate
+        vars:
+          cidr: 10.0.0.0/16
+          tags:
+            Name: vpc
+      dns:
+        vars:
+          zone: example.com
+    helm:
+      nginx:
+        vars:
+          replicas: 3
+`
+
+// writeGenerateTestStack writes a stack to a temporary directory and points the
+// catalog at it, returning the stack file and the output directory
+func writeGenerateTestStack(t *testing.T, content string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	catalogDir := viper.GetString("catalogDir")
+	viper.Set("catalogDir", dir)
+	t.Cleanup(func() { viper.Set("catalogDir", catalogDir) })
+
+	stackFile := filepath.Join(dir, "dev.yaml")
+	assert.NoError(t, os.WriteFile(stackFile, []byte(content), 0644))
+	return stackFile, filepath.Join(dir, "components", componentPlaceholder)
+}
+
+func TestGenerateTerraformFiles(t *testing.T) {
+	stackFile, outDir := writeGenerateTestStack(t, generateTestStack)
+	dir := filepath.Dir(stackFile)
+
+	files, err := generateTerraformFiles(stackFile, "dev", "", outDir, "json")
+	assert.NoError(t, err)
+
+	contents := make(map[string]string)
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file.Path)
+		contents[rel] = string(file.Content)
+	}
+	assert.Equal(t, map[string]string{
+		"components/dns/backend.tf.json":     "{\n  \"terraform\": {\n    \"backend\": {\n      \"s3\": {\n        \"bucket\": \"tfstate\",\n        \"key\": \"default.tfstate\"\n      }\n    }\n  }\n}\n",
+		"components/dns/dev-dns.tfvars.json": "{\n  \"zone\": \"example.com\"\n}\n",
+		"components/vpc/backend.tf.json":     "{\n  \"terraform\": {\n    \"backend\": {\n      \"s3\": {\n        \"bucket\": \"tfstate\",\n        \"key\": \"vpc.tfstate\"\n      }\n    }\n  }\n}\n",
+		"components/vpc/dev-vpc.tfvars.json": "{\n  \"cidr\": \"10.0.0.0/16\",\n  \"tags\": {\n    \"Name\": \"vpc\"\n  }\n}\n",
+	}, contents)
+
+	// A single component in HCL
+	files, err = generateTerraformFiles(stackFile, "dev", "vpc", outDir, "hcl")
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(dir, "components", "vpc", "dev-vpc.tfvars"), files[1].Path)
+	assert.Contains(t, string(files[1].Content), "cidr = \"10.0.0.0/16\"\ntags = {\n  Name = \"vpc\"\n}\n")
+
+	// Components sharing a directory must not generate different backends
+	_, err = generateTerraformFiles(stackFile, "dev", "", dir, "json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "backend.tf.json")
+
+	// Helm components are not Terraform components
+	_, err = generateTerraformFiles(stackFile, "dev", "nginx", outDir, "json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "terraform component 'nginx' not found")
+}
+
+func TestGenerateTerraformFilesWithoutBackend(t *testing.T) {
+	stackFile, outDir := writeGenerateTestStack(t, "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: dev\nspec:\n  components:\n    terraform:\n      vpc:\n        vars: {}\n")
+
+	files, err := generateTerraformFiles(stackFile, "dev", "", outDir, "json")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "{}\n", string(files[0].Content))
+}
+
+func TestGenerateTerraformFilesInvalidBackend(t *testing.T) {
+	stackFile, outDir := writeGenerateTestStack(t, "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: dev\nspec:\n  backend:\n    config: {}\n  components:\n    terraform:\n      vpc:\n        vars: {}\n")
+
+	_, err := generateTerraformFiles(stackFile, "dev", "", outDir, "json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.backend.type is required")
+}
+
+func TestWriteGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []generatedFile{
+		{Path: filepath.Join(dir, "vpc", "dev-vpc.tfvars.json"), Content: []byte("{}\n")},
+		{Path: filepath.Join(dir, "vpc", "backend.tf.json"), Content: []byte("{\"terraform\": {}}\n")},
+	}
+
+	// Missing files are stale, and checking writes nothing
+	changed, err := writeGeneratedFiles(files, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{files[0].Path, files[1].Path}, changed)
+	assert.NoDirExists(t, filepath.Join(dir, "vpc"))
+
+	changed, err = writeGeneratedFiles(files, false)
+	assert.NoError(t, err)
+	assert.Len(t, changed, 2)
+	data, err := os.ReadFile(files[0].Path)
+	assert.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	// Written files are up to date
+	changed, err = writeGeneratedFiles(files, true)
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// An edited file is stale
+	assert.NoError(t, os.WriteFile(files[1].Path, []byte("{}\n"), 0644))
+	changed, err = writeGeneratedFiles(files, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{files[1].Path}, changed)
+}
+
+func TestGenerateTfvarsCommandLine(t *testing.T) {
+	cmd := &cobra.Command{}
+	addGenerateTfvarsFlags(cmd)
+	defer func() {
+		stackName, componentName, generateOutDir, generateFormat, generateCheck = "", "", "", "", false
+	}()
+
+	// Only the stack is given if no output flags are set
+	assert.NoError(t, cmd.Flags().Set("stack", "dev"))
+	assert.NoError(t, cmd.Flags().Set("check", "true"))
+	assert.Equal(t, "skunk generate tfvars --stack dev", generateTfvarsCommandLine(cmd, "dev"))
+
+	// The output flags are kept, and quoted if needed
+	assert.NoError(t, cmd.Flags().Set("component", "vpc"))
+	assert.NoError(t, cmd.Flags().Set("out-dir", "my modules/{component}"))
+	assert.NoError(t, cmd.Flags().Set("format", "hcl"))
+	assert.Equal(t, "skunk generate tfvars --stack dev --component vpc --out-dir 'my modules/{component}' --format hcl", generateTfvarsCommandLine(cmd, "dev"))
+}
@@ -5,28 +5,32 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"text/tabwriter"
 
 	"github.com/mcalhoun/skunk/internal/logger"
 	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
 	tablerender "github.com/mcalhoun/skunk/internal/table-render"
 	"github.com/mcalhoun/skunk/internal/utils"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
 	// Command flags
-	jsonOutput bool
-	noColor    bool
+	jsonOutput     bool
+	noColor        bool
+	duplicatesOnly bool
 )
 
+// maxAnchorPreview is the longest anchor value preview shown in tables
+const maxAnchorPreview = 60
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List resources",
-	Long:  `Lists various resources such as stacks.`,
+	Long:  `Lists various resources such as stacks and catalog anchors.`,
 }
 
 // listStacksCmd represents the list stacks command
@@ -36,10 +40,7 @@ var listStacksCmd = &cobra.Command{
 	Long:  `List all stacks that match the configured stacksPath glob pattern.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Get filters from flags
-		filters, err := cmd.Flags().GetStringArray("filter")
-		if err != nil {
-			logger.Log.Fatalf("Error getting filters: %v", err)
-		}
+		filter := stackFilter(cmd)
 
 		// Get stacksPath from config
 		stacksPath := viper.GetString("stacksPath")
@@ -48,7 +49,7 @@ var listStacksCmd = &cobra.Command{
 		}
 
 		// Find stacks
-		stacks, err := stackfinder.FindStacks(stacksPath)
+		stacks, err := defaultStackFinder.FindStacks(stacksPath)
 		if err != nil {
 			logger.Log.Fatalf("Error finding stacks: %v", err)
 		}
@@ -58,23 +59,15 @@ var listStacksCmd = &cobra.Command{
 			return
 		}
 
-		// find duplicate stacks
-		duplicates := utils.FindDuplicateStacks(stacks)
-		if len(duplicates) > 0 {
-			for stackName, stackFiles := range duplicates {
-				filesWithBrackets := "[" + strings.Join(stackFiles, ", ") + "]"
-				logger.Log.Error("duplicate stack detected",
-					"stack", stackName,
-					"error", "Stacks must have unique names",
-					"files_count", len(stackFiles),
-					"files", filesWithBrackets)
-			}
-			os.Exit(1)
-		}
+		// Check for duplicate stack names
+		exitOnDuplicateStacks(stacks)
 
 		// Apply filters if any are specified
-		if len(filters) > 0 {
-			stacks = utils.FilterStacks(stacks, filters)
+		if filter != nil {
+			stacks, err = utils.SelectStacks(stacks, filter, loadStackDocument)
+			if err != nil {
+				exitWithDiagnostics(err, "Error filtering stacks")
+			}
 			if len(stacks) == 0 {
 				logger.Log.Info("No stacks match the specified filters")
 				return
@@ -98,6 +91,70 @@ var listStacksCmd = &cobra.Command{
 	},
 }
 
+// listAnchorsCmd represents the list anchors command
+var listAnchorsCmd = &cobra.Command{
+	Use:   "anchors",
+	Short: "List all catalog anchors",
+	Long: `List every anchor defined in the configured catalogDir with the file and line
+it is defined at and a preview of its value. Anchors defined more than once are
+reported as warnings.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get catalogDir from config
+		catalogDir := viper.GetString("catalogDir")
+		if catalogDir == "" {
+			logger.Log.Fatalf("Error: catalogDir not defined in config")
+		}
+
+		anchors, err := loadCatalogAnchors(catalogDir)
+		if err != nil {
+			exitWithDiagnostics(err, "Error loading catalog")
+		}
+		if duplicatesOnly {
+			anchors = duplicateAnchors(anchors)
+		}
+
+		if len(anchors) == 0 {
+			logger.Log.Infof("No anchors found in catalog: %s", catalogDir)
+			return
+		}
+
+		// If JSON output is requested, print as JSON and exit
+		if jsonOutput {
+			outputAnchorsJSON(anchors)
+			return
+		}
+
+		// If no-color is specified, use the plain table format
+		if noColor {
+			printAnchorsStandardTable(anchors)
+			return
+		}
+
+		// Otherwise, print pretty table output with the tablerender package
+		printAnchorsBubblesTable(anchors)
+	},
+}
+
+// loadCatalogAnchors returns every anchor in catalogDir, resolved with the
+// options stacks are parsed with so that tags such as !stack resolve.
+// Duplicate anchors are only reported as warnings, so they can be inspected.
+func loadCatalogAnchors(catalogDir string) ([]yamlparser.Anchor, error) {
+	catalog, err := yamlparser.LoadCatalogDir(catalogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := catalog.CheckDuplicates(yamlparser.DuplicateAnchorsWarn); err != nil {
+		return nil, err
+	}
+
+	opts, err := parseOptions(viper.GetString("stacksPath"), catalogDir)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Anchors(opts), nil
+}
+
 // outputJSON prints the stacks as JSON
 func outputJSON(stacks []stackfinder.StackMetadata) {
 	type jsonOutput struct {
@@ -182,6 +239,84 @@ func printStandardTable(stacks []stackfinder.StackMetadata) {
 	w.Flush()
 }
 
+// duplicateAnchors returns only the anchors whose name is defined more than once
+func duplicateAnchors(anchors []yamlparser.Anchor) []yamlparser.Anchor {
+	counts := make(map[string]int)
+	for _, anchor := range anchors {
+		counts[anchor.Name]++
+	}
+
+	var duplicates []yamlparser.Anchor
+	for _, anchor := range anchors {
+		if counts[anchor.Name] > 1 {
+			duplicates = append(duplicates, anchor)
+		}
+	}
+	return duplicates
+}
+
+// anchorPreview returns a single-line preview of an anchor value
+func anchorPreview(anchor yamlparser.Anchor) string {
+	if anchor.Error != "" {
+		return "error: " + anchor.Error
+	}
+
+	// Truncate by runes so that multi-byte characters are not cut in half
+	preview := []rune(formatVariableValue(anchor.Value))
+	if len(preview) > maxAnchorPreview {
+		preview = append(preview[:maxAnchorPreview-3], []rune("...")...)
+	}
+	return string(preview)
+}
+
+// anchorSource returns the file and line of an anchor relative to the catalog
+func anchorSource(anchor yamlparser.Anchor) string {
+	return formatSource(yamlparser.Source{File: anchor.File, Line: anchor.Line})
+}
+
+// outputAnchorsJSON prints the anchors as JSON
+func outputAnchorsJSON(anchors []yamlparser.Anchor) {
+	jsonData, err := json.MarshalIndent(anchors, "", "  ")
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonData))
+}
+
+// printAnchorsBubblesTable prints the anchors using the tablerender package
+func printAnchorsBubblesTable(anchors []yamlparser.Anchor) {
+	rows := make([][]string, 0, len(anchors))
+	for _, anchor := range anchors {
+		rows = append(rows, []string{anchor.Name, anchorSource(anchor), anchorPreview(anchor)})
+	}
+
+	// Setup table style
+	style := tablerender.DefaultTableStyle()
+	style.Title = "ANCHORS"
+	style.FirstColWidth = style.TotalWidth / 4
+
+	// Render and print the table
+	table := tablerender.RenderTable([]string{"NAME", "SOURCE", "PREVIEW"}, rows, style)
+	fmt.Println(table)
+}
+
+// printAnchorsStandardTable prints the anchors as a plain text table without any styling
+func printAnchorsStandardTable(anchors []yamlparser.Anchor) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Println("Catalog Anchors")
+	fmt.Println()
+	fmt.Fprintln(w, "Name\tSource\tPreview")
+	fmt.Fprintln(w, "----\t------\t-------")
+
+	for _, anchor := range anchors {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", anchor.Name, anchorSource(anchor), anchorPreview(anchor))
+	}
+
+	w.Flush()
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.AddCommand(listStacksCmd)
@@ -189,5 +324,10 @@ func init() {
 	// Add flags
 	listStacksCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON instead of a table")
 	listStacksCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
-	listStacksCmd.Flags().StringArray("filter", []string{}, "filter stacks by label (format: key=value or key!=value), by name prefix (format: name=pattern or name!=pattern), by regex (format: name~=regex or name!~=regex), or directly by name using wildcard pattern '*' or regex '/pattern/'")
+	addStackFilterFlags(listStacksCmd)
+
+	listCmd.AddCommand(listAnchorsCmd)
+	listAnchorsCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON instead of a table")
+	listAnchorsCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+	listAnchorsCmd.Flags().BoolVar(&duplicatesOnly, "duplicates", false, "only list anchors that are defined more than once")
 }
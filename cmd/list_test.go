@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/mcalhoun/skunk/internal/utils"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateAnchors(t *testing.T) {
+	anchors := []yamlparser.Anchor{
+		{Name: "a", File: "one.yaml"},
+		{Name: "b", File: "one.yaml"},
+		{Name: "b", File: "two.yaml"},
+	}
+
+	duplicates := duplicateAnchors(anchors)
+	assert.Equal(t, 2, len(duplicates))
+	assert.Equal(t, "one.yaml", duplicates[0].File)
+	assert.Equal(t, "two.yaml", duplicates[1].File)
+}
+
+func TestAnchorPreview(t *testing.T) {
+	assert.Equal(t, `{"region":"us-east-1"}`, anchorPreview(yamlparser.Anchor{Value: map[string]interface{}{"region": "us-east-1"}}))
+	assert.Equal(t, "error: boom", anchorPreview(yamlparser.Anchor{Error: "boom"}))
+
+	long := anchorPreview(yamlparser.Anchor{Value: strings.Repeat("x", 100)})
+	assert.Equal(t, maxAnchorPreview, len(long))
+	assert.True(t, strings.HasSuffix(long, "..."))
+
+	multiByte := anchorPreview(yamlparser.Anchor{Value: strings.Repeat("é", 100)})
+	assert.True(t, utf8.ValidString(multiByte))
+	assert.Equal(t, maxAnchorPreview, utf8.RuneCountInString(multiByte))
+	assert.True(t, strings.HasSuffix(multiByte, "..."))
+}
+
+func TestLoadCatalogAnchorsStackTag(t *testing.T) {
+	dir := t.TempDir()
+	catalogDir := filepath.Join(dir, "catalog")
+	assert.NoError(t, os.MkdirAll(catalogDir, 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "stacks"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(catalogDir, "peering.yaml"), []byte("peering: &peering\n  peer: !stack b terraform.vpc.cidr\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "stacks", "b.yaml"), []byte("apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: b\nspec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          cidr: 10.1.0.0/16\n"), 0644))
+
+	stacksPath := viper.GetString("stacksPath")
+	viper.Set("stacksPath", filepath.Join(dir, "stacks", "*.yaml"))
+	defer viper.Set("stacksPath", stacksPath)
+
+	// The !stack tag resolves in previews as it does in stacks
+	anchors, err := loadCatalogAnchors(catalogDir)
+	assert.NoError(t, err)
+	if assert.Len(t, anchors, 1) {
+		assert.Empty(t, anchors[0].Error)
+		assert.Equal(t, `{"peer":"10.1.0.0/16"}`, anchorPreview(anchors[0]))
+	}
+}
+
+func TestParseOptionsFromConfigDuplicateAnchors(t *testing.T) {
+	defer viper.Set("duplicateAnchors", viper.GetString("duplicateAnchors"))
+
+	viper.Set("duplicateAnchors", "warn")
+	opts, err := parseOptionsFromConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, yamlparser.DuplicateAnchorsWarn, opts.DuplicateAnchors)
+
+	viper.Set("duplicateAnchors", "ignore")
+	_, err = parseOptionsFromConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid duplicateAnchors config")
+}
+
+func TestStackFilter(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "plat-dev", Labels: map[string]string{"environment": "dev", "team": "platform"}},
+		{Name: "plat-prod", Labels: map[string]string{"environment": "prod", "team": "platform"}},
+		{Name: "data-prod", Labels: map[string]string{"environment": "prod", "team": "data"}},
+		{Name: "data-dev", Labels: map[string]string{"environment": "dev", "team": "data"}},
+	}
+
+	tests := []struct {
+		name     string
+		filters  []string
+		where    string
+		expected []string
+	}{
+		{
+			name:     "No filters",
+			expected: nil,
+		},
+		{
+			name:     "Where only",
+			where:    "environment=prod || team=platform",
+			expected: []string{"plat-dev", "plat-prod", "data-prod"},
+		},
+		{
+			name:     "Filter and where",
+			filters:  []string{"environment=dev"},
+			where:    "team=platform || name=data-*",
+			expected: []string{"plat-dev", "data-dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addStackFilterFlags(cmd)
+			for _, filter := range tt.filters {
+				assert.NoError(t, cmd.Flags().Set("filter", filter))
+			}
+			assert.NoError(t, cmd.Flags().Set("where", tt.where))
+
+			filter := stackFilter(cmd)
+			if tt.expected == nil {
+				assert.Nil(t, filter)
+				return
+			}
+
+			selected, err := utils.SelectStacks(stacks, filter, nil)
+			assert.NoError(t, err)
+			var names []string
+			for _, stack := range selected {
+				names = append(names, stack.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestLoadStackDocument(t *testing.T) {
+	dir := t.TempDir()
+	catalogDir := viper.GetString("catalogDir")
+	viper.Set("catalogDir", dir)
+	defer viper.Set("catalogDir", catalogDir)
+
+	empty := filepath.Join(dir, "empty.yaml")
+	list := filepath.Join(dir, "list.yaml")
+	assert.NoError(t, os.WriteFile(empty, []byte(""), 0644))
+	assert.NoError(t, os.WriteFile(list, []byte("- a\n- b\n"), 0644))
+
+	// An empty stack is an empty mapping
+	document, err := loadStackDocument(stackfinder.StackMetadata{Name: "empty", FilePath: empty})
+	assert.NoError(t, err)
+	assert.Empty(t, document)
+
+	// The parser rejects a stack that is not a mapping before it is loaded
+	_, err = loadStackDocument(stackfinder.StackMetadata{Name: "list", FilePath: list})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "document root must be a mapping, got Sequence")
+}
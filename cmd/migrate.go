@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/mcalhoun/skunk/internal/apiversion"
+	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	migrateTarget string
+	migrateDryRun bool
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [file...]",
+	Short: "Rewrite stacks to a newer apiVersion",
+	Long: `Rewrite stacks declaring an older apiVersion to the latest one, or to the
+version given with --to, applying each version's migration in turn. Only the
+lines that change are rewritten, so comments, anchors and formatting are kept.
+
+Every stack matching stacksPath is migrated unless files are given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runMigrateCmd(cmd, args, defaultStackFinder)
+	},
+}
+
+// runMigrateCmd is the implementation of the migrate command logic
+// extracted to a separate function to make it testable with a mock stack finder
+func runMigrateCmd(cmd *cobra.Command, args []string, finder StackFinder) {
+	files := args
+	if len(files) == 0 {
+		stacksPath := viper.GetString("stacksPath")
+		if stacksPath == "" {
+			logger.Log.Fatalf("Error: stacksPath not defined in config")
+		}
+
+		stacks, err := finder.FindStacks(stacksPath)
+		if err != nil {
+			logger.Log.Fatalf("Error finding stacks: %v", err)
+		}
+		for _, stack := range stacks {
+			files = append(files, stack.FilePath)
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		from, changed, err := migrateFile(file, migrateTarget, migrateDryRun)
+		switch {
+		case err != nil:
+			logger.Log.Errorf("Error migrating %s: %v", file, err)
+			failed = true
+		case !changed:
+			logger.Log.Debugf("%s is already at %s", file, migrateTarget)
+		case migrateDryRun:
+			logger.Log.Infof("Would migrate %s from %s to %s", file, from, migrateTarget)
+		default:
+			logger.Log.Infof("Migrated %s from %s to %s", file, from, migrateTarget)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// migrateFile rewrites a stack file to the target apiVersion unless dryRun is
+// set. It returns the apiVersion the stack declared and whether it changed.
+func migrateFile(path, target string, dryRun bool) (string, bool, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	from, err := apiversion.Detect(source, path)
+	if err != nil {
+		return "", false, err
+	}
+
+	migrated, err := apiversion.Migrate(source, path, target)
+	if err != nil {
+		return from, false, err
+	}
+	if bytes.Equal(migrated, source) {
+		return from, false, nil
+	}
+	if dryRun {
+		return from, true, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return from, false, err
+	}
+	return from, true, os.WriteFile(path, migrated, info.Mode().Perm())
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	// Add flags
+	migrateCmd.Flags().StringVar(&migrateTarget, "to", apiversion.Latest, "apiVersion to migrate stacks to")
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "only list the stacks that would be migrated")
+}
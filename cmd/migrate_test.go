@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/apiversion"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateFile(t *testing.T) {
+	legacy := "apiVersion: skunk.mattcalhoun.com/v1alpha1\nkind: Stack\nmetadata:\n  name: dev\nspec:\n  terraform: # components\n    vpc:\n      vars: {}\n"
+	path := filepath.Join(t.TempDir(), "dev.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(legacy), 0640))
+
+	// A dry run reports the change without writing it
+	from, changed, err := migrateFile(path, apiversion.Latest, true)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, apiversion.Group+"/v1alpha1", from)
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, legacy, string(data))
+
+	_, changed, err = migrateFile(path, apiversion.Latest, false)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	data, _ = os.ReadFile(path)
+	assert.Equal(t, "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: dev\nspec:\n  components:\n    terraform: # components\n      vpc:\n        vars: {}\n", string(data))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// A migrated stack is left alone
+	from, changed, err = migrateFile(path, apiversion.Latest, false)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, apiversion.Latest, from)
+
+	// Fixture stacks are already at the latest version
+	_, changed, err = migrateFile("../fixtures/stacks/plat-dev-east-1.yaml", apiversion.Latest, true)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/query"
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	tablerender "github.com/mcalhoun/skunk/internal/table-render"
+	"github.com/mcalhoun/skunk/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	csvOutput bool
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query <expression>",
+	Short: "Extract values from the merged documents of stacks",
+	Long: `Evaluate a path expression against the fully merged document of every stack,
+or of the stacks selected by --filter and --where, and print each value with
+the stack it came from.
+
+Expressions are a subset of jq and JSONPath:
+
+  .spec.components.terraform.vpc.vars.ipv4_primary_cidr_block   a value
+  .spec.components.terraform.vpc.vars.availability_zones[0]     a list element
+  .spec.components.terraform.*.vars.enabled                     every component
+  ..nat_gateway_enabled                                         at any depth`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runQueryCmd(cmd, args, defaultStackFinder)
+	},
+}
+
+// queryResult is a value a query selected in a stack
+type queryResult struct {
+	Stack string      `json:"stack"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// runQueryCmd is the implementation of the query command logic
+// extracted to a separate function to make it testable with a mock stack finder
+func runQueryCmd(cmd *cobra.Command, args []string, finder StackFinder) {
+	if jsonOutput && csvOutput {
+		logger.Log.Fatalf("Error: --json and --csv cannot be used together")
+	}
+
+	q, err := query.Parse(args[0])
+	if err != nil {
+		logger.Log.Fatalf("Error: %v", err)
+	}
+
+	// Get filters from flags
+	filter := stackFilter(cmd)
+
+	// Get stacksPath from config
+	stacksPath := viper.GetString("stacksPath")
+	if stacksPath == "" {
+		logger.Log.Fatalf("Error: stacksPath not defined in config")
+	}
+
+	// Find all stacks
+	stacks, err := finder.FindStacks(stacksPath)
+	if err != nil {
+		logger.Log.Fatalf("Error finding stacks: %v", err)
+	}
+
+	// Check for duplicate stack names
+	exitOnDuplicateStacks(stacks)
+
+	// Stacks merged by filters are not merged again for the query
+	loader := cachingDocumentLoader(loadStackDocument)
+
+	// Apply filters if any are specified
+	if filter != nil {
+		stacks, err = utils.SelectStacks(stacks, filter, loader)
+		if err != nil {
+			exitWithDiagnostics(err, "Error filtering stacks")
+		}
+		if len(stacks) == 0 {
+			logger.Log.Info("No stacks match the specified filters")
+			return
+		}
+	}
+
+	results, err := queryStacks(stacks, q, loader)
+	if err != nil {
+		exitWithDiagnostics(err, "Error querying stacks")
+	}
+
+	switch {
+	case jsonOutput:
+		outputQueryJSON(results)
+	case csvOutput:
+		if err := writeQueryCSV(os.Stdout, results); err != nil {
+			logger.Log.Fatalf("Error writing CSV: %v", err)
+		}
+	case len(results) == 0:
+		logger.Log.Infof("No stacks have a value at %s", q)
+	case noColor:
+		printQueryStandardTable(q, results)
+	default:
+		printQueryBubblesTable(q, results)
+	}
+}
+
+// queryStacks evaluates a query against the merged document of every stack
+// and returns the values it selected, stack by stack
+func queryStacks(stacks []stackfinder.StackMetadata, q *query.Query, loader utils.DocumentLoader) ([]queryResult, error) {
+	var results []queryResult
+	for _, stack := range stacks {
+		document, err := loader(stack)
+		if err != nil {
+			return nil, fmt.Errorf("loading stack '%s': %w", stack.Name, err)
+		}
+
+		matches := q.Evaluate(document)
+		if len(matches) == 0 {
+			logger.Log.Debugf("Stack '%s' has no value at %s", stack.Name, q)
+		}
+		for _, match := range matches {
+			results = append(results, queryResult{Stack: stack.Name, Path: match.Path, Value: match.Value})
+		}
+	}
+	return results, nil
+}
+
+// cachingDocumentLoader returns a loader that merges each stack file once
+func cachingDocumentLoader(loader utils.DocumentLoader) utils.DocumentLoader {
+	type loaded struct {
+		document map[string]interface{}
+		err      error
+	}
+	cache := make(map[string]loaded)
+
+	return func(stack stackfinder.StackMetadata) (map[string]interface{}, error) {
+		if result, ok := cache[stack.FilePath]; ok {
+			return result.document, result.err
+		}
+		document, err := loader(stack)
+		cache[stack.FilePath] = loaded{document: document, err: err}
+		return document, err
+	}
+}
+
+// outputQueryJSON prints the query results as a JSON array
+func outputQueryJSON(results []queryResult) {
+	if results == nil {
+		results = []queryResult{}
+	}
+
+	jsonData, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonData))
+}
+
+// writeQueryCSV writes the query results as CSV with a header row. Mappings
+// and lists are written as JSON.
+func writeQueryCSV(w io.Writer, results []queryResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"stack", "path", "value"}); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := writer.Write([]string{result.Stack, result.Path, formatVariableValue(result.Value)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// queryRows returns a row per query result. The path is only included if the
+// query can select several values in a stack.
+func queryRows(results []queryResult, withPath bool) [][]string {
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		row := []string{result.Stack}
+		if withPath {
+			row = append(row, result.Path)
+		}
+		rows = append(rows, append(row, formatVariableValue(result.Value)))
+	}
+	return rows
+}
+
+// queryHeaders returns the column headers matching queryRows
+func queryHeaders(withPath bool) []string {
+	if withPath {
+		return []string{"STACK", "PATH", "VALUE"}
+	}
+	return []string{"STACK", "VALUE"}
+}
+
+// printQueryBubblesTable prints the query results using the tablerender package
+func printQueryBubblesTable(q *query.Query, results []queryResult) {
+	withPath := !q.Single()
+
+	style := tablerender.DefaultTableStyle()
+	style.Title = fmt.Sprintf("QUERY: %s", q)
+	if withPath {
+		style.FirstColWidth = style.TotalWidth / 4
+	}
+
+	table := tablerender.RenderTable(queryHeaders(withPath), queryRows(results, withPath), style)
+	fmt.Println(table)
+}
+
+// printQueryStandardTable prints the query results as a plain text table
+func printQueryStandardTable(q *query.Query, results []queryResult) {
+	withPath := !q.Single()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Printf("Query: %s\n", q)
+	fmt.Println()
+
+	headers := queryHeaders(withPath)
+	underlines := make([]string, 0, len(headers))
+	for i, header := range headers {
+		headers[i] = strings.ToUpper(header[:1]) + strings.ToLower(header[1:])
+		underlines = append(underlines, strings.Repeat("-", len(header)))
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	fmt.Fprintln(w, strings.Join(underlines, "\t"))
+
+	for _, row := range queryRows(results, withPath) {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	// Add flags
+	addStackFilterFlags(queryCmd)
+	queryCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as a JSON array of stack, path and value")
+	queryCmd.Flags().BoolVar(&csvOutput, "csv", false, "output as CSV with stack, path and value columns")
+	queryCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+}
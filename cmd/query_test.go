@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/query"
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStacks(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	stacks := []stackfinder.StackMetadata{
+		{Name: "test-stack", FilePath: filepath.Join("testdata", "test_stack.yaml")},
+	}
+
+	tests := []struct {
+		expression string
+		expected   []queryResult
+	}{
+		{
+			expression: ".spec.components.terraform.vpc.vars.cidr_block",
+			expected:   []queryResult{{Stack: "test-stack", Path: ".spec.components.terraform.vpc.vars.cidr_block", Value: "10.0.0.0/16"}},
+		},
+		{
+			expression: ".spec.components.*.*.vars.replicas",
+			expected:   []queryResult{{Stack: "test-stack", Path: ".spec.components.helm.nginx.vars.replicas", Value: uint64(3)}},
+		},
+		{
+			expression: "..Environment",
+			expected:   []queryResult{{Stack: "test-stack", Path: ".spec.components.terraform.vpc.vars.tags.Environment", Value: "test"}},
+		},
+		{
+			expression: ".spec.components.terraform.eks",
+			expected:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			q, err := query.Parse(tt.expression)
+			assert.NoError(t, err)
+
+			results, err := queryStacks(stacks, q, loadStackDocument)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, results)
+		})
+	}
+}
+
+func TestQueryStacksLoadError(t *testing.T) {
+	q, err := query.Parse(".spec")
+	assert.NoError(t, err)
+
+	_, err = queryStacks([]stackfinder.StackMetadata{{Name: "broken"}}, q, func(stackfinder.StackMetadata) (map[string]interface{}, error) {
+		return nil, errors.New("unknown anchor")
+	})
+	assert.EqualError(t, err, "loading stack 'broken': unknown anchor")
+}
+
+func TestCachingDocumentLoader(t *testing.T) {
+	calls := 0
+	loader := cachingDocumentLoader(func(stack stackfinder.StackMetadata) (map[string]interface{}, error) {
+		calls++
+		return map[string]interface{}{"name": stack.Name}, nil
+	})
+
+	dev := stackfinder.StackMetadata{Name: "dev", FilePath: "dev.yaml"}
+	prod := stackfinder.StackMetadata{Name: "prod", FilePath: "prod.yaml"}
+	for _, stack := range []stackfinder.StackMetadata{dev, prod, dev, prod} {
+		document, err := loader(stack)
+		assert.NoError(t, err)
+		assert.Equal(t, stack.Name, document["name"])
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestWriteQueryCSV(t *testing.T) {
+	results := []queryResult{
+		{Stack: "dev", Path: ".vars.cidr", Value: "10.0.0.0/16"},
+		{Stack: "dev", Path: ".vars.zones", Value: []interface{}{"a", "b"}},
+		{Stack: "prod", Path: ".vars.enabled", Value: true},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeQueryCSV(&buf, results))
+	assert.Equal(t, "stack,path,value\ndev,.vars.cidr,10.0.0.0/16\ndev,.vars.zones,\"[\"\"a\"\",\"\"b\"\"]\"\nprod,.vars.enabled,true\n", buf.String())
+}
+
+func TestQueryRows(t *testing.T) {
+	results := []queryResult{
+		{Stack: "dev", Path: ".vars.cidr", Value: "10.0.0.0/16"},
+		{Stack: "prod", Path: ".vars.cidr", Value: nil},
+	}
+
+	assert.Equal(t, [][]string{{"dev", "10.0.0.0/16"}, {"prod", "null"}}, queryRows(results, false))
+	assert.Equal(t, [][]string{{"dev", ".vars.cidr", "10.0.0.0/16"}, {"prod", ".vars.cidr", "null"}}, queryRows(results, true))
+	assert.Equal(t, []string{"STACK", "PATH", "VALUE"}, queryHeaders(true))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/git"
+	"github.com/spf13/viper"
+)
+
+// revisionTree is the stacks and catalog of a git revision, exported to a
+// temporary directory so they can be parsed like the working tree
+type revisionTree struct {
+	Root       string
+	StacksPath string
+	CatalogDir string
+}
+
+// exportRevision exports the configured stacks and catalog directories as they
+// are at a git revision. The returned function removes the exported files.
+func exportRevision(ref string) (*revisionTree, func(), error) {
+	stacksPath, err := relativeToWorkingDir(viper.GetString("stacksPath"))
+	if err != nil {
+		return nil, nil, err
+	}
+	catalogDir, err := relativeToWorkingDir(viper.GetString("catalogDir"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "skunk-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	if err := git.Export(".", ref, []string{globBase(stacksPath), catalogDir}, dir); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return &revisionTree{
+		Root:       dir,
+		StacksPath: filepath.Join(dir, stacksPath),
+		CatalogDir: filepath.Join(dir, catalogDir),
+	}, cleanup, nil
+}
+
+// relativeToWorkingDir returns a path relative to the current directory, which
+// it must be inside of
+func relativeToWorkingDir(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("stacksPath and catalogDir must be defined in config")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the current directory", path)
+	}
+	return rel, nil
+}
+
+// globBase returns the directory part of a glob pattern before its first wildcard
+func globBase(pattern string) string {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+
+	var base []string
+	for _, part := range parts {
+		if strings.ContainsAny(part, "*?[{") {
+			break
+		}
+		base = append(base, part)
+	}
+	if len(base) == len(parts) {
+		// No wildcard, so the pattern is a file
+		base = base[:len(base)-1]
+	}
+	if len(base) == 0 {
+		return "."
+	}
+	return filepath.FromSlash(strings.Join(base, "/"))
+}
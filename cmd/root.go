@@ -84,6 +84,10 @@ func initConfig() {
 	viper.SetDefault("catalogDir", "fixtures/catalog")
 	viper.SetDefault("logLevel", "info")
 	viper.SetDefault("maxTableWidth", 80)
+	viper.SetDefault("merge.deep", false)
+	viper.SetDefault("merge.lists", "replace")
+	viper.SetDefault("merge.listKey", "name")
+	viper.SetDefault("duplicateAnchors", "error")
 
 	// Read environment variables
 	viper.AutomaticEnv()
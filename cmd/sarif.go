@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/validator"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// SARIF 2.1.0 constants
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolURI = "https://github.com/mcalhoun/skunk"
+)
+
+// The subset of the SARIF 2.1.0 object model needed to report findings
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// buildSARIF converts findings into a SARIF log with one rule per registered check
+func buildSARIF(findings []validator.Finding, checks *validator.Registry) sarifLog {
+	driver := sarifDriver{Name: "skunk", InformationURI: sarifToolURI}
+	for _, check := range checks.Checks() {
+		driver.Rules = append(driver.Rules, sarifRule{ID: check.Name, ShortDescription: sarifMessage{Text: check.Description}})
+	}
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, finding := range findings {
+		level := "error"
+		if finding.Severity == yamlparser.SeverityWarning {
+			level = "warning"
+		}
+
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(finding.File)}}
+		if finding.Line > 0 {
+			location.Region = &sarifRegion{StartLine: finding.Line, StartColumn: finding.Column}
+		}
+
+		results = append(results, sarifResult{
+			RuleID:    finding.Check,
+			Level:     level,
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{PhysicalLocation: location}},
+		})
+	}
+
+	return sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+// outputFindingsSARIF prints the findings as a SARIF log
+func outputFindingsSARIF(w io.Writer, findings []validator.Finding, checks *validator.Registry) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(buildSARIF(findings, checks)); err != nil {
+		logger.Log.Fatalf("Error marshaling to SARIF: %v", err)
+	}
+}
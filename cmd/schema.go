@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/schema"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Only declare variables that are specific to this file
+var (
+	terraformModule string
+	forceImport     bool
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Manage component schemas",
+	Long:  `Manage the schemas in the catalog that component vars are validated against.`,
+}
+
+// schemaImportCmd represents the schema import command
+var schemaImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Generate a component schema from a Terraform module",
+	Long: `Read the variable blocks of a Terraform module and write a schema for the
+component's vars to <catalogDir>/components/<component>/schema.json.
+
+Each variable becomes a property with its type, description and default.
+Variables without a default are required and unknown vars are rejected.
+Validation conditions using contains(), can(regex()) or length() comparisons
+become enums, patterns and length bounds; any other condition is skipped with
+a warning.
+
+The component name defaults to the name of the module directory. An existing
+schema is only replaced with --force.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSchemaImportCmd(cmd, args)
+	},
+}
+
+// runSchemaImportCmd is the implementation of the schema import command
+func runSchemaImportCmd(cmd *cobra.Command, args []string) {
+	if terraformModule == "" {
+		logger.Log.Fatalf("Error: module directory is required. Use --terraform")
+	}
+
+	catalogDir := viper.GetString("catalogDir")
+	if catalogDir == "" {
+		logger.Log.Fatalf("Error: catalogDir not defined in config")
+	}
+
+	path, warnings, err := importTerraformSchema(terraformModule, catalogDir, componentName, forceImport)
+	for _, warning := range warnings {
+		logger.Log.Warnf("%s", warning)
+	}
+	if err != nil {
+		logger.Log.Fatalf("Error importing schema: %v", err)
+	}
+
+	logger.Log.Infof("Wrote schema to %s", path)
+}
+
+// importTerraformSchema writes the schema of a Terraform module into the
+// catalog directory of a component and returns the path it was written to.
+// An existing schema keeps its file name and format.
+func importTerraformSchema(moduleDir, catalogDir, component string, force bool) (string, []string, error) {
+	if component == "" {
+		abs, err := filepath.Abs(moduleDir)
+		if err != nil {
+			return "", nil, err
+		}
+		component = filepath.Base(abs)
+	}
+
+	s, warnings, err := schema.ImportTerraform(moduleDir)
+	if err != nil {
+		return "", warnings, err
+	}
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Title = component
+
+	path := schema.NewComponentSchemas(catalogDir).SchemaFile(component)
+	if path != "" && !force {
+		return "", warnings, fmt.Errorf("%s already exists, use --force to replace it", path)
+	}
+	if path == "" {
+		path = filepath.Join(catalogDir, schema.ComponentsDir, component, "schema.json")
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", warnings, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.JSONToYAML(data); err != nil {
+			return "", warnings, fmt.Errorf("failed to convert schema to YAML: %w", err)
+		}
+	} else {
+		data = append(data, '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", warnings, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", warnings, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, warnings, nil
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaImportCmd)
+
+	// Add flags
+	schemaImportCmd.Flags().StringVar(&terraformModule, "terraform", "", "Terraform module directory to read variable blocks from (required)")
+	schemaImportCmd.Flags().StringVarP(&componentName, "component", "c", "", "component to write the schema for (default is the module directory name)")
+	schemaImportCmd.Flags().BoolVar(&forceImport, "force", false, "replace an existing schema")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportTerraformSchema(t *testing.T) {
+	dir := t.TempDir()
+	moduleDir := filepath.Join(dir, "modules", "vpc")
+	catalogDir := filepath.Join(dir, "catalog")
+	assert.NoError(t, os.MkdirAll(moduleDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(moduleDir, "variables.tf"), []byte(`
+variable "cidr" {
+  type = string
+}
+
+variable "enabled" {
+  type    = bool
+  default = true
+
+  validation {
+    condition     = var.enabled != null
+    error_message = "Required."
+  }
+}
+`), 0600))
+
+	// The component name defaults to the module directory name
+	path, warnings, err := importTerraformSchema(moduleDir, catalogDir, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(catalogDir, "components", "vpc", "schema.json"), path)
+	assert.Len(t, warnings, 1)
+
+	s, err := schema.Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "vpc", s.Title)
+	assert.Equal(t, []string{"cidr"}, s.Required)
+	assert.Len(t, s.Validate(map[string]interface{}{"cidr": "10.0.0.0/16", "enable": false}), 1)
+
+	// An existing schema is only replaced with force, keeping its format
+	_, _, err = importTerraformSchema(moduleDir, catalogDir, "vpc", false)
+	assert.ErrorContains(t, err, "already exists")
+
+	yamlPath := filepath.Join(catalogDir, "components", "network", "schema.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(yamlPath), 0755))
+	assert.NoError(t, os.WriteFile(yamlPath, []byte("type: object\n"), 0600))
+
+	path, _, err = importTerraformSchema(moduleDir, catalogDir, "network", true)
+	assert.NoError(t, err)
+	assert.Equal(t, yamlPath, path)
+
+	s, err = schema.Load(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "network", s.Title)
+	assert.Contains(t, s.Properties, "enabled")
+}
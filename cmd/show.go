@@ -3,14 +3,16 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"text/tabwriter"
 
-	"github.com/goccy/go-yaml"
 	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/renderer"
+	"github.com/mcalhoun/skunk/internal/schema"
 	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
 	tablerender "github.com/mcalhoun/skunk/internal/table-render"
 	"github.com/mcalhoun/skunk/internal/utils"
@@ -21,15 +23,29 @@ import (
 
 // Only declare variables that are specific to this file
 var (
-	stackName     string
-	componentName string
-	tfVars        bool
+	stackName      string
+	componentName  string
+	tfVars         bool
+	showProvenance bool
+	renderFormat   string
+	showFirst      bool
+	showPivot      bool
 )
 
+// defaultRenderFormat is the value of --render given without a format
+const defaultRenderFormat = "default"
+
+// componentRenderers are the output formats offered for each component type.
+// Renderers for additional component types can be registered here.
+var componentRenderers = renderer.DefaultRegistry()
+
 // ComponentVar represents a component variable
 type ComponentVar struct {
 	Name  string      `json:"name"`
 	Value interface{} `json:"value"`
+	// Sources lists where the value was set, effective source first,
+	// followed by the values it overrode. Only populated with --provenance.
+	Sources []yamlparser.Source `json:"sources,omitempty"`
 }
 
 // Component represents a component in a stack
@@ -52,7 +68,18 @@ var showCmd = &cobra.Command{
 var showStackCmd = &cobra.Command{
 	Use:   "stack",
 	Short: "Show stack components",
-	Long:  `Show detailed information about components in a specific stack.`,
+	Long: `Show detailed information about components in a specific stack.
+
+When --filter or --where match several stacks, each stack is shown in its own
+section. With --component, --pivot shows the component's vars in a single
+table instead, with a row per variable and a column per stack. --first only
+shows the first matching stack.
+
+With --component and --render, the component's vars are written in a format
+of its type instead of a table: tfvars or tfvars-json for terraform components,
+values for helm components, and yaml or json for other types. --render without
+a format uses the type's default format.`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		runShowStackCmd(cmd, args, defaultStackFinder)
 	},
@@ -62,13 +89,10 @@ var showStackCmd = &cobra.Command{
 // extracted to a separate function to make it testable with a mock stack finder
 func runShowStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
 	// Get filters from flags
-	filters, err := cmd.Flags().GetStringArray("filter")
-	if err != nil {
-		logger.Log.Fatalf("Error getting filters: %v", err)
-	}
+	filter := stackFilter(cmd)
 
-	if stackName == "" && len(filters) == 0 {
-		logger.Log.Fatalf("Error: either stack name or filter is required. Use --stackName/-s or --filter")
+	if stackName == "" && filter == nil {
+		logger.Log.Fatalf("Error: either stack name or filter is required. Use --stackName/-s, --filter or --where")
 	}
 
 	// Validate that --tfvars is only used with --component
@@ -76,6 +100,32 @@ func runShowStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
 		logger.Log.Fatalf("Error: --tfvars can only be used with --component")
 	}
 
+	// Validate that --render is only used with --component, and not with --tfvars
+	if renderFormat != "" && componentName == "" {
+		logger.Log.Fatalf("Error: --render can only be used with --component")
+	}
+	if renderFormat != "" && tfVars {
+		logger.Log.Fatalf("Error: --render and --tfvars cannot be used together")
+	}
+
+	// Validate that --provenance is only used with --component
+	if showProvenance && componentName == "" {
+		logger.Log.Fatalf("Error: --provenance can only be used with --component")
+	}
+
+	// Validate that --first is only used with filters
+	if showFirst && (filter == nil || stackName != "") {
+		logger.Log.Fatalf("Error: --first can only be used with --filter or --where, and not with --stackName")
+	}
+
+	// Validate that --pivot is only used with --component, and not with other output formats
+	if showPivot && componentName == "" {
+		logger.Log.Fatalf("Error: --pivot can only be used with --component")
+	}
+	if showPivot && (tfVars || renderFormat != "" || showProvenance) {
+		logger.Log.Fatalf("Error: --pivot cannot be used with --tfvars, --render or --provenance")
+	}
+
 	// Get stacksPath from config
 	stacksPath := viper.GetString("stacksPath")
 	if stacksPath == "" {
@@ -89,61 +139,73 @@ func runShowStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
 	}
 
 	// Check for duplicate stack names
-	duplicates := utils.FindDuplicateStacks(stacks)
-	if len(duplicates) > 0 {
-		for stackName, stackFiles := range duplicates {
-			filesWithBrackets := "[" + strings.Join(stackFiles, ", ") + "]"
-			logger.Log.Error("duplicate stack detected",
-				"stack", stackName,
-				"error", "Stacks must have unique names",
-				"files_count", len(stackFiles),
-				"files", filesWithBrackets)
-		}
-		os.Exit(1)
-	}
+	exitOnDuplicateStacks(stacks)
 
 	// Apply filters if any are specified
-	if len(filters) > 0 {
-		stacks = utils.FilterStacks(stacks, filters)
+	if filter != nil {
+		stacks, err = utils.SelectStacks(stacks, filter, loadStackDocument)
+		if err != nil {
+			exitWithDiagnostics(err, "Error filtering stacks")
+		}
 		if len(stacks) == 0 {
 			logger.Log.Info("No stacks match the specified filters")
 			return
 		}
 	}
 
-	var targetStack *stackfinder.StackMetadata
+	targets := selectShowTargets(stacks, stackName)
+	if len(targets) > 1 && (tfVars || renderFormat != "") {
+		logger.Log.Fatalf("Error: --tfvars and --render show a single stack, but %d stacks match; use --first or narrow the filters", len(targets))
+	}
 
-	// If filters are used without stackName, use the first matching stack
-	if stackName == "" && len(stacks) > 0 {
-		targetStack = &stacks[0]
-		// Update stackName with the selected stack's name
-		stackName = targetStack.Name
-		// Inform the user which stack was selected
-		logger.Log.Infof("Selected stack '%s' based on filter criteria", targetStack.Name)
-	} else {
-		// Find the stack by name
-		for i, stack := range stacks {
-			if stack.Name == stackName {
-				targetStack = &stacks[i]
-				break
-			}
-		}
+	if showPivot {
+		showComponentPivot(targets, componentName)
+		return
+	}
+	if len(targets) > 1 {
+		showStackSections(targets, componentName)
+		return
 	}
+	showStack(&targets[0])
+}
 
-	if targetStack == nil {
-		if stackName != "" {
-			logger.Log.Fatalf("Error: stack with name '%s' not found", stackName)
-		} else {
-			logger.Log.Fatal("Error: no matching stack found")
+// selectShowTargets returns the stacks to show: the stack named name, or with
+// no name every stack that matched the filters, or the first one with --first
+func selectShowTargets(stacks []stackfinder.StackMetadata, name string) []stackfinder.StackMetadata {
+	if name != "" {
+		stack := findStackByName(stacks, name)
+		if stack == nil {
+			logger.Log.Fatalf("Error: stack with name '%s' not found", name)
+			return nil
 		}
-		return
+		return []stackfinder.StackMetadata{*stack}
+	}
+
+	if len(stacks) == 0 {
+		logger.Log.Fatal("Error: no matching stack found")
+		return nil
+	}
+
+	if showFirst {
+		// Inform the user which stack was selected
+		logger.Log.Infof("Selected stack '%s' based on filter criteria", stacks[0].Name)
+		return stacks[:1]
 	}
+	return stacks
+}
+
+// showStack shows the components of a stack, or the variables of the
+// component selected with --component in the requested format
+func showStack(targetStack *stackfinder.StackMetadata) {
+	stackName := targetStack.Name
 
-	// Parse the YAML file to extract components
-	components, err := extractComponents(targetStack.FilePath)
+	// Parse the stack once for its components, vars and schema check
+	schemas := schema.NewComponentSchemas(catalogDirFromConfig())
+	stack, err := parseShowStack(targetStack.FilePath, needsProvenance(schemas, componentName, showProvenance))
 	if err != nil {
-		logger.Log.Fatalf("Error extracting components: %v", err)
+		exitWithDiagnostics(err, "Error extracting components")
 	}
+	components := extractComponents(stack)
 
 	if len(components) == 0 {
 		logger.Log.Infof("No components found in stack '%s'", targetStack.Name)
@@ -153,33 +215,43 @@ func runShowStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
 	// If a specific component is requested, show its variables
 	if componentName != "" {
 		// Find the component
-		var foundComponent *Component
-		for i, comp := range components {
-			if comp.Name == componentName {
-				foundComponent = &components[i]
-				break
-			}
-		}
-
+		foundComponent := findComponentByName(components, componentName)
 		if foundComponent == nil {
 			logger.Log.Fatalf("Error: component with name '%s' not found in stack '%s'", componentName, stackName)
 			return
 		}
 
+		// Look up the renderer before doing any work, so an unknown format fails fast
+		var componentRenderer *renderer.Renderer
+		if tfVars || renderFormat != "" {
+			r, err := componentRenderers.Lookup(foundComponent.Type, renderFormatFor(renderFormat, tfVars))
+			if err != nil {
+				logger.Log.Fatalf("Error: %v", err)
+			}
+			componentRenderer = &r
+		}
+
 		// Extract component variables
-		vars, err := extractComponentVars(targetStack.FilePath, foundComponent.Type, foundComponent.Name)
+		vars, err := extractComponentVarsWithSources(stack, foundComponent.Type, foundComponent.Name, showProvenance)
 		if err != nil {
-			logger.Log.Fatalf("Error extracting component variables: %v", err)
+			exitWithDiagnostics(err, "Error extracting component variables")
 		}
 
+		// Report vars that don't match the component's schema, if it has one
+		diags, err := checkComponentSchema(schemas, stack, foundComponent.Type, foundComponent.Name)
+		if err != nil {
+			exitWithDiagnostics(err, "Error checking component schema")
+		}
+		reportDiagnostics(diags)
+
 		if len(vars) == 0 {
 			logger.Log.Infof("No variables found for component '%s' in stack '%s'", componentName, stackName)
 			return
 		}
 
-		// If tfvars output is requested, output in Terraform format
-		if tfVars {
-			outputTerraformVars(vars, filepath.Base(targetStack.FilePath), componentName)
+		// If a rendered format is requested, such as tfvars, output the vars in it
+		if componentRenderer != nil {
+			outputRenderedVars(*componentRenderer, vars, filepath.Base(targetStack.FilePath), foundComponent)
 			return
 		}
 
@@ -217,37 +289,306 @@ func runShowStackCmd(cmd *cobra.Command, args []string, finder StackFinder) {
 	printComponentsBubblesTable(stackName, components)
 }
 
-// extractComponents extracts components from a stack YAML file
-func extractComponents(filePath string) ([]Component, error) {
-	// Get catalogDir from config
+// stackSection is what show stack shows for one of several stacks: its
+// components, or the variables of the component selected with --component
+type stackSection struct {
+	Stack      string         `json:"stack"`
+	Components []Component    `json:"components,omitempty"`
+	Component  *Component     `json:"component,omitempty"`
+	Vars       []ComponentVar `json:"vars,omitempty"`
+}
+
+// loadStackSections loads the components of each stack, or the variables of
+// the named component if component is set. Stacks without components, or
+// without the component or its variables, are left out.
+func loadStackSections(stacks []stackfinder.StackMetadata, component string, withSources bool) []stackSection {
+	schemas := schema.NewComponentSchemas(catalogDirFromConfig())
+	withProvenance := needsProvenance(schemas, component, withSources)
+
+	var sections []stackSection
+	for _, stack := range stacks {
+		parsed, err := parseShowStack(stack.FilePath, withProvenance)
+		if err != nil {
+			exitWithDiagnostics(err, "Error extracting components of stack '%s'", stack.Name)
+		}
+		components := extractComponents(parsed)
+		if len(components) == 0 {
+			logger.Log.Infof("No components found in stack '%s'", stack.Name)
+			continue
+		}
+
+		if component == "" {
+			sections = append(sections, stackSection{Stack: stack.Name, Components: components})
+			continue
+		}
+
+		foundComponent := findComponentByName(components, component)
+		if foundComponent == nil {
+			logger.Log.Warnf("Component with name '%s' not found in stack '%s'", component, stack.Name)
+			continue
+		}
+
+		vars, err := extractComponentVarsWithSources(parsed, foundComponent.Type, foundComponent.Name, withSources)
+		if err != nil {
+			exitWithDiagnostics(err, "Error extracting component variables of stack '%s'", stack.Name)
+		}
+
+		// Report vars that don't match the component's schema, if it has one
+		diags, err := checkComponentSchema(schemas, parsed, foundComponent.Type, foundComponent.Name)
+		if err != nil {
+			exitWithDiagnostics(err, "Error checking component schema")
+		}
+		reportDiagnostics(diags)
+
+		if len(vars) == 0 {
+			logger.Log.Infof("No variables found for component '%s' in stack '%s'", component, stack.Name)
+			continue
+		}
+		sections = append(sections, stackSection{Stack: stack.Name, Component: foundComponent, Vars: vars})
+	}
+	return sections
+}
+
+// showStackSections shows each stack in its own section, or all of them in a
+// single JSON array
+func showStackSections(stacks []stackfinder.StackMetadata, component string) {
+	sections := loadStackSections(stacks, component, showProvenance)
+
+	if jsonOutput {
+		if sections == nil {
+			sections = []stackSection{}
+		}
+		jsonData, err := json.MarshalIndent(sections, "", "  ")
+		if err != nil {
+			logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	for i, section := range sections {
+		if noColor && i > 0 {
+			fmt.Println()
+		}
+
+		switch {
+		case section.Component == nil && noColor:
+			printComponentsStandardTable(section.Stack, section.Components)
+		case section.Component == nil:
+			printComponentsBubblesTable(section.Stack, section.Components)
+		case noColor:
+			printComponentVarsStandardTable(section.Stack, section.Vars, section.Component)
+		default:
+			printComponentVarsBubblesTable(section.Stack, section.Vars, section.Component)
+		}
+	}
+}
+
+// showComponentPivot shows the variables of a component in every stack as a
+// single table, with a row per variable and a column per stack
+func showComponentPivot(stacks []stackfinder.StackMetadata, component string) {
+	sections := loadStackSections(stacks, component, false)
+	if len(sections) == 0 {
+		logger.Log.Infof("No matching stack has variables for component '%s'", component)
+		return
+	}
+
+	// Components are found by name, so check they are the same component everywhere
+	if err := checkPivotComponentType(sections); err != nil {
+		logger.Log.Fatalf("Error: %v", err)
+	}
+
+	if jsonOutput {
+		outputPivotJSON(sections)
+		return
+	}
+
+	title := fmt.Sprintf("%s/%s", sections[0].Component.Type, sections[0].Component.Name)
+
+	if noColor {
+		headers, rows := pivotComponentVars(sections, formatVariableValue)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Printf("Component: %s\n\n", title)
+		underlines := make([]string, 0, len(headers))
+		for _, header := range headers {
+			underlines = append(underlines, strings.Repeat("-", len(header)))
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		fmt.Fprintln(w, strings.Join(underlines, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		w.Flush()
+		return
+	}
+
+	colorScheme := tablerender.DefaultColorScheme()
+	headers, rows := pivotComponentVars(sections, func(value interface{}) string {
+		return tablerender.FormatValueWithColor(value, colorScheme)
+	})
+	headers[0] = strings.ToUpper(headers[0])
+
+	style := tablerender.DefaultTableStyle()
+	style.Title = "COMPONENT: " + title
+	style.FirstColWidth = style.TotalWidth / (len(headers) + 1)
+
+	table := tablerender.RenderTable(headers, rows, style)
+	fmt.Println(table)
+}
+
+// checkPivotComponentType returns an error if the component of the sections
+// does not have the same type in every stack
+func checkPivotComponentType(sections []stackSection) error {
+	first := sections[0]
+	for _, section := range sections[1:] {
+		if section.Component.Type != first.Component.Type {
+			return fmt.Errorf("--pivot needs the same component in every stack, but '%s' is a %s component in stack '%s' and a %s component in stack '%s'",
+				first.Component.Name, first.Component.Type, first.Stack, section.Component.Type, section.Stack)
+		}
+	}
+	return nil
+}
+
+// pivotComponentVars returns the headers and rows of a pivot table: a row per
+// variable sorted by name, and a column per stack holding the formatted value
+// of the variable in that stack, or nothing if the stack does not set it
+func pivotComponentVars(sections []stackSection, format func(interface{}) string) ([]string, [][]string) {
+	headers := []string{"Variable"}
+	values := make([]map[string]interface{}, 0, len(sections))
+	var names []string
+	seen := make(map[string]bool)
+	for _, section := range sections {
+		headers = append(headers, section.Stack)
+		stackValues := make(map[string]interface{}, len(section.Vars))
+		for _, v := range section.Vars {
+			stackValues[v.Name] = v.Value
+			if !seen[v.Name] {
+				seen[v.Name] = true
+				names = append(names, v.Name)
+			}
+		}
+		values = append(values, stackValues)
+	}
+	sort.Strings(names)
+
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		row := []string{name}
+		for _, stackValues := range values {
+			value, ok := stackValues[name]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, format(value))
+		}
+		rows = append(rows, row)
+	}
+	return headers, rows
+}
+
+// outputPivotJSON prints the variables of a component in every stack as a
+// JSON object of variable names to objects of stack names to values
+func outputPivotJSON(sections []stackSection) {
+	pivot := make(map[string]map[string]interface{})
+	for _, section := range sections {
+		for _, v := range section.Vars {
+			if pivot[v.Name] == nil {
+				pivot[v.Name] = make(map[string]interface{})
+			}
+			pivot[v.Name][section.Stack] = v.Value
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(pivot, "", "  ")
+	if err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+
+	fmt.Println(string(jsonData))
+}
+
+// findComponentByName returns the component with the given name, or nil if there is none
+func findComponentByName(components []Component, name string) *Component {
+	for i, component := range components {
+		if component.Name == name {
+			return &components[i]
+		}
+	}
+	return nil
+}
+
+// parsedStack is the merged document of a stack, parsed once for everything
+// show stack reads from it. Provenance is nil unless it was requested.
+type parsedStack struct {
+	FilePath   string
+	Document   map[string]interface{}
+	Provenance yamlparser.ProvenanceMap
+}
+
+// catalogDirFromConfig returns the configured catalogDir
+func catalogDirFromConfig() string {
 	catalogDir := viper.GetString("catalogDir")
 	if catalogDir == "" {
 		// Default to fixtures/catalog if not specified
 		catalogDir = "fixtures/catalog"
 	}
+	return catalogDir
+}
+
+// parseShowStack merges a stack with the configured catalog and merge settings,
+// recording where each value was set only if withProvenance is set
+func parseShowStack(filePath string, withProvenance bool) (*parsedStack, error) {
+	catalogDir := catalogDirFromConfig()
+
+	// Get merge settings from config
+	opts, err := parseOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	// Use our YAML parser that can handle anchors
-	mergedYAML, err := yamlparser.MergeYAML(filePath, catalogDir)
+	stack := &parsedStack{FilePath: filePath}
+	if withProvenance {
+		stack.Document, stack.Provenance, err = yamlparser.ParseStackWithProvenance(filePath, catalogDir, opts)
+	} else {
+		stack.Document, err = yamlparser.ParseStackWithOptions(filePath, catalogDir, opts)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to merge YAML: %w", err)
 	}
+	return stack, nil
+}
 
-	// Parse the merged YAML to get the components structure
-	var stack struct {
-		Spec struct {
-			Components map[string]map[string]interface{} `yaml:"components"`
-		} `yaml:"spec"`
+// needsProvenance returns true if showing a component needs to know where its
+// vars were set: to show their sources, or to position the violations of the
+// component's schema. A schema that fails to load is reported by the check.
+func needsProvenance(schemas *schema.ComponentSchemas, component string, withSources bool) bool {
+	if withSources {
+		return true
 	}
-
-	if err := yaml.Unmarshal(mergedYAML, &stack); err != nil {
-		return nil, fmt.Errorf("failed to parse merged YAML: %w", err)
+	if component == "" {
+		return false
 	}
+	s, _, err := schemas.Lookup(component)
+	return err == nil && s != nil
+}
+
+// extractComponents extracts the components of a merged stack
+func extractComponents(stack *parsedStack) []Component {
+	spec, _ := stack.Document["spec"].(map[string]interface{})
+	componentTypes, _ := spec["components"].(map[string]interface{})
 
 	// Extract component types and names
 	var components []Component
 
 	// Iterate through component types (e.g., terraform)
-	for typeName, typeComponents := range stack.Spec.Components {
+	for typeName, typeValue := range componentTypes {
+		typeComponents, ok := typeValue.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
 		// Iterate through component names (e.g., vpc)
 		for componentName := range typeComponents {
 			components = append(components, Component{
@@ -257,32 +598,20 @@ func extractComponents(filePath string) ([]Component, error) {
 		}
 	}
 
-	return components, nil
+	return components
 }
 
 // extractComponentVars extracts variables from a specific component in a stack
-func extractComponentVars(filePath, componentType, componentName string) ([]ComponentVar, error) {
-	// Get catalogDir from config
-	catalogDir := viper.GetString("catalogDir")
-	if catalogDir == "" {
-		// Default to fixtures/catalog if not specified
-		catalogDir = "fixtures/catalog"
-	}
-
-	// Use our YAML parser that can handle anchors
-	mergedYAML, err := yamlparser.MergeYAML(filePath, catalogDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to merge YAML: %w", err)
-	}
-
-	// Parse the merged YAML to get the component structure
-	var stack map[string]interface{}
-	if err := yaml.Unmarshal(mergedYAML, &stack); err != nil {
-		return nil, fmt.Errorf("failed to parse merged YAML: %w", err)
-	}
+func extractComponentVars(stack *parsedStack, componentType, componentName string) ([]ComponentVar, error) {
+	return extractComponentVarsWithSources(stack, componentType, componentName, false)
+}
 
+// extractComponentVarsWithSources extracts variables from a specific component in a stack,
+// attaching the provenance of each variable when includeSources is set and the
+// stack was parsed with provenance
+func extractComponentVarsWithSources(stack *parsedStack, componentType, componentName string, includeSources bool) ([]ComponentVar, error) {
 	// Navigate to the component vars
-	spec, ok := stack["spec"].(map[string]interface{})
+	spec, ok := stack.Document["spec"].(map[string]interface{})
 	if !ok {
 		return nil, fmt.Errorf("spec section not found in YAML")
 	}
@@ -310,10 +639,16 @@ func extractComponentVars(filePath, componentType, componentName string) ([]Comp
 	// Convert vars map to slice of ComponentVar
 	var vars []ComponentVar
 	for name, value := range varsMap {
-		vars = append(vars, ComponentVar{
+		v := ComponentVar{
 			Name:  name,
 			Value: value,
-		})
+		}
+		if includeSources {
+			if p, ok := stack.Provenance.Lookup("spec", "components", componentType, componentName, "vars", name); ok {
+				v.Sources = p.Chain()
+			}
+		}
+		vars = append(vars, v)
 	}
 
 	// Sort vars by name for consistent output
@@ -324,6 +659,51 @@ func extractComponentVars(filePath, componentType, componentName string) ([]Comp
 	return vars, nil
 }
 
+// checkComponentSchema validates the merged vars of a component against the schema
+// in its catalog directory, returning a diagnostic for every violation
+func checkComponentSchema(schemas *schema.ComponentSchemas, stack *parsedStack, componentType, componentName string) (yamlparser.Diagnostics, error) {
+	if s, _, err := schemas.Lookup(componentName); err != nil || s == nil {
+		return nil, err
+	}
+
+	diags := schemas.CheckComponent(stack.FilePath, stack.Document, stack.Provenance, componentType, componentName)
+	for _, diag := range diags {
+		diag.LoadSnippet()
+	}
+	return diags, nil
+}
+
+// parseOptionsFromConfig builds the YAML parser options from the config: merge
+// settings, the duplicate anchor policy and the !stack tag for the configured stacks
+func parseOptionsFromConfig() (yamlparser.Options, error) {
+	return parseOptions(viper.GetString("stacksPath"), catalogDirFromConfig())
+}
+
+// parseOptions builds the YAML parser options from the config, resolving !stack
+// references against the stacks and catalog given instead of the configured ones
+func parseOptions(stacksPath, catalogDir string) (yamlparser.Options, error) {
+	opts := yamlparser.DefaultOptions()
+	opts.Merge.Deep = viper.GetBool("merge.deep")
+	opts.Merge.Lists = yamlparser.ListStrategy(viper.GetString("merge.lists"))
+	opts.Merge.ListKey = viper.GetString("merge.listKey")
+
+	if err := opts.Merge.Validate(); err != nil {
+		return opts, fmt.Errorf("invalid merge config: %w", err)
+	}
+
+	opts.DuplicateAnchors = yamlparser.DuplicateAnchorPolicy(viper.GetString("duplicateAnchors"))
+	if err := opts.DuplicateAnchors.Validate(); err != nil {
+		return opts, fmt.Errorf("invalid duplicateAnchors config: %w", err)
+	}
+
+	// Resolve !stack references against the given stacks
+	if stacksPath != "" && catalogDir != "" {
+		stackfinder.NewStackResolver(stacksPath, catalogDir, opts).Register(opts.Tags)
+	}
+
+	return opts, nil
+}
+
 // outputComponentsJSON prints components as JSON
 func outputComponentsJSON(components []Component) {
 	jsonData, err := json.MarshalIndent(components, "", "  ")
@@ -377,6 +757,12 @@ func printComponentVarsBubblesTable(stackName string, vars []ComponentVar, compo
 	// Create a title based on component
 	title := fmt.Sprintf("STACK: %s\nCOMPONENT: %s/%s", stackName, component.Type, component.Name)
 
+	// Variables with provenance get their own layout with source columns
+	if hasSources(vars) {
+		printComponentVarsProvenanceTable(title, vars)
+		return
+	}
+
 	// Convert vars to a map for the table renderer
 	data := make(map[string]interface{}, len(vars))
 	for _, v := range vars {
@@ -401,6 +787,35 @@ func printComponentVarsBubblesTable(stackName string, vars []ComponentVar, compo
 	fmt.Println(table)
 }
 
+// printComponentVarsProvenanceTable prints component variables with their source file and anchor
+func printComponentVarsProvenanceTable(title string, vars []ComponentVar) {
+	colorScheme := tablerender.DefaultColorScheme()
+
+	rows := make([][]string, 0, len(vars))
+	for _, v := range vars {
+		source, anchor := "", ""
+		if len(v.Sources) > 0 {
+			source = formatSource(v.Sources[0])
+			anchor = v.Sources[0].Anchor
+		}
+		rows = append(rows, []string{v.Name, tablerender.FormatValueWithColor(v.Value, colorScheme), source, anchor})
+	}
+
+	// Sort rows by name for consistent output
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+
+	// Setup table style, narrowing the name column to make room for the sources
+	style := tablerender.DefaultTableStyle()
+	style.Title = title
+	style.FirstColWidth = style.TotalWidth / 4
+
+	// Render and print the table
+	table := tablerender.RenderTable([]string{"VARIABLE", "VALUE", "SOURCE", "ANCHOR"}, rows, style)
+	fmt.Println(table)
+}
+
 // printComponentVarsStandardTable prints component variables as a plain text table
 func printComponentVarsStandardTable(stackName string, vars []ComponentVar, component *Component) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
@@ -408,18 +823,67 @@ func printComponentVarsStandardTable(stackName string, vars []ComponentVar, comp
 	fmt.Printf("Stack: %s\n", stackName)
 	fmt.Printf("Component: %s/%s\n", component.Type, component.Name)
 	fmt.Println()
-	fmt.Fprintln(w, "Variable\tValue")
-	fmt.Fprintln(w, "--------\t-----")
+
+	withSources := hasSources(vars)
+	if withSources {
+		fmt.Fprintln(w, "Variable\tValue\tSource\tAnchor\tOverrides")
+		fmt.Fprintln(w, "--------\t-----\t------\t------\t---------")
+	} else {
+		fmt.Fprintln(w, "Variable\tValue")
+		fmt.Fprintln(w, "--------\t-----")
+	}
 
 	for _, v := range vars {
 		// Convert value to string representation
 		valueStr := formatVariableValue(v.Value)
-		fmt.Fprintf(w, "%s\t%s\n", v.Name, valueStr)
+		if !withSources {
+			fmt.Fprintf(w, "%s\t%s\n", v.Name, valueStr)
+			continue
+		}
+
+		source, anchor := "", ""
+		var overrides []string
+		for i, src := range v.Sources {
+			if i == 0 {
+				source = formatSource(src)
+				anchor = src.Anchor
+				continue
+			}
+			overrides = append(overrides, fmt.Sprintf("%s=%s", formatSource(src), formatVariableValue(src.Value)))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Name, valueStr, source, anchor, strings.Join(overrides, ", "))
 	}
 
 	w.Flush()
 }
 
+// hasSources reports whether any variable carries provenance information
+func hasSources(vars []ComponentVar) bool {
+	for _, v := range vars {
+		if len(v.Sources) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSource formats a source as a file:line pair, relative to the catalog
+// directory for catalog files and to the current directory otherwise
+func formatSource(src yamlparser.Source) string {
+	relPath := src.File
+	if absPath, err := filepath.Abs(src.File); err == nil {
+		if rel, err := filepath.Rel(".", absPath); err == nil {
+			relPath = rel
+		}
+		if absCatalog, err := filepath.Abs(viper.GetString("catalogDir")); err == nil {
+			if rel, err := filepath.Rel(absCatalog, absPath); err == nil && !strings.HasPrefix(rel, "..") {
+				relPath = rel
+			}
+		}
+	}
+	return fmt.Sprintf("%s:%d", relPath, src.Line)
+}
+
 // formatVariableValue formats a value as a string for plain text output
 func formatVariableValue(value interface{}) string {
 	if value == nil {
@@ -439,32 +903,32 @@ func formatVariableValue(value interface{}) string {
 	}
 }
 
-// outputTerraformVars prints component variables in Terraform .tfvars format
-func outputTerraformVars(vars []ComponentVar, stackFile string, componentName string) {
-	// Add a header comment with stack and component info
-	fmt.Printf("# Terraform variables for component '%s' from stack '%s'\n", componentName, stackFile)
-	fmt.Printf("# Generated by skunk\n\n")
+// renderFormatFor returns the renderer format selected by the --render and
+// --tfvars flags, or an empty string for the component type's default format
+func renderFormatFor(format string, tfVars bool) string {
+	if tfVars {
+		return "tfvars"
+	}
+	if format == defaultRenderFormat {
+		return ""
+	}
+	return format
+}
+
+// outputRenderedVars prints component variables with a renderer of the component's type
+func outputRenderedVars(r renderer.Renderer, vars []ComponentVar, stackFile string, component *Component) {
+	if err := writeRenderedVars(os.Stdout, r, vars, stackFile, component); err != nil {
+		logger.Log.Fatalf("Error rendering component '%s' as %s: %v", component.Name, r.Format, err)
+	}
+}
 
-	// Use the tablerender package to format values
+// writeRenderedVars writes component variables with a renderer
+func writeRenderedVars(w io.Writer, r renderer.Renderer, vars []ComponentVar, stackFile string, component *Component) error {
+	values := make(map[string]interface{}, len(vars))
 	for _, v := range vars {
-		// Convert to appropriate Terraform syntax
-		valueStr := ""
-		switch v.Value.(type) {
-		case string:
-			valueStr = fmt.Sprintf("\"%v\"", v.Value)
-		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
-			valueStr = fmt.Sprintf("%v", v.Value)
-		default:
-			// Use JSON marshaling for complex types
-			jsonData, err := json.Marshal(v.Value)
-			if err != nil {
-				valueStr = fmt.Sprintf("%v", v.Value)
-			} else {
-				valueStr = string(jsonData)
-			}
-		}
-		fmt.Printf("%s = %s\n", v.Name, valueStr)
+		values[v.Name] = v.Value
 	}
+	return r.Render(w, renderer.Component{Type: component.Type, Name: component.Name, Stack: stackFile, Vars: values})
 }
 
 func init() {
@@ -477,5 +941,10 @@ func init() {
 	showStackCmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON instead of a table")
 	showStackCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
 	showStackCmd.Flags().BoolVar(&tfVars, "tfvars", false, "output component variables in Terraform format (only valid with --component)")
-	showStackCmd.Flags().StringArray("filter", []string{}, "filter stacks by label (format: key=value or key!=value), by name prefix (format: name=pattern or name!=pattern), by regex (format: name~=regex or name!~=regex), or directly by name using wildcard pattern '*' or regex '/pattern/'")
+	showStackCmd.Flags().StringVar(&renderFormat, "render", "", "output component variables in a format of the component's type, or its default format if none is given (only valid with --component)")
+	showStackCmd.Flags().Lookup("render").NoOptDefVal = defaultRenderFormat
+	showStackCmd.Flags().BoolVar(&showProvenance, "provenance", false, "show the file, line and anchor each variable was set by (only valid with --component)")
+	showStackCmd.Flags().BoolVar(&showFirst, "first", false, "only show the first stack matching --filter or --where")
+	showStackCmd.Flags().BoolVar(&showPivot, "pivot", false, "show the component's variables in every matching stack as one table with a column per stack (only valid with --component)")
+	addStackFilterFlags(showStackCmd)
 }
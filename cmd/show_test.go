@@ -3,15 +3,19 @@ package cmd
 import (
 	"bytes"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/charmbracelet/log"
 	"github.com/mcalhoun/skunk/internal/logger"
+	"github.com/mcalhoun/skunk/internal/schema"
 	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -160,6 +164,8 @@ func captureError(f func()) (string, error) {
 	return buf.String(), captureErr
 }
 
+var update = flag.Bool("update", false, "update golden files")
+
 // setupTestEnvironment creates necessary test configuration for tests
 func setupTestEnvironment(t *testing.T) func() {
 	t.Helper()
@@ -183,14 +189,27 @@ func setupTestEnvironment(t *testing.T) func() {
 	}
 }
 
+// parseTestStack parses a stack for the extract functions, failing the test on error
+func parseTestStack(t *testing.T, filePath string, withProvenance bool) *parsedStack {
+	t.Helper()
+	stack, err := parseShowStack(filePath, withProvenance)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", filePath, err)
+	}
+	return stack
+}
+
 func TestExtractComponents(t *testing.T) {
 	cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
 	testFilePath := filepath.Join("testdata", "test_stack.yaml")
 
-	components, err := extractComponents(testFilePath)
+	stack, err := parseShowStack(testFilePath, false)
 	assert.NoError(t, err)
+	assert.Nil(t, stack.Provenance)
+
+	components := extractComponents(stack)
 	assert.Equal(t, 3, len(components))
 
 	// Create a map to easily verify components
@@ -204,7 +223,7 @@ func TestExtractComponents(t *testing.T) {
 	assert.Equal(t, "helm", compMap["nginx"])
 
 	// Test with non-existent file
-	_, err = extractComponents("nonexistent.yaml")
+	_, err = parseShowStack("nonexistent.yaml", false)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to merge YAML")
 }
@@ -215,8 +234,11 @@ func TestExtractComponentVars(t *testing.T) {
 
 	testFilePath := filepath.Join("testdata", "test_stack.yaml")
 
+	stack, err := parseShowStack(testFilePath, false)
+	assert.NoError(t, err)
+
 	// Test VPC component vars
-	vars, err := extractComponentVars(testFilePath, "terraform", "vpc")
+	vars, err := extractComponentVars(stack, "terraform", "vpc")
 	assert.NoError(t, err)
 
 	// Create a map to easily verify vars
@@ -229,19 +251,150 @@ func TestExtractComponentVars(t *testing.T) {
 	assert.Equal(t, true, varMap["enable_dns"])
 
 	// Test for a non-existent component
-	_, err = extractComponentVars(testFilePath, "invalid", "component")
+	_, err = extractComponentVars(stack, "invalid", "component")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "component type 'invalid' not found")
 
 	// Test for a non-existent component name
-	_, err = extractComponentVars(testFilePath, "terraform", "nonexistent")
+	_, err = extractComponentVars(stack, "terraform", "nonexistent")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "component 'nonexistent' not found")
+}
 
-	// Test with non-existent file
-	_, err = extractComponentVars("nonexistent.yaml", "terraform", "vpc")
+func TestCheckComponentSchema(t *testing.T) {
+	catalogDir := t.TempDir()
+	schemaDir := filepath.Join(catalogDir, "components", "vpc")
+	assert.NoError(t, os.MkdirAll(schemaDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(schemaDir, "schema.yaml"), []byte("type: object\nproperties:\n  cidr_block:\n    type: integer\n"), 0644))
+	schemas := schema.NewComponentSchemas(catalogDir)
+
+	// Provenance is only needed for sources, or to position schema violations
+	assert.True(t, needsProvenance(schemas, "vpc", false))
+	assert.False(t, needsProvenance(schemas, "database", false))
+	assert.False(t, needsProvenance(schemas, "", false))
+	assert.True(t, needsProvenance(schemas, "", true))
+
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	stack := parseTestStack(t, filepath.Join("testdata", "test_stack.yaml"), true)
+	diags, err := checkComponentSchema(schemas, stack, "terraform", "vpc")
+	assert.NoError(t, err)
+	if assert.Len(t, diags, 1) {
+		assert.Contains(t, diags[0].Message, "cidr_block")
+		assert.Equal(t, 13, diags[0].Line)
+	}
+
+	diags, err = checkComponentSchema(schemas, stack, "terraform", "database")
+	assert.NoError(t, err)
+	assert.Empty(t, diags)
+}
+
+func TestWriteRenderedVarsGolden(t *testing.T) {
+	catalogDir := viper.GetString("catalogDir")
+	viper.Set("catalogDir", "../fixtures/catalog")
+	defer viper.Set("catalogDir", catalogDir)
+
+	stacks, err := filepath.Glob("../fixtures/stacks/*.yaml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stacks)
+
+	for _, stackFile := range stacks {
+		name := filepath.Base(stackFile)
+		t.Run(name, func(t *testing.T) {
+			vars, err := extractComponentVars(parseTestStack(t, stackFile, false), "terraform", "vpc")
+			assert.NoError(t, err)
+
+			r, err := componentRenderers.Lookup("terraform", "tfvars")
+			assert.NoError(t, err)
+
+			var buf bytes.Buffer
+			assert.NoError(t, writeRenderedVars(&buf, r, vars, name, &Component{Type: "terraform", Name: "vpc"}))
+
+			golden := filepath.Join("testdata", "tfvars", strings.TrimSuffix(name, ".yaml")+".vpc.tfvars")
+			if *update {
+				assert.NoError(t, os.MkdirAll(filepath.Dir(golden), 0755))
+				assert.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestExtractComponentVarsDeepMerge(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	defer viper.Set("merge.deep", false)
+
+	testFilePath := filepath.Join("testdata", "deep_merge_stack.yaml")
+
+	// Shallow merge replaces the whole tags map
+	vars, err := extractComponentVars(parseTestStack(t, testFilePath, false), "terraform", "network")
+	assert.NoError(t, err)
+	varMap := make(map[string]interface{})
+	for _, v := range vars {
+		varMap[v.Name] = v.Value
+	}
+	assert.Equal(t, map[string]interface{}{"Name": "deep-vpc"}, varMap["tags"])
+
+	// Deep merge keeps the tags that were not overridden
+	viper.Set("merge.deep", true)
+	vars, err = extractComponentVars(parseTestStack(t, testFilePath, false), "terraform", "network")
+	assert.NoError(t, err)
+	varMap = make(map[string]interface{})
+	for _, v := range vars {
+		varMap[v.Name] = v.Value
+	}
+	assert.Equal(t, map[string]interface{}{"Name": "deep-vpc", "Team": "platform"}, varMap["tags"])
+	assert.Equal(t, "10.1.0.0/16", varMap["cidr_block"])
+
+	// Invalid list strategies are rejected
+	viper.Set("merge.lists", "shuffle")
+	defer viper.Set("merge.lists", "")
+	_, err = parseShowStack(testFilePath, false)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to merge YAML")
+	assert.Contains(t, err.Error(), "invalid merge config")
+}
+
+func TestExtractComponentVarsWithSources(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	testFilePath := filepath.Join("testdata", "deep_merge_stack.yaml")
+
+	stack := parseTestStack(t, testFilePath, true)
+	vars, err := extractComponentVarsWithSources(stack, "terraform", "network", true)
+	assert.NoError(t, err)
+
+	sources := make(map[string][]yamlparser.Source)
+	for _, v := range vars {
+		sources[v.Name] = v.Sources
+	}
+
+	// cidr_block comes from the anchor only
+	assert.Len(t, sources["cidr_block"], 1)
+	assert.Equal(t, "network-defaults", sources["cidr_block"][0].Anchor)
+	assert.Equal(t, 2, sources["cidr_block"][0].Line)
+
+	// tags is set in the stack and overrides the anchor's tags
+	assert.Len(t, sources["tags"], 2)
+	assert.Equal(t, "", sources["tags"][0].Anchor)
+	assert.Equal(t, 17, sources["tags"][0].Line)
+	assert.Equal(t, "network-defaults", sources["tags"][1].Anchor)
+
+	// Sources are left out unless requested, and unknown without provenance
+	vars, err = extractComponentVars(stack, "terraform", "network")
+	assert.NoError(t, err)
+	for _, v := range vars {
+		assert.Nil(t, v.Sources)
+	}
+	vars, err = extractComponentVarsWithSources(parseTestStack(t, testFilePath, false), "terraform", "network", true)
+	assert.NoError(t, err)
+	for _, v := range vars {
+		assert.Nil(t, v.Sources)
+	}
 }
 
 func TestFormatVariableValue(t *testing.T) {
@@ -287,7 +440,9 @@ func TestHelpCommands(t *testing.T) {
 	assert.NotNil(t, showStackCmd.Flags().Lookup("json"))
 	assert.NotNil(t, showStackCmd.Flags().Lookup("no-color"))
 	assert.NotNil(t, showStackCmd.Flags().Lookup("tfvars"))
+	assert.NotNil(t, showStackCmd.Flags().Lookup("provenance"))
 	assert.NotNil(t, showStackCmd.Flags().Lookup("filter"))
+	assert.NotNil(t, showStackCmd.Flags().Lookup("where"))
 }
 
 func setupTestCommand() *cobra.Command {
@@ -297,7 +452,10 @@ func setupTestCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&jsonOutput, "json", false, "output as JSON")
 	cmd.Flags().BoolVar(&noColor, "no-color", false, "disable color")
 	cmd.Flags().BoolVar(&tfVars, "tfvars", false, "output as Terraform vars")
-	cmd.Flags().StringArray("filter", []string{}, "filter stacks")
+	cmd.Flags().BoolVar(&showProvenance, "provenance", false, "show variable sources")
+	cmd.Flags().BoolVar(&showFirst, "first", false, "only show the first stack")
+	cmd.Flags().BoolVar(&showPivot, "pivot", false, "show a pivot table")
+	addStackFilterFlags(cmd)
 	return cmd
 }
 
@@ -615,6 +773,7 @@ func TestFilterFunctionality(t *testing.T) {
 			jsonOutput = false
 			noColor = false
 			tfVars = false
+			showFirst = true
 
 			// Set filter
 			if err := cmd.Flags().Set("filter", tt.filter); err != nil {
@@ -635,6 +794,154 @@ func TestFilterFunctionality(t *testing.T) {
 	}
 }
 
+// NewMultiStackFinder returns a mock stack finder with several stacks of the same file
+func NewMultiStackFinder(t *testing.T) *MockStackFinder {
+	absPath, err := filepath.Abs(filepath.Join("testdata", "test_stack.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to get absolute path: %v", err)
+	}
+
+	return &MockStackFinder{
+		Stacks: []stackfinder.StackMetadata{
+			{Name: "dev-stack", FilePath: absPath, Labels: map[string]string{"env": "dev"}},
+			{Name: "prod-stack", FilePath: absPath, Labels: map[string]string{"env": "prod"}},
+			{Name: "test-stack", FilePath: absPath, Labels: map[string]string{"env": "test"}},
+		},
+	}
+}
+
+func TestSelectShowTargets(t *testing.T) {
+	stacks := NewMultiStackFinder(t).Stacks
+	defer func() { showFirst = false }()
+
+	showFirst = false
+	assert.Equal(t, stacks, selectShowTargets(stacks, ""))
+	assert.Equal(t, stacks[1:2], selectShowTargets(stacks, "prod-stack"))
+
+	showFirst = true
+	assert.Equal(t, stacks[:1], selectShowTargets(stacks, ""))
+}
+
+func TestRunShowStackCmdMultipleStacks(t *testing.T) {
+	cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	mockFinder := NewMultiStackFinder(t)
+
+	tests := []struct {
+		name     string
+		setup    func()
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "a section per stack",
+			setup:    func() { noColor = true },
+			contains: []string{"STACK: prod-stack", "STACK: test-stack", "nginx"},
+			excludes: []string{"dev-stack"},
+		},
+		{
+			name:     "component vars per stack",
+			setup:    func() { componentName = "vpc" },
+			contains: []string{"prod-stack", "test-stack", "10.0.0.0/16"},
+			excludes: []string{"dev-stack"},
+		},
+		{
+			name:     "only the first stack",
+			setup:    func() { noColor = true; showFirst = true },
+			contains: []string{"STACK: prod-stack"},
+			excludes: []string{"test-stack"},
+		},
+		{
+			name:     "pivot table",
+			setup:    func() { componentName = "vpc"; noColor = true; showPivot = true },
+			contains: []string{"Component: terraform/vpc", "Variable", "prod-stack", "test-stack", "cidr_block", "10.0.0.0/16"},
+		},
+		{
+			name:     "sections as json",
+			setup:    func() { componentName = "vpc"; jsonOutput = true },
+			contains: []string{`"stack": "prod-stack"`, `"stack": "test-stack"`, `"vars": [`},
+		},
+		{
+			name:     "pivot as json",
+			setup:    func() { componentName = "vpc"; jsonOutput = true; showPivot = true },
+			contains: []string{`"cidr_block": {`, `"prod-stack": "10.0.0.0/16"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stackName = ""
+			componentName = ""
+			jsonOutput = false
+			noColor = false
+			tfVars = false
+			showFirst = false
+			showPivot = false
+			defer func() { showFirst, showPivot, componentName, jsonOutput, noColor = false, false, "", false, false }()
+
+			// Registering the flags resets the variables, so set them afterwards
+			cmd := setupTestCommand()
+			assert.NoError(t, cmd.Flags().Set("where", "env in (prod, test)"))
+			tt.setup()
+
+			output := captureOutput(func() {
+				runShowStackCmd(cmd, []string{}, mockFinder)
+			})
+
+			for _, want := range tt.contains {
+				assert.Contains(t, output, want)
+			}
+			for _, unwanted := range tt.excludes {
+				assert.NotContains(t, output, unwanted)
+			}
+		})
+	}
+}
+
+func TestPivotComponentVars(t *testing.T) {
+	component := &Component{Type: "terraform", Name: "vpc"}
+	sections := []stackSection{
+		{
+			Stack:     "dev",
+			Component: component,
+			Vars: []ComponentVar{
+				{Name: "cidr_block", Value: "10.0.0.0/16"},
+				{Name: "nat_gateway", Value: false},
+			},
+		},
+		{
+			Stack:     "prod",
+			Component: component,
+			Vars: []ComponentVar{
+				{Name: "cidr_block", Value: "10.1.0.0/16"},
+				{Name: "azs", Value: []interface{}{"a", "b"}},
+			},
+		},
+	}
+
+	headers, rows := pivotComponentVars(sections, formatVariableValue)
+	assert.Equal(t, []string{"Variable", "dev", "prod"}, headers)
+	assert.Equal(t, [][]string{
+		{"azs", "", `["a","b"]`},
+		{"cidr_block", "10.0.0.0/16", "10.1.0.0/16"},
+		{"nat_gateway", "false", ""},
+	}, rows)
+}
+
+func TestCheckPivotComponentType(t *testing.T) {
+	sections := []stackSection{
+		{Stack: "dev", Component: &Component{Type: "terraform", Name: "app"}},
+		{Stack: "prod", Component: &Component{Type: "terraform", Name: "app"}},
+	}
+	assert.NoError(t, checkPivotComponentType(sections))
+
+	sections = append(sections, stackSection{Stack: "test", Component: &Component{Type: "helmfile", Name: "app"}})
+	err := checkPivotComponentType(sections)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "'app' is a terraform component in stack 'dev' and a helmfile component in stack 'test'")
+}
+
 func TestPrintFunctions(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -692,20 +999,64 @@ func TestPrintFunctions(t *testing.T) {
 			contains: []string{"Stack: test-stack", "Component: test-type/test-component", "Variable", "Value", "var1", "value1", "var2", "value2"},
 		},
 		{
-			name: "outputTerraformVars",
+			name: "printComponentVarsBubblesTable with sources",
+			function: func() {
+				vars := []ComponentVar{
+					{Name: "enabled", Value: false, Sources: []yamlparser.Source{
+						{File: "overrides.yaml", Line: 2, Anchor: "vpc-overrides", Value: false},
+						{File: "defaults.yaml", Line: 2, Anchor: "vpc-defaults", Value: true},
+					}},
+				}
+				component := &Component{Type: "terraform", Name: "vpc"}
+				printComponentVarsBubblesTable("test-stack", vars, component)
+			},
+			contains: []string{"VARIABLE", "VALUE", "SOURCE", "ANCHOR", "overrides.yaml:2", "vpc-overrides"},
+		},
+		{
+			name: "printComponentVarsStandardTable with sources",
+			function: func() {
+				vars := []ComponentVar{
+					{Name: "enabled", Value: false, Sources: []yamlparser.Source{
+						{File: "overrides.yaml", Line: 2, Anchor: "vpc-overrides", Value: false},
+						{File: "defaults.yaml", Line: 2, Anchor: "vpc-defaults", Value: true},
+					}},
+				}
+				component := &Component{Type: "terraform", Name: "vpc"}
+				printComponentVarsStandardTable("test-stack", vars, component)
+			},
+			contains: []string{"Source", "Anchor", "Overrides", "overrides.yaml:2", "vpc-overrides", "defaults.yaml:2=true"},
+		},
+		{
+			name: "outputRenderedVars tfvars",
 			function: func() {
 				vars := []ComponentVar{
 					{Name: "cidr", Value: "10.0.0.0/16"},
 					{Name: "enable", Value: true},
 					{Name: "tags", Value: map[string]string{"Name": "test"}},
 				}
-				outputTerraformVars(vars, "test_stack.yaml", "vpc")
+				r, _ := componentRenderers.Lookup("terraform", "tfvars")
+				outputRenderedVars(r, vars, "test_stack.yaml", &Component{Type: "terraform", Name: "vpc"})
 			},
 			contains: []string{
 				"# Terraform variables for component 'vpc'",
-				"cidr = \"10.0.0.0/16\"",
+				"cidr   = \"10.0.0.0/16\"",
 				"enable = true",
-				"tags = {\"Name\":\"test\"}",
+				"tags   = {\n  Name = \"test\"\n}",
+			},
+		},
+		{
+			name: "outputRenderedVars helm default",
+			function: func() {
+				vars := []ComponentVar{
+					{Name: "replicas", Value: 3},
+					{Name: "image", Value: map[string]interface{}{"repository": "nginx", "tag": "1.0.0"}},
+				}
+				r, _ := componentRenderers.Lookup("helm", "")
+				outputRenderedVars(r, vars, "test_stack.yaml", &Component{Type: "helm", Name: "nginx"})
+			},
+			contains: []string{
+				"# Helm values for component 'nginx' from stack 'test_stack.yaml'",
+				"image:\n  repository: nginx\n  tag: 1.0.0\nreplicas: 3\n",
 			},
 		},
 		{
@@ -775,6 +1126,18 @@ func TestJSONOutputFunctions(t *testing.T) {
 			},
 			contains: []string{"\"name\": \"nested\"", "\"value\": {", "\"a\": 1", "\"b\": {", "\"c\": \"d\""},
 		},
+		{
+			name: "outputComponentVarsJSON with sources",
+			function: func() {
+				vars := []ComponentVar{
+					{Name: "enabled", Value: false, Sources: []yamlparser.Source{
+						{File: "overrides.yaml", Line: 2, Column: 3, Anchor: "vpc-overrides", Value: false},
+					}},
+				}
+				outputComponentVarsJSON(vars)
+			},
+			contains: []string{"\"sources\": [", "\"file\": \"overrides.yaml\"", "\"line\": 2", "\"anchor\": \"vpc-overrides\""},
+		},
 	}
 
 	for _, tt := range tests {
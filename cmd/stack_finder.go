@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/logger"
 	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/mcalhoun/skunk/internal/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // StackFinder interface allows for easily mocking stack finder functionality in tests
@@ -12,12 +20,102 @@ type StackFinder interface {
 // DefaultStackFinder is the default implementation that uses the stackfinder package
 type DefaultStackFinder struct{}
 
-// FindStacks implements the StackFinder interface using the actual stackfinder package
+// FindStacks implements the StackFinder interface using the actual stackfinder package.
+// Stacks are resolved against the configured catalog so that labels set through
+// anchors and merges are visible to filters. Every stack that fails to resolve is
+// reported at once, and the stacks are still returned.
 func (f *DefaultStackFinder) FindStacks(pattern string) ([]stackfinder.StackMetadata, error) {
-	return stackfinder.FindStacks(pattern)
+	catalogDir := viper.GetString("catalogDir")
+	if catalogDir == "" {
+		return stackfinder.FindStacks(pattern)
+	}
+
+	opts, err := parseOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	stacks, diags, err := stackfinder.FindStacksWithDiagnostics(pattern, catalogDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reportDiagnostics(diags)
+	return stacks, nil
 }
 
 // Creates a new default stack finder
 func NewDefaultStackFinder() StackFinder {
 	return &DefaultStackFinder{}
 }
+
+// exitOnDuplicateStacks logs every stack name defined by more than one file and exits if there are any
+func exitOnDuplicateStacks(stacks []stackfinder.StackMetadata) {
+	duplicates := utils.FindDuplicateStacks(stacks)
+	if len(duplicates) == 0 {
+		return
+	}
+
+	for stackName, stackFiles := range duplicates {
+		filesWithBrackets := "[" + strings.Join(stackFiles, ", ") + "]"
+		logger.Log.Error("duplicate stack detected",
+			"stack", stackName,
+			"error", "Stacks must have unique names",
+			"files_count", len(stackFiles),
+			"files", filesWithBrackets)
+	}
+	os.Exit(1)
+}
+
+// findStackByName returns the stack with the given name, or nil if there is none
+func findStackByName(stacks []stackfinder.StackMetadata, name string) *stackfinder.StackMetadata {
+	for i, stack := range stacks {
+		if stack.Name == name {
+			return &stacks[i]
+		}
+	}
+	return nil
+}
+
+// loadStackDocument returns the merged document of a stack, for filters that
+// address components and variables, and for commands that compare stacks
+func loadStackDocument(stack stackfinder.StackMetadata) (map[string]interface{}, error) {
+	document, err := describeStack(stack.FilePath, "", "")
+	if err != nil {
+		return nil, err
+	}
+	// The parser rejects non-mapping roots; this guards the conversion anyway
+	mapping, ok := document.(map[string]interface{})
+	if !ok || mapping == nil {
+		return nil, fmt.Errorf("stack %s is not a mapping", stack.FilePath)
+	}
+	return mapping, nil
+}
+
+// addStackFilterFlags adds the --filter and --where flags that select stacks to a command
+func addStackFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("filter", []string{}, "filter stacks by label selector, such as 'env=prod', 'env in (dev,staging)', 'team notin (data)', 'region' or '!deprecated', by name (format: name=pattern, name~=regex, a pattern with '*' or '?', or '/regex/'), or by merged contents (format: component=type/name or vars.type.component.variable=value); comma-separated requirements are ANDed")
+	cmd.Flags().String("where", "", "filter stacks by a boolean expression of --filter requirements combined with &&, || and !, and grouped with parentheses, such as '(env=prod || team=platform) && name~=^plat-'")
+}
+
+// stackFilter compiles the --filter and --where flags of a command into an
+// expression, or returns nil if neither flag is set. An invalid filter exits.
+func stackFilter(cmd *cobra.Command) utils.Expression {
+	filters, err := cmd.Flags().GetStringArray("filter")
+	if err != nil {
+		logger.Log.Fatalf("Error getting filters: %v", err)
+	}
+	where, err := cmd.Flags().GetString("where")
+	if err != nil {
+		logger.Log.Fatalf("Error getting filters: %v", err)
+	}
+	if len(filters) == 0 && where == "" {
+		return nil
+	}
+
+	filter, err := utils.CompileFilters(filters, where)
+	if err != nil {
+		logger.Log.Fatalf("Error: %v", err)
+	}
+	return filter
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/mcalhoun/skunk/internal/logger"
+	tablerender "github.com/mcalhoun/skunk/internal/table-render"
+	"github.com/mcalhoun/skunk/internal/validator"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	sarifOutput bool
+)
+
+// validationChecks are the checks run by the validate command. Additional
+// checks can be registered here.
+var validationChecks = validator.DefaultRegistry()
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate every stack and the catalog",
+	Long: `Parse every stack matching stacksPath and every file in catalogDir and report
+all problems found: stacks that fail to parse or reference missing aliases,
+catalog anchors that do not resolve, duplicate anchors, stack names that are
+missing or used more than once, and components without vars.
+
+Exits with a non-zero status if any error is found, so it can be used in CI.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runValidateCmd(cmd, args)
+	},
+}
+
+// runValidateCmd is the implementation of the validate command
+func runValidateCmd(cmd *cobra.Command, args []string) {
+	if jsonOutput && sarifOutput {
+		logger.Log.Fatalf("Error: --json and --sarif cannot be used together")
+	}
+
+	stacksPath := viper.GetString("stacksPath")
+	if stacksPath == "" {
+		logger.Log.Fatalf("Error: stacksPath not defined in config")
+	}
+
+	catalogDir := viper.GetString("catalogDir")
+	if catalogDir == "" {
+		logger.Log.Fatalf("Error: catalogDir not defined in config")
+	}
+
+	findings, err := validateRepository(stacksPath, catalogDir)
+	if err != nil {
+		exitWithDiagnostics(err, "Error loading stacks")
+	}
+
+	switch {
+	case jsonOutput:
+		outputFindingsJSON(os.Stdout, findings)
+	case sarifOutput:
+		outputFindingsSARIF(os.Stdout, findings, validationChecks)
+	default:
+		writeFindings(os.Stdout, findings, !noColor)
+		if noColor {
+			printValidationStandardTable(findings)
+		} else {
+			printValidationBubblesTable(findings)
+		}
+	}
+
+	if validator.HasErrors(findings) {
+		os.Exit(1)
+	}
+}
+
+// validateRepository runs every validation check against the configured stacks and catalog
+func validateRepository(stacksPath, catalogDir string) ([]validator.Finding, error) {
+	opts, err := parseOptionsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := validator.Load(stacksPath, catalogDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return validator.Validate(repo, validationChecks), nil
+}
+
+// writeFindings writes each finding labeled with its check, followed by a summary line
+func writeFindings(w io.Writer, findings []validator.Finding, color bool) {
+	diags := make(yamlparser.Diagnostics, 0, len(findings))
+	for _, finding := range findings {
+		writeDiagnostic(w, finding.Diagnostic, finding.Check, color)
+		diags = append(diags, finding.Diagnostic)
+	}
+	fmt.Fprintln(w, diagnosticsSummary(diags))
+	fmt.Fprintln(w)
+}
+
+// outputFindingsJSON prints the findings as JSON
+func outputFindingsJSON(w io.Writer, findings []validator.Finding) {
+	if findings == nil {
+		findings = []validator.Finding{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) // code frames contain ">" markers
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(findings); err != nil {
+		logger.Log.Fatalf("Error marshaling to JSON: %v", err)
+	}
+}
+
+// validationSummary returns one row per check with its number of errors and warnings
+func validationSummary(findings []validator.Finding) [][]string {
+	type counts struct{ errors, warnings int }
+	byCheck := make(map[string]*counts)
+	for _, finding := range findings {
+		c, ok := byCheck[finding.Check]
+		if !ok {
+			c = &counts{}
+			byCheck[finding.Check] = c
+		}
+		if finding.Severity == yamlparser.SeverityWarning {
+			c.warnings++
+		} else {
+			c.errors++
+		}
+	}
+
+	var rows [][]string
+	for _, check := range validationChecks.Checks() {
+		c, ok := byCheck[check.Name]
+		if !ok {
+			c = &counts{}
+		}
+
+		status := "ok"
+		switch {
+		case c.errors > 0:
+			status = "failed"
+		case c.warnings > 0:
+			status = "warning"
+		}
+		rows = append(rows, []string{check.Name, status, strconv.Itoa(c.errors), strconv.Itoa(c.warnings)})
+	}
+	return rows
+}
+
+// printValidationBubblesTable prints the validation summary using the tablerender package
+func printValidationBubblesTable(findings []validator.Finding) {
+	style := tablerender.DefaultTableStyle()
+	style.Title = "VALIDATION"
+
+	table := tablerender.RenderTable([]string{"CHECK", "STATUS", "ERRORS", "WARNINGS"}, validationSummary(findings), style)
+	fmt.Println(table)
+}
+
+// printValidationStandardTable prints the validation summary as a plain text table without any styling
+func printValidationStandardTable(findings []validator.Finding) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Println("Validation Summary")
+	fmt.Println()
+	fmt.Fprintln(w, "Check\tStatus\tErrors\tWarnings")
+	fmt.Fprintln(w, "-----\t------\t------\t--------")
+
+	for _, row := range validationSummary(findings) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row[0], row[1], row[2], row[3])
+	}
+
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	// Add flags
+	validateCmd.Flags().BoolVar(&jsonOutput, "json", false, "output findings as JSON instead of a report")
+	validateCmd.Flags().BoolVar(&sarifOutput, "sarif", false, "output findings as a SARIF 2.1.0 log")
+	validateCmd.Flags().BoolVar(&noColor, "no-color", false, "disable colored output")
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mcalhoun/skunk/internal/validator"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFindings = []validator.Finding{
+	{Check: "parse", Diagnostic: &yamlparser.Diagnostic{Severity: yamlparser.SeverityError, File: "stacks/dev.yaml", Line: 4, Column: 7, Message: "could not find alias"}},
+	{Check: "duplicate-anchors", Diagnostic: &yamlparser.Diagnostic{Severity: yamlparser.SeverityWarning, File: "catalog/a.yaml", Message: "duplicate anchor"}},
+}
+
+func TestValidationSummary(t *testing.T) {
+	rows := validationSummary(testFindings)
+
+	assert.Equal(t, len(validationChecks.Checks()), len(rows))
+	assert.Equal(t, []string{"parse", "failed", "1", "0"}, rows[0])
+	for _, row := range rows {
+		if row[0] == "duplicate-anchors" {
+			assert.Equal(t, []string{"duplicate-anchors", "warning", "0", "1"}, row)
+		}
+		if row[0] == "component-vars" {
+			assert.Equal(t, []string{"component-vars", "ok", "0", "0"}, row)
+		}
+	}
+}
+
+func TestWriteFindings(t *testing.T) {
+	var buf bytes.Buffer
+	writeFindings(&buf, testFindings, false)
+
+	assert.Contains(t, buf.String(), "error[parse]: could not find alias\n  --> stacks/dev.yaml:4:7\n")
+	assert.Contains(t, buf.String(), "warning[duplicate-anchors]: duplicate anchor\n  --> catalog/a.yaml\n")
+	assert.Contains(t, buf.String(), "1 error, 1 warning in 2 files")
+}
+
+func TestOutputFindingsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	outputFindingsJSON(&buf, testFindings)
+
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 2, len(decoded))
+	assert.Equal(t, "parse", decoded[0]["check"])
+	assert.Equal(t, "stacks/dev.yaml", decoded[0]["file"])
+
+	buf.Reset()
+	outputFindingsJSON(&buf, nil)
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestOutputFindingsSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	outputFindingsSARIF(&buf, testFindings, validationChecks)
+
+	var log sarifLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, 1, len(log.Runs))
+
+	run := log.Runs[0]
+	assert.Equal(t, len(validationChecks.Checks()), len(run.Tool.Driver.Rules))
+	assert.Equal(t, 2, len(run.Results))
+
+	assert.Equal(t, "parse", run.Results[0].RuleID)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "stacks/dev.yaml", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, &sarifRegion{StartLine: 4, StartColumn: 7}, run.Results[0].Locations[0].PhysicalLocation.Region)
+
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region)
+}
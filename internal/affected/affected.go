@@ -0,0 +1,262 @@
+// Package affected finds the stacks and components whose merged documents
+// differ between two revisions of a repository
+package affected
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/validator"
+)
+
+// Status tells how a stack or component changed
+type Status string
+
+const (
+	// Added is only present at the head revision
+	Added Status = "added"
+	// Removed is only present at the base revision
+	Removed Status = "removed"
+	// Modified is present at both revisions with different contents
+	Modified Status = "modified"
+)
+
+// Component is a component whose merged contents changed
+type Component struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+}
+
+// Stack is a stack whose merged document changed
+type Stack struct {
+	Name   string `json:"stack"`
+	File   string `json:"file"`
+	Status Status `json:"status"`
+	// Components lists the components that changed. It is empty if only
+	// other parts of the stack, such as its labels, changed.
+	Components []Component `json:"components"`
+	// Causes lists the changed files the stack is built from, with the
+	// anchors the stack reaches through each of them
+	Causes []string `json:"causes,omitempty"`
+}
+
+// Revision is every stack of a repository at one revision
+type Revision struct {
+	// Root is the directory the revision was loaded from. Paths are reported
+	// relative to it so that both revisions use the same paths.
+	Root string
+	Repo *validator.Repository
+}
+
+// Find compares the merged document of every stack at two revisions, matching
+// stacks by name, and returns the stacks that changed sorted by name.
+// changedFiles are the files that differ between the revisions, relative to
+// the roots, and are used to explain why each stack changed. A stack that
+// failed to parse at a revision is treated as empty there.
+func Find(base, head Revision, changedFiles []string) []Stack {
+	changed := make(map[string]bool, len(changedFiles))
+	for _, file := range changedFiles {
+		changed[filepath.Clean(file)] = true
+	}
+
+	baseStacks := stacksByName(base)
+	headStacks := stacksByName(head)
+
+	names := make([]string, 0, len(baseStacks)+len(headStacks))
+	for name := range baseStacks {
+		names = append(names, name)
+	}
+	for name := range headStacks {
+		if _, ok := baseStacks[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var result []Stack
+	for _, name := range names {
+		oldStack, hasOld := baseStacks[name]
+		newStack, hasNew := headStacks[name]
+
+		var oldDocument, newDocument map[string]interface{}
+		if hasOld {
+			oldDocument = oldStack.Document
+		}
+		if hasNew {
+			newDocument = newStack.Document
+		}
+		if hasOld && hasNew && reflect.DeepEqual(oldDocument, newDocument) {
+			continue
+		}
+
+		stack := Stack{Name: name, Status: Modified, Components: changedComponents(oldDocument, newDocument)}
+		switch {
+		case !hasOld:
+			stack.Status = Added
+		case !hasNew:
+			stack.Status = Removed
+		}
+
+		causes := make(map[string][]string)
+		if hasOld {
+			stack.File = relativePath(base.Root, oldStack.FilePath)
+			addCauses(causes, base.Root, oldStack, changed)
+		}
+		if hasNew {
+			stack.File = relativePath(head.Root, newStack.FilePath)
+			addCauses(causes, head.Root, newStack, changed)
+		}
+		stack.Causes = formatCauses(causes)
+
+		result = append(result, stack)
+	}
+	return result
+}
+
+// stacksByName indexes the stacks of a revision by name, or by file for
+// stacks without a name
+func stacksByName(revision Revision) map[string]*validator.Stack {
+	stacks := make(map[string]*validator.Stack)
+	if revision.Repo == nil {
+		return stacks
+	}
+	for _, stack := range revision.Repo.Stacks {
+		name := stack.Name
+		if name == "" {
+			name = relativePath(revision.Root, stack.FilePath)
+		}
+		stacks[name] = stack
+	}
+	return stacks
+}
+
+// changedComponents compares the components of two merged stacks and returns
+// the ones that were added, removed or modified, sorted by type and name
+func changedComponents(old, new map[string]interface{}) []Component {
+	oldComponents := components(old)
+	newComponents := components(new)
+
+	keys := make([]Component, 0, len(oldComponents)+len(newComponents))
+	for key := range oldComponents {
+		keys = append(keys, key)
+	}
+	for key := range newComponents {
+		if _, ok := oldComponents[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].Name < keys[j].Name
+	})
+
+	result := make([]Component, 0)
+	for _, key := range keys {
+		oldValue, hasOld := oldComponents[key]
+		newValue, hasNew := newComponents[key]
+		switch {
+		case !hasOld:
+			key.Status = Added
+		case !hasNew:
+			key.Status = Removed
+		case !reflect.DeepEqual(oldValue, newValue):
+			key.Status = Modified
+		default:
+			continue
+		}
+		result = append(result, key)
+	}
+	return result
+}
+
+// components returns the components of a merged stack by type and name
+func components(document map[string]interface{}) map[Component]interface{} {
+	result := make(map[Component]interface{})
+	spec, _ := document["spec"].(map[string]interface{})
+	componentTypes, _ := spec["components"].(map[string]interface{})
+	for typeName, typeValue := range componentTypes {
+		typeComponents, _ := typeValue.(map[string]interface{})
+		for name, value := range typeComponents {
+			result[Component{Type: typeName, Name: name}] = value
+		}
+	}
+	return result
+}
+
+// addCauses records every changed file the stack is built from: the stack
+// file itself and the files its values were set in, such as imported and
+// catalog files, with the anchors the values were reached through
+func addCauses(causes map[string][]string, root string, stack *validator.Stack, changed map[string]bool) {
+	add := func(file, anchor string) {
+		file = relativePath(root, file)
+		if !changed[file] {
+			return
+		}
+		if _, ok := causes[file]; !ok {
+			causes[file] = nil
+		}
+		if anchor != "" && !contains(causes[file], anchor) {
+			causes[file] = append(causes[file], anchor)
+		}
+	}
+
+	add(stack.FilePath, "")
+	for _, prov := range stack.Provenance {
+		for _, source := range prov.Chain() {
+			add(source.File, source.Anchor)
+		}
+	}
+}
+
+// formatCauses returns each file with the anchors reached through it, sorted by file
+func formatCauses(causes map[string][]string) []string {
+	files := make([]string, 0, len(causes))
+	for file := range causes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		anchors := causes[file]
+		if len(anchors) == 0 {
+			result = append(result, file)
+			continue
+		}
+		sort.Strings(anchors)
+		label := "anchor"
+		if len(anchors) > 1 {
+			label = "anchors"
+		}
+		result = append(result, fmt.Sprintf("%s (%s %s)", file, label, strings.Join(anchors, ", ")))
+	}
+	return result
+}
+
+// relativePath returns path relative to root, or path itself if it is not below root
+func relativePath(root, path string) string {
+	if root == "" {
+		return filepath.Clean(path)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.Clean(path)
+	}
+	return rel
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
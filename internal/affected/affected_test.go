@@ -0,0 +1,92 @@
+package affected
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/mcalhoun/skunk/internal/validator"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// stack builds a stack with the given terraform components, whose vars were
+// set by the given sources
+func stack(root, name, file string, components map[string]interface{}, sources ...yamlparser.Source) *validator.Stack {
+	prov := yamlparser.ProvenanceMap{}
+	for i, source := range sources {
+		source.File = filepath.Join(root, source.File)
+		prov[string(rune('a'+i))] = &yamlparser.Provenance{Source: source}
+	}
+	return &validator.Stack{
+		StackMetadata: stackfinder.StackMetadata{Name: name, FilePath: filepath.Join(root, file)},
+		Document: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec":     map[string]interface{}{"components": map[string]interface{}{"terraform": components}},
+		},
+		Provenance: prov,
+	}
+}
+
+func TestFind(t *testing.T) {
+	vpc := map[string]interface{}{"vars": map[string]interface{}{"cidr": "10.0.0.0/16"}}
+	vpcChanged := map[string]interface{}{"vars": map[string]interface{}{"cidr": "10.1.0.0/16"}}
+	dns := map[string]interface{}{"vars": map[string]interface{}{}}
+	catalog := yamlparser.Source{File: "catalog/vpc.yaml", Anchor: "vpc-defaults"}
+
+	base := Revision{Root: "/base", Repo: &validator.Repository{Stacks: []*validator.Stack{
+		stack("/base", "dev", "stacks/dev.yaml", map[string]interface{}{"vpc": vpc, "dns": dns}, catalog),
+		stack("/base", "prod", "stacks/prod.yaml", map[string]interface{}{"vpc": vpc}, catalog),
+		stack("/base", "old", "stacks/old.yaml", map[string]interface{}{"vpc": vpc}),
+		stack("/base", "same", "stacks/same.yaml", map[string]interface{}{"vpc": vpc}),
+	}}}
+	head := Revision{Root: "/head", Repo: &validator.Repository{Stacks: []*validator.Stack{
+		stack("/head", "dev", "stacks/dev.yaml", map[string]interface{}{"vpc": vpcChanged}, catalog),
+		stack("/head", "prod", "stacks/prod.yaml", map[string]interface{}{"vpc": vpcChanged}, catalog),
+		stack("/head", "new", "stacks/new.yaml", map[string]interface{}{"vpc": vpc}),
+		stack("/head", "same", "stacks/same.yaml", map[string]interface{}{"vpc": vpc}),
+	}}}
+
+	changed := []string{"catalog/vpc.yaml", "stacks/dev.yaml", "stacks/old.yaml", "stacks/new.yaml", "stacks/same.yaml"}
+	expected := []Stack{
+		{
+			Name: "dev", File: "stacks/dev.yaml", Status: Modified,
+			Components: []Component{
+				{Type: "terraform", Name: "dns", Status: Removed},
+				{Type: "terraform", Name: "vpc", Status: Modified},
+			},
+			Causes: []string{"catalog/vpc.yaml (anchor vpc-defaults)", "stacks/dev.yaml"},
+		},
+		{
+			Name: "new", File: "stacks/new.yaml", Status: Added,
+			Components: []Component{{Type: "terraform", Name: "vpc", Status: Added}},
+			Causes:     []string{"stacks/new.yaml"},
+		},
+		{
+			Name: "old", File: "stacks/old.yaml", Status: Removed,
+			Components: []Component{{Type: "terraform", Name: "vpc", Status: Removed}},
+			Causes:     []string{"stacks/old.yaml"},
+		},
+		{
+			Name: "prod", File: "stacks/prod.yaml", Status: Modified,
+			Components: []Component{{Type: "terraform", Name: "vpc", Status: Modified}},
+			Causes:     []string{"catalog/vpc.yaml (anchor vpc-defaults)"},
+		},
+	}
+
+	result := Find(base, head, changed)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected affected stacks:\nexpected %+v\ngot      %+v", expected, result)
+	}
+}
+
+func TestFindOnlyMetadataChanged(t *testing.T) {
+	base := Revision{Repo: &validator.Repository{Stacks: []*validator.Stack{stack("", "dev", "dev.yaml", nil)}}}
+	head := Revision{Repo: &validator.Repository{Stacks: []*validator.Stack{stack("", "dev", "dev.yaml", nil)}}}
+	head.Repo.Stacks[0].Document["metadata"] = map[string]interface{}{"name": "dev", "labels": map[string]interface{}{"env": "dev"}}
+
+	result := Find(base, head, []string{"dev.yaml"})
+	if len(result) != 1 || len(result[0].Components) != 0 || result[0].Components == nil {
+		t.Errorf("Expected a stack without changed components, got %+v", result)
+	}
+}
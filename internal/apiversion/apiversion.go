@@ -0,0 +1,119 @@
+// Package apiversion knows the apiVersions a stack can declare, the schema of
+// the top-level structure of each one and how to migrate stacks between them.
+package apiversion
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/schema"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// Group is the API group of every stack apiVersion
+const Group = "skunk.mattcalhoun.com"
+
+// Latest is the apiVersion new stacks should declare
+const Latest = Group + "/v1"
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Version is a supported stack apiVersion
+type Version struct {
+	// Name is the full apiVersion, such as skunk.mattcalhoun.com/v1
+	Name string
+	// Schema describes the top-level structure of a merged stack of this version
+	Schema *schema.Schema
+
+	// migrate rewrites a stack of this version into the next one, nil for the latest version
+	migrate migration
+}
+
+// versions lists the supported apiVersions, oldest first. Each version except
+// the last migrates into the one after it.
+var versions = []*Version{
+	{Name: Group + "/v1alpha1", Schema: mustLoadSchema("v1alpha1"), migrate: moveComponents},
+	{Name: Latest, Schema: mustLoadSchema("v1")},
+}
+
+// mustLoadSchema parses an embedded schema, panicking if it is invalid
+func mustLoadSchema(name string) *schema.Schema {
+	path := "schemas/" + name + ".json"
+	data, err := schemaFiles.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	s, err := schema.Parse(data, path)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Supported returns the names of the supported apiVersions, oldest first
+func Supported() []string {
+	names := make([]string, 0, len(versions))
+	for _, version := range versions {
+		names = append(names, version.Name)
+	}
+	return names
+}
+
+// Lookup returns the version with the given name
+func Lookup(name string) (*Version, bool) {
+	_, version := index(name)
+	return version, version != nil
+}
+
+// IsLatest reports whether the version is the latest one
+func (v *Version) IsLatest() bool {
+	return v.Name == Latest
+}
+
+// index returns the position of a version in versions and the version, or -1 and nil
+func index(name string) (int, *Version) {
+	for i, version := range versions {
+		if version.Name == name {
+			return i, version
+		}
+	}
+	return -1, nil
+}
+
+// CheckStack reports the problems with the apiVersion and top-level structure
+// of a merged stack document: a missing or unsupported apiVersion, a version
+// older than Latest as a warning, and every mismatch with the version's schema.
+// Each diagnostic is positioned where the offending value was set according to prov.
+func CheckStack(stackFile string, document map[string]interface{}, prov yamlparser.ProvenanceMap) yamlparser.Diagnostics {
+	diag := func(severity yamlparser.Severity, path []string, format string, args ...interface{}) *yamlparser.Diagnostic {
+		d := &yamlparser.Diagnostic{Severity: severity, File: stackFile, Message: fmt.Sprintf(format, args...)}
+		schema.Locate(d, prov, path)
+		return d
+	}
+
+	value, ok := document["apiVersion"]
+	if !ok || value == nil {
+		return yamlparser.Diagnostics{diag(yamlparser.SeverityError, []string{"kind"},
+			"stack has no apiVersion, expected %s", Latest)}
+	}
+
+	name, _ := value.(string)
+	version, ok := Lookup(name)
+	if !ok {
+		return yamlparser.Diagnostics{diag(yamlparser.SeverityError, []string{"apiVersion"},
+			"unsupported apiVersion %q, supported versions are %s", fmt.Sprint(value), strings.Join(Supported(), ", "))}
+	}
+
+	var diags yamlparser.Diagnostics
+	if !version.IsLatest() {
+		diags = append(diags, diag(yamlparser.SeverityWarning, []string{"apiVersion"},
+			"apiVersion %s is deprecated, run \"skunk migrate\" to upgrade to %s", version.Name, Latest))
+	}
+	for _, violation := range version.Schema.Validate(document) {
+		diags = append(diags, diag(yamlparser.SeverityError, violation.Path,
+			"stack does not match %s: %s", version.Name, violation.String()))
+	}
+	return diags
+}
@@ -0,0 +1,193 @@
+package apiversion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+const legacyStack = `# Production VPC
+apiVersion: "skunk.mattcalhoun.com/v1alpha1" # the old layout
+kind: Stack
+metadata:
+  name: prod
+spec:
+  # Terraform components
+  terraform:
+    vpc: &vpc
+      vars:
+        name: vpc # inline comment
+        description: |
+          multi-line
+          text
+  imports:
+    - catalog/base.yaml
+
+  helmfile:
+    app:
+      vars: {}
+# trailing comment
+`
+
+const migratedStack = `# Production VPC
+apiVersion: "skunk.mattcalhoun.com/v1" # the old layout
+kind: Stack
+metadata:
+  name: prod
+spec:
+  # Terraform components
+  components:
+    terraform:
+      vpc: &vpc
+        vars:
+          name: vpc # inline comment
+          description: |
+            multi-line
+            text
+    helmfile:
+      app:
+        vars: {}
+  imports:
+    - catalog/base.yaml
+
+# trailing comment
+`
+
+func TestMigrate(t *testing.T) {
+	migrated, err := Migrate([]byte(legacyStack), "stack.yaml", Latest)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if string(migrated) != migratedStack {
+		t.Errorf("Expected:\n%s\nGot:\n%s", migratedStack, migrated)
+	}
+
+	// Migrating a stack that is already at the target leaves it unchanged
+	again, err := Migrate(migrated, "stack.yaml", Latest)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if string(again) != string(migrated) {
+		t.Errorf("Expected an unchanged stack, got:\n%s", again)
+	}
+
+	version, err := Detect(migrated, "stack.yaml")
+	if err != nil || version != Latest {
+		t.Errorf("Expected %s, got %q (%v)", Latest, version, err)
+	}
+}
+
+func TestMigrateKeepsReservedSpecKeys(t *testing.T) {
+	source := "apiVersion: skunk.mattcalhoun.com/v1alpha1\nkind: Stack\nmetadata:\n  name: a\nspec:\n  merge:\n    deep: true\n  terraform:\n    vpc: {vars: {}}\n  backend:\n    type: s3\n  helm:\n    app: {vars: {}}\n"
+	expected := "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: a\nspec:\n  merge:\n    deep: true\n  components:\n    terraform:\n      vpc: {vars: {}}\n    helm:\n      app: {vars: {}}\n  backend:\n    type: s3\n"
+
+	migrated, err := Migrate([]byte(source), "stack.yaml", Latest)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if string(migrated) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, migrated)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		target   string
+		expected string
+	}{
+		"unsupported target":  {"apiVersion: skunk.mattcalhoun.com/v1\n", "v2", `unsupported apiVersion "v2"`},
+		"unsupported version": {"apiVersion: v1\nkind: Stack\n", Latest, `stack.yaml:1:13: unsupported apiVersion "v1"`},
+		"no apiVersion":       {"kind: Stack\n", Latest, "stack.yaml: stack has no apiVersion"},
+		"downgrade":           {"apiVersion: skunk.mattcalhoun.com/v1\n", Group + "/v1alpha1", "cannot migrate from skunk.mattcalhoun.com/v1 to the older"},
+		"flow spec":           {"apiVersion: skunk.mattcalhoun.com/v1alpha1\nspec: {terraform: {}}\n", Latest, "spec must be a block mapping"},
+		"components in spec":  {"apiVersion: skunk.mattcalhoun.com/v1alpha1\nspec:\n  components: {}\n", Latest, "spec already has a components key"},
+		"merge key in spec":   {"globals: &globals\n  imports: [catalog/globals]\napiVersion: skunk.mattcalhoun.com/v1alpha1\nspec:\n  imports: [catalog/vpc]\n  <<: *globals\n  terraform: {}\n", Latest, "spec has a merge key (<<); move the component types under spec.components by hand"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Migrate([]byte(tc.source), "stack.yaml", tc.target)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckStack(t *testing.T) {
+	dir := t.TempDir()
+	testCases := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{
+			name:   "Valid",
+			source: "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: a\n  labels: {env: dev, count: 2}\nspec:\n  components:\n    terraform:\n      vpc: {vars: {}}\n",
+		},
+		{
+			name:   "Typos",
+			source: "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetdata:\n  name: a\nspec:\n  compnents: {}\n",
+			expected: []string{
+				`stack.yaml: stack does not match skunk.mattcalhoun.com/v1: missing required property "metadata"`,
+				`stack.yaml:3:1: stack does not match skunk.mattcalhoun.com/v1: metdata: unknown property, did you mean "metadata"?`,
+				`stack.yaml:6:3: stack does not match skunk.mattcalhoun.com/v1: spec.compnents: unknown property, did you mean "components"?`,
+			},
+		},
+		{
+			name:   "Deprecated",
+			source: "apiVersion: skunk.mattcalhoun.com/v1alpha1\nkind: Stack\nmetadata:\n  name: a\nspec:\n  terraform:\n    vpc: {vars: {}}\n",
+			expected: []string{
+				`stack.yaml:1:1: apiVersion skunk.mattcalhoun.com/v1alpha1 is deprecated, run "skunk migrate" to upgrade to skunk.mattcalhoun.com/v1`,
+			},
+		},
+		{
+			name:     "Unsupported",
+			source:   "apiVersion: v1\nkind: Stack\n",
+			expected: []string{`stack.yaml:1:1: unsupported apiVersion "v1", supported versions are skunk.mattcalhoun.com/v1alpha1, skunk.mattcalhoun.com/v1`},
+		},
+		{
+			name:     "Missing",
+			source:   "kind: Stack\n",
+			expected: []string{`stack.yaml:1:1: stack has no apiVersion, expected skunk.mattcalhoun.com/v1`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, "stack.yaml")
+			if err := os.WriteFile(path, []byte(tc.source), 0600); err != nil {
+				t.Fatalf("Failed to write stack: %v", err)
+			}
+			document, prov, err := yamlparser.ParseStackWithProvenance(path, dir, yamlparser.DefaultOptions())
+			if err != nil {
+				t.Fatalf("ParseStackWithProvenance failed: %v", err)
+			}
+
+			diags := CheckStack(path, document, prov)
+			if len(diags) != len(tc.expected) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tc.expected), diags)
+			}
+			for i, diag := range diags {
+				if expected := filepath.Join(dir, tc.expected[i]); diag.Error() != expected {
+					t.Errorf("Expected %q, got %q", expected, diag.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	for _, version := range versions {
+		if version.Schema == nil || version.Schema.Title != "Stack "+version.Name {
+			t.Errorf("Expected the schema of %s to be titled after it", version.Name)
+		}
+		if (version.migrate == nil) != version.IsLatest() {
+			t.Errorf("Expected every version but the latest to have a migration, %s does not", version.Name)
+		}
+	}
+}
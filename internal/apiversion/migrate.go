@@ -0,0 +1,264 @@
+package apiversion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// migration rewrites the source of a stack into the next apiVersion. It edits
+// the source text at the positions of the parsed nodes, so comments, anchors
+// and formatting are preserved.
+type migration func(source []byte, root []*ast.MappingValueNode) ([]byte, error)
+
+// Detect returns the apiVersion declared by the source of a stack
+func Detect(source []byte, file string) (string, error) {
+	root, err := parseRoot(source, file)
+	if err != nil {
+		return "", err
+	}
+	value, err := apiVersionToken(root, file)
+	if err != nil {
+		return "", err
+	}
+	return value.Value, nil
+}
+
+// Migrate rewrites the source of a stack to the target apiVersion, applying
+// every migration between the stack's apiVersion and target in turn. It
+// returns the source unchanged if the stack already declares target, and an
+// error if the stack declares a newer or unsupported apiVersion.
+func Migrate(source []byte, file, target string) ([]byte, error) {
+	targetIndex, _ := index(target)
+	if targetIndex < 0 {
+		return nil, fmt.Errorf("unsupported apiVersion %q, supported versions are %s", target, strings.Join(Supported(), ", "))
+	}
+
+	for {
+		root, err := parseRoot(source, file)
+		if err != nil {
+			return nil, err
+		}
+		value, err := apiVersionToken(root, file)
+		if err != nil {
+			return nil, err
+		}
+
+		i, version := index(value.Value)
+		switch {
+		case version == nil:
+			return nil, fmt.Errorf("%s:%d:%d: unsupported apiVersion %q, supported versions are %s",
+				file, value.Position.Line, value.Position.Column, value.Value, strings.Join(Supported(), ", "))
+		case i == targetIndex:
+			return source, nil
+		case i > targetIndex:
+			return nil, fmt.Errorf("%s: cannot migrate from %s to the older %s", file, version.Name, target)
+		}
+
+		if source, err = version.migrate(source, root); err != nil {
+			return nil, fmt.Errorf("%s: failed to migrate from %s: %w", file, version.Name, err)
+		}
+
+		// The migration may move lines, so find the apiVersion again before replacing it
+		if root, err = parseRoot(source, file); err != nil {
+			return nil, fmt.Errorf("%s: migration from %s produced invalid YAML: %w", file, version.Name, err)
+		}
+		if value, err = apiVersionToken(root, file); err != nil {
+			return nil, err
+		}
+		source = replaceScalar(source, value, versions[i+1].Name)
+	}
+}
+
+// parseRoot parses the source of a stack and returns the entries of its top-level mapping
+func parseRoot(source []byte, file string) ([]*ast.MappingValueNode, error) {
+	f, err := parser.ParseBytes(source, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	if len(f.Docs) != 1 {
+		return nil, fmt.Errorf("%s: expected a single YAML document, found %d", file, len(f.Docs))
+	}
+
+	entries, flow := mappingEntries(f.Docs[0].Body)
+	if entries == nil || flow {
+		return nil, fmt.Errorf("%s: expected the stack to be a block mapping", file)
+	}
+	return entries, nil
+}
+
+// mappingEntries returns the entries of a mapping node and whether it is in
+// flow style, or nil if the node is not a mapping
+func mappingEntries(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values, n.IsFlowStyle
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, false
+	case *ast.AnchorNode:
+		return mappingEntries(n.Value)
+	}
+	return nil, false
+}
+
+// entry returns the entry with the given key, or nil
+func entry(entries []*ast.MappingValueNode, key string) *ast.MappingValueNode {
+	for _, e := range entries {
+		if e.Key.GetToken() != nil && e.Key.GetToken().Value == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// apiVersionToken returns the token of the apiVersion value
+func apiVersionToken(root []*ast.MappingValueNode, file string) (*token.Token, error) {
+	e := entry(root, "apiVersion")
+	if e == nil {
+		return nil, fmt.Errorf("%s: stack has no apiVersion", file)
+	}
+	if _, ok := e.Value.(*ast.StringNode); !ok {
+		tk := e.Value.GetToken()
+		return nil, fmt.Errorf("%s:%d:%d: apiVersion must be a string", file, tk.Position.Line, tk.Position.Column)
+	}
+	return e.Value.GetToken(), nil
+}
+
+// replaceScalar replaces the text of a single-line scalar token with value,
+// keeping the quotes of a quoted scalar
+func replaceScalar(source []byte, tk *token.Token, value string) []byte {
+	lines := strings.SplitAfter(string(source), "\n")
+	line := lines[tk.Position.Line-1]
+
+	start := len(string([]rune(line)[:tk.Position.Column-1]))
+	rest := line[start:]
+
+	var end int
+	switch quote := rest[0]; quote {
+	case '"', '\'':
+		end = strings.IndexByte(rest[1:], quote) + 2
+		value = string(quote) + value + string(quote)
+	default:
+		end = len(rest)
+		if i := strings.Index(rest, " #"); i >= 0 {
+			end = i
+		}
+		end = len(strings.TrimRight(rest[:end], " \t\r\n"))
+	}
+
+	lines[tk.Position.Line-1] = line[:start] + value + rest[end:]
+	return []byte(strings.Join(lines, ""))
+}
+
+// reservedSpecKeys are the entries of a v1alpha1 spec that are settings of the
+// stack rather than component types, so they stay directly under spec
+var reservedSpecKeys = map[string]bool{
+	"imports": true,
+	"merge":   true,
+	"backend": true,
+}
+
+// moveComponents migrates v1alpha1 to v1. v1alpha1 stacks list components by
+// type directly under spec; v1 stacks nest them under spec.components. Every
+// entry of spec other than the reserved keys is indented under a new
+// components key placed where the first of them was.
+func moveComponents(source []byte, root []*ast.MappingValueNode) ([]byte, error) {
+	spec := entry(root, "spec")
+	if spec == nil {
+		return source, nil
+	}
+	entries, flow := mappingEntries(spec.Value)
+	if flow {
+		return nil, fmt.Errorf("spec must be a block mapping to be migrated")
+	}
+	if entries == nil {
+		return source, nil
+	}
+	if entry(entries, "components") != nil {
+		return nil, fmt.Errorf("spec already has a components key; move the component types under it by hand")
+	}
+	// A merge key may carry reserved keys as well as component types, so which
+	// of them belong under components cannot be told from the stack alone
+	if entry(entries, "<<") != nil {
+		return nil, fmt.Errorf("spec has a merge key (<<); move the component types under spec.components by hand")
+	}
+
+	lines := strings.SplitAfter(string(source), "\n")
+
+	// spec ends where the next top-level key starts
+	specEnd := len(lines) + 1
+	for _, e := range root {
+		if line := e.Key.GetToken().Position.Line; line > spec.Key.GetToken().Position.Line && line < specEnd {
+			specEnd = line
+		}
+	}
+
+	// Each entry spans from its key to the next entry's key. Lines are 1-based.
+	type span struct {
+		start, end int
+		move       bool
+	}
+	spans := make([]span, len(entries))
+	for i, e := range entries {
+		spans[i].start = e.Key.GetToken().Position.Line
+		spans[i].move = !reservedSpecKeys[e.Key.GetToken().Value]
+		if i+1 < len(entries) {
+			spans[i].end = entries[i+1].Key.GetToken().Position.Line
+		} else {
+			spans[i].end = specEnd
+		}
+		if spans[i].start <= spec.Key.GetToken().Position.Line {
+			return nil, fmt.Errorf("spec must be a block mapping to be migrated")
+		}
+	}
+
+	indent := strings.Repeat(" ", entries[0].Key.GetToken().Position.Column-1)
+
+	// Blank lines and comments less indented than the entries that follow the
+	// last entry belong to whatever comes after spec, so they stay in place
+	last := &spans[len(spans)-1]
+	trailing := last.end
+	for trailing > last.start+1 {
+		line := lines[trailing-2]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && (!strings.HasPrefix(trimmed, "#") || strings.HasPrefix(line, indent)) {
+			break
+		}
+		trailing--
+	}
+	last.end = trailing
+
+	var moved []string
+	for _, s := range spans {
+		if !s.move {
+			continue
+		}
+		for _, line := range lines[s.start-1 : s.end-1] {
+			if strings.TrimSpace(line) != "" {
+				line = indent + line
+			}
+			moved = append(moved, line)
+		}
+	}
+	if len(moved) == 0 {
+		return source, nil
+	}
+
+	result := append([]string{}, lines[:spans[0].start-1]...)
+	placed := false
+	for _, s := range spans {
+		switch {
+		case !s.move:
+			result = append(result, lines[s.start-1:s.end-1]...)
+		case !placed:
+			result = append(result, indent+"components:\n")
+			result = append(result, moved...)
+			placed = true
+		}
+	}
+	result = append(result, lines[trailing-1:]...)
+	return []byte(strings.Join(result, "")), nil
+}
@@ -0,0 +1,119 @@
+// Package diff compares merged stack documents and reports the paths whose
+// values were added, removed or changed.
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of a change
+type Kind string
+
+const (
+	// Added is a path only present in the new document
+	Added Kind = "added"
+	// Removed is a path only present in the old document
+	Removed Kind = "removed"
+	// Changed is a path whose value differs between the documents
+	Changed Kind = "changed"
+)
+
+// Change is a difference between two documents at a path
+type Change struct {
+	// Path is the dot-separated path of the value, with list elements
+	// addressed by their index
+	Path string      `json:"path"`
+	Kind Kind        `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Compare deep-compares two documents decoded from YAML or JSON and returns
+// their differences ordered by path. Mappings are compared key by key and lists
+// element by element, so only the values that differ are reported. A value
+// whose type changes, such as a list replaced by a mapping, is reported as a
+// single change. prefix is prepended to the path of every change.
+func Compare(old, new interface{}, prefix ...string) []Change {
+	var changes []Change
+	compare(&changes, append([]string{}, prefix...), old, new, true, true)
+	return changes
+}
+
+// compare appends the differences between old and new at path. hasOld and hasNew
+// tell whether the value is present, so that nil values can be told apart from
+// missing ones.
+func compare(changes *[]Change, path []string, old, new interface{}, hasOld, hasNew bool) {
+	switch {
+	case !hasOld && !hasNew:
+		return
+	case !hasOld:
+		*changes = append(*changes, Change{Path: joinPath(path), Kind: Added, New: new})
+		return
+	case !hasNew:
+		*changes = append(*changes, Change{Path: joinPath(path), Kind: Removed, Old: old})
+		return
+	}
+
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		for _, key := range mergedKeys(oldMap, newMap) {
+			oldValue, hasOld := oldMap[key]
+			newValue, hasNew := newMap[key]
+			compare(changes, append(path, key), oldValue, newValue, hasOld, hasNew)
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if oldIsList && newIsList {
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			var oldValue, newValue interface{}
+			if i < len(oldList) {
+				oldValue = oldList[i]
+			}
+			if i < len(newList) {
+				newValue = newList[i]
+			}
+			compare(changes, append(path, strconv.Itoa(i)), oldValue, newValue, i < len(oldList), i < len(newList))
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: joinPath(path), Kind: Changed, Old: old, New: new})
+	}
+}
+
+// mergedKeys returns the keys of both maps in sorted order
+func mergedKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// joinPath returns a path as a dot-separated string
+func joinPath(path []string) string {
+	return strings.Join(path, ".")
+}
+
+// Summary counts the changes of each kind
+func Summary(changes []Change) map[Kind]int {
+	counts := map[Kind]int{Added: 0, Removed: 0, Changed: 0}
+	for _, change := range changes {
+		counts[change.Kind]++
+	}
+	return counts
+}
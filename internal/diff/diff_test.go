@@ -0,0 +1,61 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	old := map[string]interface{}{
+		"name":    "dev",
+		"enabled": true,
+		"removed": nil,
+		"zones":   []interface{}{"a", "b", "c"},
+		"tags":    map[string]interface{}{"env": "dev", "team": "platform"},
+		"shape":   []interface{}{"x"},
+	}
+	new := map[string]interface{}{
+		"name":    "prod",
+		"enabled": true,
+		"added":   false,
+		"zones":   []interface{}{"a", "d"},
+		"tags":    map[string]interface{}{"env": "prod", "team": "platform", "tier": 1},
+		"shape":   map[string]interface{}{"x": 1},
+	}
+
+	expected := []Change{
+		{Path: "added", Kind: Added, New: false},
+		{Path: "name", Kind: Changed, Old: "dev", New: "prod"},
+		{Path: "removed", Kind: Removed},
+		{Path: "shape", Kind: Changed, Old: []interface{}{"x"}, New: map[string]interface{}{"x": 1}},
+		{Path: "tags.env", Kind: Changed, Old: "dev", New: "prod"},
+		{Path: "tags.tier", Kind: Added, New: 1},
+		{Path: "zones.1", Kind: Changed, Old: "b", New: "d"},
+		{Path: "zones.2", Kind: Removed, Old: "c"},
+	}
+
+	changes := Compare(old, new)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Unexpected changes:\nexpected %#v\ngot      %#v", expected, changes)
+	}
+
+	summary := Summary(changes)
+	if summary[Added] != 2 || summary[Removed] != 2 || summary[Changed] != 4 {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	doc := map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": 1}}}
+	if changes := Compare(doc, doc); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+func TestComparePrefix(t *testing.T) {
+	changes := Compare(map[string]interface{}{}, map[string]interface{}{"vpc": map[string]interface{}{"vars": nil}}, "spec", "components", "terraform")
+	expected := []Change{{Path: "spec.components.terraform.vpc", Kind: Added, New: map[string]interface{}{"vars": nil}}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
@@ -0,0 +1,148 @@
+// Package git reads revisions of the repository skunk runs in with the git CLI
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// run runs git in dir and returns its standard output. The error includes
+// what git wrote to standard error.
+func run(dir string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// ResolveCommit returns the commit id a revision such as a branch, tag or
+// HEAD~1 refers to
+func ResolveCommit(dir, rev string) (string, error) {
+	out, err := run(dir, nil, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Export writes the files under paths as they are at a revision to dest,
+// keeping their paths relative to dir. Files outside dir are not exported.
+func Export(dir, rev string, paths []string, dest string) error {
+	commit, err := ResolveCommit(dir, rev)
+	if err != nil {
+		return err
+	}
+
+	// List the blobs under the paths, with names relative to dir
+	args := append([]string{"ls-tree", "-r", "-z", commit, "--"}, paths...)
+	out, err := run(dir, nil, args...)
+	if err != nil {
+		return err
+	}
+
+	var names, objects []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// Each entry is "<mode> <type> <object>\t<name>"
+		meta, name, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 || fields[1] != "blob" || strings.HasPrefix(name, "../") {
+			continue
+		}
+		names = append(names, name)
+		objects = append(objects, fields[2])
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	// Read every blob with a single cat-file process
+	out, err = run(dir, strings.NewReader(strings.Join(objects, "\n")+"\n"), "cat-file", "--batch")
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(out))
+	for _, name := range names {
+		content, err := readBatchObject(reader)
+		if err != nil {
+			return fmt.Errorf("reading %s at %s: %w", name, rev, err)
+		}
+
+		path := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBatchObject reads one object from the output of git cat-file --batch,
+// which is a "<object> <type> <size>" line followed by the contents and a newline
+func readBatchObject(reader *bufio.Reader) ([]byte, error) {
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected cat-file output %q", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected cat-file output %q", strings.TrimSpace(header))
+	}
+
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return content[:size], nil
+}
+
+// ChangedFiles returns the files that differ between two revisions, relative
+// to dir. Only files inside dir are listed. A renamed file is listed under
+// both its old and new names.
+func ChangedFiles(dir, base, head string) ([]string, error) {
+	var commits []string
+	for _, rev := range []string{base, head} {
+		commit, err := ResolveCommit(dir, rev)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+
+	out, err := run(dir, nil, "diff", "--name-only", "--no-renames", "--relative", "-z", commits[0], commits[1], "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			files = append(files, filepath.FromSlash(name))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,144 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// initRepo creates a repository with a commit of the given files and returns its directory
+func initRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		if _, err := run(dir, nil, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	return dir
+}
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := initRepo(t, map[string]string{
+		"infra/stacks/dev.yaml":    "name: dev\n",
+		"infra/catalog/vpc.yaml":   "vpc: &vpc\n  cidr: 10.0.0.0/16\n",
+		"infra/catalog/empty.yaml": "",
+		"other/ignored.yaml":       "ignored: true\n",
+	})
+
+	// Later changes are not exported
+	writeFiles(t, dir, map[string]string{"infra/stacks/dev.yaml": "name: changed\n"})
+
+	dest := t.TempDir()
+	if err := Export(filepath.Join(dir, "infra"), "HEAD", []string{"stacks", "catalog"}, dest); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"stacks/dev.yaml":    "name: dev\n",
+		"catalog/vpc.yaml":   "vpc: &vpc\n  cidr: 10.0.0.0/16\n",
+		"catalog/empty.yaml": "",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("Failed to read %s: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, string(data))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "other")); !os.IsNotExist(err) {
+		t.Error("Expected files outside the paths not to be exported")
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	dir := initRepo(t, map[string]string{"a.txt": "a\n"})
+
+	commit, err := ResolveCommit(dir, "HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit failed: %v", err)
+	}
+	if len(commit) != 40 {
+		t.Errorf("Expected a commit id, got %q", commit)
+	}
+
+	if _, err := ResolveCommit(dir, "missing-branch"); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+	if err := Export(dir, "missing-branch", []string{"."}, t.TempDir()); err == nil {
+		t.Error("Expected Export to fail for an unknown revision")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := initRepo(t, map[string]string{
+		"infra/stacks/dev.yaml":  "name: dev\n",
+		"infra/stacks/old.yaml":  "name: old\n",
+		"infra/catalog/vpc.yaml": "vpc: {}\n",
+		"README.md":              "readme\n",
+	})
+
+	writeFiles(t, dir, map[string]string{
+		"infra/catalog/vpc.yaml": "vpc: {cidr: 10.0.0.0/16}\n",
+		"infra/stacks/new.yaml":  "name: old\n",
+		"README.md":              "changed\n",
+	})
+	if err := os.Remove(filepath.Join(dir, "infra", "stacks", "old.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "change"},
+	} {
+		if _, err := run(dir, nil, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	files, err := ChangedFiles(filepath.Join(dir, "infra"), "HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("ChangedFiles failed: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join("catalog", "vpc.yaml"),
+		filepath.Join("stacks", "new.yaml"),
+		filepath.Join("stacks", "old.yaml"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	if _, err := ChangedFiles(dir, "missing-branch", "HEAD"); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+}
@@ -0,0 +1,385 @@
+package hcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenType identifies the kind of a token
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNewline
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+// token is a lexical token. For strings, value holds the unescaped text with
+// interpolation sequences left as written.
+type token struct {
+	typ    tokenType
+	text   string
+	value  string
+	start  int
+	end    int
+	line   int
+	column int
+}
+
+// punctuation lists the operators and delimiters, longest first
+var punctuation = []string{
+	"...", "==", "!=", "<=", ">=", "&&", "||", "=>",
+	"{", "}", "[", "]", "(", ")", "=", ",", ":", ".", "?", "!", "<", ">", "+", "-", "*", "/", "%",
+}
+
+// lexer splits HCL source into tokens
+type lexer struct {
+	src    string
+	file   string
+	pos    int
+	line   int
+	column int
+}
+
+// tokenize returns every token in src, ending with an EOF token
+func tokenize(src, file string) ([]token, error) {
+	l := &lexer{src: src, file: file, line: 1, column: 1}
+
+	var tokens []token
+	for {
+		tk, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tk)
+		if tk.typ == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+// errorf returns an error positioned at the current line and column
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d:%d: %s", l.file, l.line, l.column, fmt.Sprintf(format, args...))
+}
+
+// advance moves past n bytes, tracking lines and columns
+func (l *lexer) advance(n int) {
+	for i := 0; i < n; i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+		l.pos++
+	}
+}
+
+// next returns the next token, skipping spaces and comments
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+
+	tk := token{start: l.pos, line: l.line, column: l.column}
+	if l.pos >= len(l.src) {
+		tk.typ = tokenEOF
+		tk.end = l.pos
+		return tk, nil
+	}
+
+	c := l.src[l.pos]
+	rest := l.src[l.pos:]
+	switch {
+	case c == '\n':
+		tk.typ = tokenNewline
+		l.advance(1)
+	case c == '"':
+		value, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		tk.typ = tokenString
+		tk.value = value
+	case strings.HasPrefix(rest, "<<") && (len(rest) > 2 && (rest[2] == '-' || isIdentStart(rune(rest[2])))):
+		value, err := l.heredoc()
+		if err != nil {
+			return token{}, err
+		}
+		tk.typ = tokenString
+		tk.value = value
+	case c >= '0' && c <= '9':
+		tk.typ = tokenNumber
+		l.number()
+	default:
+		r, size := utf8.DecodeRuneInString(rest)
+		if isIdentStart(r) {
+			tk.typ = tokenIdent
+			l.advance(size)
+			for l.pos < len(l.src) {
+				r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+				if !isIdentPart(r) {
+					break
+				}
+				l.advance(size)
+			}
+			break
+		}
+
+		for _, p := range punctuation {
+			if strings.HasPrefix(rest, p) {
+				tk.typ = tokenPunct
+				l.advance(len(p))
+				break
+			}
+		}
+		if tk.typ != tokenPunct {
+			return token{}, l.errorf("unexpected character %q", r)
+		}
+	}
+
+	tk.end = l.pos
+	tk.text = l.src[tk.start:tk.end]
+	return tk, nil
+}
+
+// skipSpace skips whitespace other than newlines, and comments. A line
+// comment ends before its newline so the newline still terminates attributes.
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		rest := l.src[l.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r':
+			l.advance(1)
+		case rest[0] == '#' || strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			l.advance(end)
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return l.errorf("unterminated comment")
+			}
+			l.advance(end + 4)
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// number consumes a number literal
+func (l *lexer) number() {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c >= '0' && c <= '9', c == '.' && l.pos+1 < len(l.src) && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9':
+			l.advance(1)
+		case (c == 'e' || c == 'E') && l.pos+1 < len(l.src):
+			l.advance(1)
+			if l.src[l.pos] == '+' || l.src[l.pos] == '-' {
+				l.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// quoted consumes a quoted string and returns its unescaped value. Template
+// sequences such as ${var.name} are kept as written, including any quotes
+// inside them.
+func (l *lexer) quoted() (string, error) {
+	l.advance(1) // opening quote
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		rest := l.src[l.pos:]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return b.String(), nil
+		case c == '\n':
+			return "", l.errorf("unterminated string")
+		case c == '\\':
+			value, size, err := unescape(rest)
+			if err != nil {
+				return "", l.errorf("%v", err)
+			}
+			b.WriteString(value)
+			l.advance(size)
+		case strings.HasPrefix(rest, "$${") || strings.HasPrefix(rest, "%%{"):
+			b.WriteString(rest[1:3])
+			l.advance(3)
+		case strings.HasPrefix(rest, "${") || strings.HasPrefix(rest, "%{"):
+			size, err := templateSequence(rest)
+			if err != nil {
+				return "", l.errorf("%v", err)
+			}
+			b.WriteString(rest[:size])
+			l.advance(size)
+		default:
+			b.WriteByte(c)
+			l.advance(1)
+		}
+	}
+	return "", l.errorf("unterminated string")
+}
+
+// heredoc consumes a <<MARKER or <<-MARKER heredoc and returns its content.
+// The indented form strips the indentation common to every line.
+func (l *lexer) heredoc() (string, error) {
+	l.advance(2)
+	indented := false
+	if l.src[l.pos] == '-' {
+		indented = true
+		l.advance(1)
+	}
+
+	start := l.pos
+	for l.pos < len(l.src) && isIdentPart(rune(l.src[l.pos])) {
+		l.advance(1)
+	}
+	marker := l.src[start:l.pos]
+	if marker == "" || l.pos >= len(l.src) || (l.src[l.pos] != '\n' && !strings.HasPrefix(l.src[l.pos:], "\r\n")) {
+		return "", l.errorf("invalid heredoc marker")
+	}
+	for l.src[l.pos] != '\n' {
+		l.advance(1)
+	}
+	l.advance(1)
+
+	var lines []string
+	for l.pos < len(l.src) {
+		end := strings.IndexByte(l.src[l.pos:], '\n')
+		if end < 0 {
+			end = len(l.src) - l.pos
+		}
+		line := strings.TrimSuffix(l.src[l.pos:l.pos+end], "\r")
+		if strings.TrimSpace(line) == marker {
+			l.advance(len(strings.TrimRight(l.src[l.pos:l.pos+end], " \t\r")))
+			if indented {
+				lines = stripIndent(lines)
+			}
+			if len(lines) == 0 {
+				return "", nil
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		}
+		lines = append(lines, unescapeTemplates(line))
+		l.advance(end)
+		if l.pos < len(l.src) {
+			l.advance(1)
+		}
+	}
+	return "", l.errorf("heredoc %s is not terminated", marker)
+}
+
+// unescape decodes the escape sequence at the start of s, returning its value and length
+func unescape(s string) (string, int, error) {
+	if len(s) < 2 {
+		return "", 0, fmt.Errorf("invalid escape sequence")
+	}
+	switch s[1] {
+	case 'n':
+		return "\n", 2, nil
+	case 't':
+		return "\t", 2, nil
+	case 'r':
+		return "\r", 2, nil
+	case '"':
+		return "\"", 2, nil
+	case '\\':
+		return "\\", 2, nil
+	case 'u', 'U':
+		size := 6
+		if s[1] == 'U' {
+			size = 10
+		}
+		if len(s) < size {
+			return "", 0, fmt.Errorf("invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(s[2:size], 16, 32)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid unicode escape %q", s[:size])
+		}
+		return string(rune(n)), size, nil
+	}
+	return "", 0, fmt.Errorf("invalid escape sequence %q", s[:2])
+}
+
+// templateSequence returns the length of the ${...} or %{...} sequence at the
+// start of s, skipping nested braces and quoted strings
+func templateSequence(s string) (int, error) {
+	depth := 0
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		case c == '\n':
+			return 0, fmt.Errorf("unterminated template sequence")
+		}
+	}
+	return 0, fmt.Errorf("unterminated template sequence")
+}
+
+// unescapeTemplates turns the escaped $${ and %%{ sequences of a heredoc line into ${ and %{
+func unescapeTemplates(s string) string {
+	s = strings.ReplaceAll(s, "$${", "${")
+	return strings.ReplaceAll(s, "%%{", "%{")
+}
+
+// stripIndent removes the indentation shared by every non-blank line
+func stripIndent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			line = line[indent:]
+		}
+		result[i] = line
+	}
+	return result
+}
+
+// isIdentStart reports whether r can start an identifier
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// isIdentPart reports whether r can continue an identifier
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
@@ -0,0 +1,647 @@
+// Package hcl reads the subset of HCL used to declare Terraform module inputs.
+// It parses bodies of attributes and blocks and keeps expressions both as
+// source text and, where they are literals, calls or references, as values.
+package hcl
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ExprKind identifies the shape of an expression
+type ExprKind int
+
+const (
+	// KindOther is any expression not covered by the other kinds, such as one
+	// using operators. Only its source text is available.
+	KindOther ExprKind = iota
+	// KindLiteral is a string, number, bool or null literal
+	KindLiteral
+	// KindTuple is a [...] expression
+	KindTuple
+	// KindObject is a {...} expression
+	KindObject
+	// KindCall is a function call such as list(string)
+	KindCall
+	// KindReference is a bare name or traversal such as string or var.name
+	KindReference
+)
+
+// Expression is a parsed HCL expression
+type Expression struct {
+	Kind   ExprKind
+	Source string
+	Line   int
+	Column int
+
+	// Value holds the value of a literal: string, int64, float64, bool or nil
+	Value interface{}
+	// Items holds the elements of a tuple or the arguments of a call
+	Items []*Expression
+	// Fields holds the items of an object, in source order
+	Fields []*Field
+	// Name holds the function of a call or the traversal of a reference
+	Name string
+}
+
+// Field is a key and value in an object expression
+type Field struct {
+	Key   string
+	Value *Expression
+}
+
+// Attribute is a name = expression pair in a body
+type Attribute struct {
+	Name string
+	Expr *Expression
+	Line int
+}
+
+// Block is a block such as variable "name" { ... }
+type Block struct {
+	Type   string
+	Labels []string
+	Body   *Body
+	Line   int
+}
+
+// Body is the content of a file or block
+type Body struct {
+	Attributes []*Attribute
+	Blocks     []*Block
+}
+
+// File is a parsed HCL file
+type File struct {
+	Name string
+	Body *Body
+}
+
+// Attribute returns the attribute with the given name, or nil
+func (b *Body) Attribute(name string) *Attribute {
+	for _, attr := range b.Attributes {
+		if attr.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+// BlocksOfType returns the blocks of the given type, in source order
+func (b *Body) BlocksOfType(typ string) []*Block {
+	var blocks []*Block
+	for _, block := range b.Blocks {
+		if block.Type == typ {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Literal returns the Go value of an expression made only of literals, tuples
+// and objects. Tuples become []interface{} and objects map[string]interface{}.
+// It reports false for any other expression.
+func (e *Expression) Literal() (interface{}, bool) {
+	switch e.Kind {
+	case KindLiteral:
+		return e.Value, true
+	case KindTuple:
+		items := make([]interface{}, 0, len(e.Items))
+		for _, item := range e.Items {
+			value, ok := item.Literal()
+			if !ok {
+				return nil, false
+			}
+			items = append(items, value)
+		}
+		return items, true
+	case KindObject:
+		fields := make(map[string]interface{}, len(e.Fields))
+		for _, field := range e.Fields {
+			value, ok := field.Value.Literal()
+			if !ok {
+				return nil, false
+			}
+			fields[field.Key] = value
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// ParseFile reads and parses an HCL file
+func ParseFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return Parse(data, path)
+}
+
+// Parse parses HCL source. The file name is only used in error messages.
+func Parse(data []byte, file string) (*File, error) {
+	src := string(data)
+	tokens, err := tokenize(src, file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: src, file: file, tokens: tokens}
+	body, err := p.body(false)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Name: file, Body: body}, nil
+}
+
+// parser builds a body from tokens
+type parser struct {
+	src    string
+	file   string
+	tokens []token
+	pos    int
+	// nested counts the brackets the parser is inside; newlines are insignificant when it is positive
+	nested int
+}
+
+// peek returns the next significant token
+func (p *parser) peek() token {
+	p.skipNewlines()
+	return p.tokens[p.pos]
+}
+
+// skipNewlines skips newlines inside brackets, where they carry no meaning
+func (p *parser) skipNewlines() {
+	if p.nested == 0 {
+		return
+	}
+	for p.tokens[p.pos].typ == tokenNewline {
+		p.pos++
+	}
+}
+
+// take returns the next significant token and moves past it
+func (p *parser) take() token {
+	tk := p.peek()
+	if tk.typ != tokenEOF {
+		p.pos++
+	}
+	return tk
+}
+
+// is reports whether the next token is the given punctuation
+func (p *parser) is(punct string) bool {
+	tk := p.peek()
+	return tk.typ == tokenPunct && tk.text == punct
+}
+
+// expect consumes the given punctuation or fails
+func (p *parser) expect(punct string) (token, error) {
+	tk := p.take()
+	if tk.typ != tokenPunct || tk.text != punct {
+		return tk, p.errorAt(tk, "expected %q, got %s", punct, describe(tk))
+	}
+	return tk, nil
+}
+
+// errorAt returns an error positioned at a token
+func (p *parser) errorAt(tk token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d:%d: %s", p.file, tk.line, tk.column, fmt.Sprintf(format, args...))
+}
+
+// describe names a token for error messages
+func describe(tk token) string {
+	switch tk.typ {
+	case tokenEOF:
+		return "end of file"
+	case tokenNewline:
+		return "newline"
+	}
+	return strconv.Quote(tk.text)
+}
+
+// body parses attributes and blocks up to the end of the file, or up to a
+// closing brace when inBlock is set
+func (p *parser) body(inBlock bool) (*Body, error) {
+	body := &Body{}
+	for {
+		tk := p.tokens[p.pos]
+		switch {
+		case tk.typ == tokenNewline:
+			p.pos++
+			continue
+		case tk.typ == tokenEOF:
+			if inBlock {
+				return nil, p.errorAt(tk, "expected \"}\", got end of file")
+			}
+			return body, nil
+		case tk.typ == tokenPunct && tk.text == "}" && inBlock:
+			return body, nil
+		case tk.typ != tokenIdent:
+			return nil, p.errorAt(tk, "expected an attribute or block, got %s", describe(tk))
+		}
+		p.pos++
+
+		if p.is("=") {
+			p.pos++
+			expr, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.endOfLine(); err != nil {
+				return nil, err
+			}
+			body.Attributes = append(body.Attributes, &Attribute{Name: tk.text, Expr: expr, Line: tk.line})
+			continue
+		}
+
+		block := &Block{Type: tk.text, Line: tk.line}
+		for !p.is("{") {
+			label := p.take()
+			switch label.typ {
+			case tokenString:
+				block.Labels = append(block.Labels, label.value)
+			case tokenIdent:
+				block.Labels = append(block.Labels, label.text)
+			default:
+				return nil, p.errorAt(label, "expected a block label or \"{\", got %s", describe(label))
+			}
+		}
+		p.pos++
+
+		inner, err := p.body(true)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++ // closing brace
+		block.Body = inner
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+		body.Blocks = append(body.Blocks, block)
+	}
+}
+
+// endOfLine checks that an attribute or block is followed by a newline, the
+// end of the enclosing block or the end of the file
+func (p *parser) endOfLine() error {
+	tk := p.tokens[p.pos]
+	switch {
+	case tk.typ == tokenNewline:
+		p.pos++
+		return nil
+	case tk.typ == tokenEOF, tk.typ == tokenPunct && tk.text == "}":
+		return nil
+	}
+	return p.errorAt(tk, "expected a newline, got %s", describe(tk))
+}
+
+// binaryOperators are the operators that combine two expressions
+var binaryOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"&&": true, "||": true, "+": true, "-": true, "*": true, "/": true, "%": true,
+}
+
+// expression parses a full expression, including operators and conditionals.
+// Expressions with operators are kept as source text only.
+func (p *parser) expression() (*Expression, error) {
+	start := p.peek()
+	expr, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	other := false
+	for {
+		tk := p.tokens[p.pos]
+		if p.nested > 0 {
+			tk = p.peek()
+		}
+		if tk.typ != tokenPunct {
+			break
+		}
+		switch {
+		case binaryOperators[tk.text]:
+			p.pos++
+			if _, err := p.unary(); err != nil {
+				return nil, err
+			}
+		case tk.text == "?":
+			p.pos++
+			p.nested++
+			_, err := p.expression()
+			if err == nil {
+				_, err = p.expect(":")
+			}
+			p.nested--
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expression(); err != nil {
+				return nil, err
+			}
+		default:
+			if other {
+				return p.other(start), nil
+			}
+			return expr, nil
+		}
+		other = true
+	}
+
+	if other {
+		return p.other(start), nil
+	}
+	return expr, nil
+}
+
+// other returns a KindOther expression spanning from start to the last consumed token
+func (p *parser) other(start token) *Expression {
+	end := p.tokens[p.pos-1].end
+	return &Expression{Kind: KindOther, Source: p.src[start.start:end], Line: start.line, Column: start.column}
+}
+
+// unary parses an expression with an optional ! or - prefix. A negated
+// number stays a literal.
+func (p *parser) unary() (*Expression, error) {
+	start := p.peek()
+	if start.typ != tokenPunct || (start.text != "!" && start.text != "-") {
+		return p.postfix()
+	}
+	p.pos++
+
+	expr, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if start.text == "-" && expr.Kind == KindLiteral {
+		switch n := expr.Value.(type) {
+		case int64:
+			return p.literal(start, -n), nil
+		case float64:
+			return p.literal(start, -n), nil
+		}
+	}
+	return p.other(start), nil
+}
+
+// literal returns a literal expression spanning from start to the last consumed token
+func (p *parser) literal(start token, value interface{}) *Expression {
+	expr := p.other(start)
+	expr.Kind = KindLiteral
+	expr.Value = value
+	return expr
+}
+
+// postfix parses a primary expression followed by attribute access, indexes
+// and splats. Traversals of references stay references; any other traversal
+// is kept as source text.
+func (p *parser) postfix() (*Expression, error) {
+	start := p.peek()
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	traversed := false
+	for {
+		tk := p.tokens[p.pos]
+		if tk.typ != tokenPunct || (tk.text != "." && tk.text != "[") {
+			break
+		}
+		p.pos++
+		traversed = true
+
+		if tk.text == "." {
+			next := p.take()
+			if next.typ != tokenIdent && next.typ != tokenNumber && (next.typ != tokenPunct || next.text != "*") {
+				return nil, p.errorAt(next, "expected an attribute name, got %s", describe(next))
+			}
+			continue
+		}
+
+		p.nested++
+		if p.is("*") {
+			p.pos++
+		} else if _, err := p.expression(); err != nil {
+			p.nested--
+			return nil, err
+		}
+		_, err := p.expect("]")
+		p.nested--
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !traversed {
+		return expr, nil
+	}
+	result := p.other(start)
+	if expr.Kind == KindReference {
+		result.Kind = KindReference
+		result.Name = result.Source
+	}
+	return result, nil
+}
+
+// primary parses a literal, tuple, object, call, reference or parenthesized expression
+func (p *parser) primary() (*Expression, error) {
+	tk := p.take()
+	switch tk.typ {
+	case tokenString:
+		return p.literal(tk, tk.value), nil
+	case tokenNumber:
+		if n, err := strconv.ParseInt(tk.text, 10, 64); err == nil {
+			return p.literal(tk, n), nil
+		}
+		n, err := strconv.ParseFloat(tk.text, 64)
+		if err != nil {
+			return nil, p.errorAt(tk, "invalid number %s", tk.text)
+		}
+		return p.literal(tk, n), nil
+	case tokenIdent:
+		switch tk.text {
+		case "true":
+			return p.literal(tk, true), nil
+		case "false":
+			return p.literal(tk, false), nil
+		case "null":
+			return p.literal(tk, nil), nil
+		}
+		if p.tokens[p.pos].typ == tokenPunct && p.tokens[p.pos].text == "(" {
+			return p.call(tk)
+		}
+		expr := p.other(tk)
+		expr.Kind = KindReference
+		expr.Name = tk.text
+		return expr, nil
+	case tokenPunct:
+		switch tk.text {
+		case "[":
+			return p.tuple(tk)
+		case "{":
+			return p.object(tk)
+		case "(":
+			p.nested++
+			expr, err := p.expression()
+			if err == nil {
+				_, err = p.expect(")")
+			}
+			p.nested--
+			return expr, err
+		}
+	}
+	return nil, p.errorAt(tk, "expected an expression, got %s", describe(tk))
+}
+
+// call parses the arguments of a function call
+func (p *parser) call(name token) (*Expression, error) {
+	p.pos++ // opening parenthesis
+	p.nested++
+	defer func() { p.nested-- }()
+
+	var args []*Expression
+	for !p.is(")") {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.is("...") {
+			p.pos++
+		}
+		if !p.is(",") {
+			break
+		}
+		p.pos++
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	expr := p.other(name)
+	expr.Kind = KindCall
+	expr.Name = name.text
+	expr.Items = args
+	return expr, nil
+}
+
+// tuple parses the elements of a [...] expression. A for expression is kept
+// as source text.
+func (p *parser) tuple(open token) (*Expression, error) {
+	p.nested++
+	defer func() { p.nested-- }()
+
+	if tk := p.peek(); tk.typ == tokenIdent && tk.text == "for" {
+		return p.forExpression(open, "]")
+	}
+
+	var items []*Expression
+	for !p.is("]") {
+		item, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.is(",") {
+			break
+		}
+		p.pos++
+	}
+	if _, err := p.expect("]"); err != nil {
+		return nil, err
+	}
+
+	expr := p.other(open)
+	expr.Kind = KindTuple
+	expr.Items = items
+	return expr, nil
+}
+
+// object parses the items of a {...} expression. Items are separated by
+// commas or newlines and use = or : between key and value. A for expression
+// is kept as source text.
+func (p *parser) object(open token) (*Expression, error) {
+	p.nested++
+	defer func() { p.nested-- }()
+
+	if tk := p.peek(); tk.typ == tokenIdent && tk.text == "for" {
+		return p.forExpression(open, "}")
+	}
+
+	var fields []*Field
+	for !p.is("}") {
+		key, err := p.objectKey()
+		if err != nil {
+			return nil, err
+		}
+		if tk := p.take(); tk.typ != tokenPunct || (tk.text != "=" && tk.text != ":") {
+			return nil, p.errorAt(tk, "expected \"=\" or \":\" after object key, got %s", describe(tk))
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, &Field{Key: key, Value: value})
+		if p.is(",") {
+			p.pos++
+		}
+	}
+	if _, err := p.expect("}"); err != nil {
+		return nil, err
+	}
+
+	expr := p.other(open)
+	expr.Kind = KindObject
+	expr.Fields = fields
+	return expr, nil
+}
+
+// objectKey parses an object key: a name, a string or a parenthesized expression
+func (p *parser) objectKey() (string, error) {
+	tk := p.peek()
+	switch {
+	case tk.typ == tokenIdent:
+		p.pos++
+		return tk.text, nil
+	case tk.typ == tokenString:
+		p.pos++
+		return tk.value, nil
+	case tk.typ == tokenPunct && tk.text == "(":
+		expr, err := p.primary()
+		if err != nil {
+			return "", err
+		}
+		if s, ok := expr.Value.(string); ok && expr.Kind == KindLiteral {
+			return s, nil
+		}
+		return expr.Source, nil
+	}
+	return "", p.errorAt(tk, "expected an object key, got %s", describe(tk))
+}
+
+// forExpression skips a for expression up to its closing bracket
+func (p *parser) forExpression(open token, closing string) (*Expression, error) {
+	depth := 0
+	for {
+		tk := p.take()
+		switch {
+		case tk.typ == tokenEOF:
+			return nil, p.errorAt(open, "unterminated for expression")
+		case tk.typ != tokenPunct:
+		case strings.ContainsAny(tk.text, "[{(") && len(tk.text) == 1:
+			depth++
+		case strings.ContainsAny(tk.text, "]})") && len(tk.text) == 1:
+			if depth == 0 {
+				if tk.text != closing {
+					return nil, p.errorAt(tk, "expected %q, got %s", closing, describe(tk))
+				}
+				return p.other(open), nil
+			}
+			depth--
+		}
+	}
+}
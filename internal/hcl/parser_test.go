@@ -0,0 +1,148 @@
+package hcl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testModule = `# Inputs of the module
+variable "name" {
+  type        = string
+  description = "Name of the \"VPC\""
+}
+
+/* Networking */
+variable "cidr" {
+  type    = string
+  default = "10.0.0.0/16" // the default
+}
+
+variable "subnets" {
+  type = map(object({
+    cidr  = string
+    zones = optional(list(string), ["a"])
+  }))
+  default = {
+    public = { cidr = "10.0.0.0/24", zones = ["a", "b"] }
+    "private-1": {
+      cidr = "10.0.1.0/24"
+    }
+  }
+}
+
+variable "max_azs" {
+  type    = number
+  default = -2.5
+
+  validation {
+    condition     = var.max_azs > 0 && contains([1, 2, 3], var.max_azs)
+    error_message = <<-EOT
+      Must be between
+        one and three
+    EOT
+  }
+}
+
+locals {
+  names = [for s in var.subnets : "${s.cidr}-x"]
+  first = var.subnets["public"].zones[0]
+  value = var.enabled ? "on" : "off"
+}
+`
+
+func TestParse(t *testing.T) {
+	file, err := Parse([]byte(testModule), "main.tf")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	variables := file.Body.BlocksOfType("variable")
+	if len(variables) != 4 {
+		t.Fatalf("Expected 4 variable blocks, got %d", len(variables))
+	}
+	if variables[1].Labels[0] != "cidr" || variables[1].Line != 8 {
+		t.Errorf("Expected variable cidr on line 8, got %v on line %d", variables[1].Labels, variables[1].Line)
+	}
+
+	name := variables[0].Body
+	if typ := name.Attribute("type").Expr; typ.Kind != KindReference || typ.Name != "string" {
+		t.Errorf("Expected a reference to string, got %+v", typ)
+	}
+	if description, _ := name.Attribute("description").Expr.Literal(); description != `Name of the "VPC"` {
+		t.Errorf("Expected unescaped description, got %q", description)
+	}
+	if name.Attribute("default") != nil {
+		t.Errorf("Expected no default")
+	}
+
+	// Type constraints are calls with nested objects
+	subnets := variables[2].Body
+	typ := subnets.Attribute("type").Expr
+	if typ.Kind != KindCall || typ.Name != "map" || typ.Items[0].Name != "object" {
+		t.Fatalf("Expected map(object(...)), got %q", typ.Source)
+	}
+	fields := typ.Items[0].Items[0].Fields
+	if len(fields) != 2 || fields[1].Key != "zones" || fields[1].Value.Name != "optional" || fields[1].Value.Items[0].Source != "list(string)" {
+		t.Errorf("Expected cidr and optional zones fields, got %+v", fields)
+	}
+
+	defaults, ok := subnets.Attribute("default").Expr.Literal()
+	if !ok {
+		t.Fatalf("Expected a literal default")
+	}
+	expected := map[string]interface{}{
+		"public":    map[string]interface{}{"cidr": "10.0.0.0/24", "zones": []interface{}{"a", "b"}},
+		"private-1": map[string]interface{}{"cidr": "10.0.1.0/24"},
+	}
+	if !reflect.DeepEqual(defaults, expected) {
+		t.Errorf("Expected %v, got %v", expected, defaults)
+	}
+
+	// Negative numbers stay literals and expressions with operators keep their source
+	maxAZs := variables[3].Body
+	if value, _ := maxAZs.Attribute("default").Expr.Literal(); value != -2.5 {
+		t.Errorf("Expected -2.5, got %v", value)
+	}
+	validation := maxAZs.BlocksOfType("validation")[0].Body
+	condition := validation.Attribute("condition").Expr
+	if condition.Kind != KindOther || condition.Source != "var.max_azs > 0 && contains([1, 2, 3], var.max_azs)" {
+		t.Errorf("Expected the condition source, got %q", condition.Source)
+	}
+	if message, _ := validation.Attribute("error_message").Expr.Literal(); message != "Must be between\n  one and three\n" {
+		t.Errorf("Expected the heredoc without common indentation, got %q", message)
+	}
+
+	locals := file.Body.BlocksOfType("locals")[0].Body
+	if names := locals.Attribute("names").Expr; names.Kind != KindOther || !strings.HasPrefix(names.Source, "[for s") {
+		t.Errorf("Expected a for expression, got %+v", names)
+	}
+	if first := locals.Attribute("first").Expr; first.Kind != KindReference || first.Name != `var.subnets["public"].zones[0]` {
+		t.Errorf("Expected a traversal, got %+v", first)
+	}
+	if value := locals.Attribute("value").Expr; value.Kind != KindOther || value.Source != `var.enabled ? "on" : "off"` {
+		t.Errorf("Expected a conditional, got %+v", value)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		expected string
+	}{
+		"unterminated block":  {"variable \"x\" {\n  type = string\n", "main.tf:3:1: expected \"}\", got end of file"},
+		"missing value":       {"x = \n", "main.tf:1:5: expected an expression, got newline"},
+		"two attributes":      {"x = 1 y = 2\n", "main.tf:1:7: expected a newline, got \"y\""},
+		"unterminated string": {"x = \"abc\n", "main.tf:1:9: unterminated string"},
+		"bad character":       {"x = @\n", "main.tf:1:5: unexpected character '@'"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.source), "main.tf")
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("Expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
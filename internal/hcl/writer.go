@@ -0,0 +1,318 @@
+package hcl
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// KeyValue is an attribute to write: a name and the value to write for it
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// indentUnit is the indentation of each nesting level, as written by terraform fmt
+const indentUnit = "  "
+
+// identifierRe matches names that can be written without quotes
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// WriteAttributes writes one name = value line per attribute, in the order
+// given, with the equals signs aligned like terraform fmt does. Names must be
+// identifiers, as they are in a .tfvars file. Values are written as described
+// by FormatValue.
+func WriteAttributes(w io.Writer, attributes []KeyValue) error {
+	width := 0
+	for _, attr := range attributes {
+		if !identifierRe.MatchString(attr.Key) {
+			return fmt.Errorf("%q is not a valid attribute name", attr.Key)
+		}
+		if len(attr.Key) > width {
+			width = len(attr.Key)
+		}
+	}
+
+	for _, attr := range attributes {
+		var b strings.Builder
+		if err := writeValue(&b, attr.Value, ""); err != nil {
+			return fmt.Errorf("%s: %w", attr.Key, err)
+		}
+		if _, err := fmt.Fprintf(w, "%-*s = %s\n", width, attr.Key, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatValue returns the HCL expression for a value decoded from YAML or
+// JSON. Maps become objects with sorted keys, quoted unless they are
+// identifiers. Lists of scalars are written on one line and other lists one
+// item per line. Multi-line strings ending with a newline become heredocs,
+// and template sequences such as ${ are escaped so strings are taken literally.
+func FormatValue(value interface{}) (string, error) {
+	var b strings.Builder
+	if err := writeValue(&b, value, ""); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// writeValue writes a value whose first line continues the current line and
+// whose following lines are indented by indent
+func writeValue(b *strings.Builder, value interface{}, indent string) error {
+	if value == nil {
+		b.WriteString("null")
+		return nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		writeString(b, v, indent)
+		return nil
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("%v cannot be written in HCL", f)
+		}
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case reflect.String:
+		writeString(b, rv.String(), indent)
+	case reflect.Bool:
+		b.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Slice, reflect.Array:
+		return writeList(b, rv, indent)
+	case reflect.Map:
+		return writeObject(b, rv, indent)
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			b.WriteString("null")
+			return nil
+		}
+		return writeValue(b, rv.Elem().Interface(), indent)
+	default:
+		return fmt.Errorf("values of type %T cannot be written in HCL", value)
+	}
+	return nil
+}
+
+// writeList writes a list on one line if all of its items are scalars, or one item per line
+func writeList(b *strings.Builder, rv reflect.Value, indent string) error {
+	if rv.Len() == 0 {
+		b.WriteString("[]")
+		return nil
+	}
+
+	items := make([]interface{}, rv.Len())
+	inline := true
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+		if !isInline(items[i]) {
+			inline = false
+		}
+	}
+
+	if inline {
+		b.WriteString("[")
+		for i, item := range items {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if err := writeValue(b, item, indent); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+		b.WriteString("]")
+		return nil
+	}
+
+	inner := indent + indentUnit
+	b.WriteString("[\n")
+	for i, item := range items {
+		b.WriteString(inner)
+		if err := writeValue(b, item, inner); err != nil {
+			return fmt.Errorf("%d: %w", i, err)
+		}
+		b.WriteString(",\n")
+	}
+	b.WriteString(indent + "]")
+	return nil
+}
+
+// writeObject writes a map as an object with sorted keys and aligned equals signs
+func writeObject(b *strings.Builder, rv reflect.Value, indent string) error {
+	if rv.Len() == 0 {
+		b.WriteString("{}")
+		return nil
+	}
+
+	names := make([]string, 0, rv.Len())
+	values := make(map[string]interface{}, rv.Len())
+	for _, key := range rv.MapKeys() {
+		name := fmt.Sprint(key.Interface())
+		names = append(names, name)
+		values[name] = rv.MapIndex(key).Interface()
+	}
+	sort.Strings(names)
+
+	labels := make([]string, len(names))
+	width := 0
+	for i, name := range names {
+		labels[i] = objectKey(name)
+		if n := utf8.RuneCountInString(labels[i]); n > width {
+			width = n
+		}
+	}
+
+	inner := indent + indentUnit
+	b.WriteString("{\n")
+	for i, name := range names {
+		b.WriteString(inner + labels[i] + strings.Repeat(" ", width-utf8.RuneCountInString(labels[i])) + " = ")
+		if err := writeValue(b, values[name], inner); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+	return nil
+}
+
+// keywords are identifiers that would not be read as a literal object key
+var keywords = map[string]bool{"true": true, "false": true, "null": true, "for": true, "in": true, "if": true}
+
+// objectKey returns a name as an object key, quoted unless it is an identifier
+func objectKey(name string) string {
+	if identifierRe.MatchString(name) && !keywords[name] {
+		return name
+	}
+	return quote(name)
+}
+
+// isInline reports whether a value is written on a single line
+func isInline(value interface{}) bool {
+	if s, ok := value.(string); ok {
+		return !useHeredoc(s)
+	}
+	if value == nil {
+		return true
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return reflect.ValueOf(value).Len() == 0
+	}
+	return true
+}
+
+// useHeredoc reports whether a string is written as a heredoc. Only strings
+// ending with a newline can be, since a heredoc always ends with one.
+func useHeredoc(s string) bool {
+	return strings.Count(s, "\n") > 1 && strings.HasSuffix(s, "\n") && !strings.Contains(s, "\r")
+}
+
+// writeString writes a quoted string, or a heredoc for multi-line strings
+func writeString(b *strings.Builder, s string, indent string) {
+	if !useHeredoc(s) {
+		b.WriteString(quote(s))
+		return
+	}
+
+	marker := "EOT"
+	for i := 1; containsLine(s, marker); i++ {
+		marker = "EOT" + strconv.Itoa(i)
+	}
+
+	// The indented form strips the indentation common to every line, so it is
+	// only used when some line of the string is not indented itself
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if hasUnindentedLine(lines) {
+		inner := indent + indentUnit
+		b.WriteString("<<-" + marker + "\n")
+		for _, line := range lines {
+			if line != "" {
+				line = inner + escapeTemplates(line)
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString(indent + marker)
+		return
+	}
+
+	b.WriteString("<<" + marker + "\n")
+	for _, line := range lines {
+		b.WriteString(escapeTemplates(line) + "\n")
+	}
+	b.WriteString(marker)
+}
+
+// hasUnindentedLine reports whether any non-blank line starts without whitespace
+func hasUnindentedLine(lines []string) bool {
+	for _, line := range lines {
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			return true
+		}
+	}
+	return false
+}
+
+// containsLine reports whether s has a line that is exactly marker once trimmed,
+// which would end a heredoc using it early
+func containsLine(s, marker string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeTemplates escapes the ${ and %{ sequences that would otherwise start
+// an interpolation or directive
+func escapeTemplates(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
+
+// quote returns s as a quoted HCL string
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return escapeTemplates(b.String())
+}
@@ -0,0 +1,125 @@
+package hcl
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// testValues covers every kind of value the writer handles
+var testValues = []KeyValue{
+	{Key: "name", Value: "vpc"},
+	{Key: "enabled", Value: true},
+	{Key: "max_azs", Value: int64(3)},
+	{Key: "ratio", Value: 0.25},
+	{Key: "nothing", Value: nil},
+	{Key: "quoted", Value: `say "hi" \ ${var.name} %{if true}`},
+	{Key: "single_line", Value: "tab\tand newline\n"},
+	{Key: "zones", Value: []interface{}{"a", "b", int64(1), nil}},
+	{Key: "empty_list", Value: []interface{}{}},
+	{Key: "empty_map", Value: map[string]interface{}{}},
+	{Key: "policy", Value: "{\n  \"Version\": \"2012-10-17\"\n}\n"},
+	{Key: "indented", Value: "  first\n    second\n"},
+	{Key: "marker", Value: "EOT\n${x}\n"},
+	{Key: "tags", Value: map[string]interface{}{
+		"Name":            "vpc",
+		"kubernetes.io/x": "shared",
+		"null":            "keyword",
+		"z":               int64(1),
+	}},
+	{Key: "subnets", Value: []interface{}{
+		map[string]interface{}{
+			"cidr":      "10.0.0.0/24",
+			"zones":     []interface{}{"a"},
+			"user_data": "#!/bin/bash\n\necho ${HOME}\n",
+		},
+		[]interface{}{[]interface{}{"nested"}},
+	}},
+}
+
+func TestWriteAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteAttributes(&buf, testValues); err != nil {
+		t.Fatalf("WriteAttributes failed: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "values.tfvars")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", golden, err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", golden, err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("Output does not match %s (run go test -update to regenerate):\n%s", golden, buf.String())
+	}
+
+	// The output parses back to the values that were written
+	file, err := Parse(buf.Bytes(), golden)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+	for _, kv := range testValues {
+		attr := file.Body.Attribute(kv.Key)
+		if attr == nil {
+			t.Errorf("Attribute %s is missing", kv.Key)
+			continue
+		}
+		value, ok := attr.Expr.Literal()
+		if !ok {
+			t.Errorf("Attribute %s is not a literal: %s", kv.Key, attr.Expr.Source)
+			continue
+		}
+		if !reflect.DeepEqual(value, kv.Value) {
+			t.Errorf("Attribute %s: expected %#v, got %#v", kv.Key, kv.Value, value)
+		}
+	}
+}
+
+func TestWriteAttributesErrors(t *testing.T) {
+	testCases := map[string][]KeyValue{
+		"invalid name":        {{Key: "not valid", Value: 1}},
+		"unsupported type":    {{Key: "fn", Value: func() {}}},
+		"nested invalid type": {{Key: "m", Value: map[string]interface{}{"x": make(chan int)}}},
+	}
+
+	for name, attributes := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if err := WriteAttributes(&bytes.Buffer{}, attributes); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	testCases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"plain", `"plain"`},
+		{uint64(7), "7"},
+		{map[string]string{"a b": "c"}, "{\n  \"a b\" = \"c\"\n}"},
+		{[]string{"x", "y"}, `["x", "y"]`},
+		{"one\ntwo", `"one\ntwo"`},
+	}
+
+	for _, tc := range testCases {
+		result, err := FormatValue(tc.value)
+		if err != nil {
+			t.Errorf("FormatValue(%#v) failed: %v", tc.value, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("FormatValue(%#v): expected %q, got %q", tc.value, tc.expected, result)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+// Package query evaluates path expressions against merged stack documents.
+// The expressions are a subset of jq and JSONPath: keys, list indexes,
+// wildcards and recursive descent.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segmentKind is what a segment of a query selects
+type segmentKind int
+
+const (
+	// segmentKey selects the value of a mapping key
+	segmentKey segmentKind = iota
+	// segmentIndex selects a list element, counting from the end if negative
+	segmentIndex
+	// segmentWildcard selects every value of a mapping or element of a list
+	segmentWildcard
+)
+
+// segment is a step of a query
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+	// recursive applies the segment to the value and all of its descendants
+	recursive bool
+}
+
+// Query is a parsed path expression
+type Query struct {
+	expression string
+	segments   []segment
+}
+
+// Match is a value a query selected and its path in the document
+type Match struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// SyntaxError is a query that could not be parsed
+type SyntaxError struct {
+	Expression string
+	Pos        int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query %q: %s at column %d", e.Expression, e.Message, e.Pos+1)
+}
+
+// Parse parses a path expression. Expressions start with . or $ and are made of:
+// - .key or ."key" or ["key"]: the value of a mapping key
+// - [0], [-1]: a list element, counting from the end if negative
+// - .* or [*]: every value of a mapping or element of a list
+// - ..key, ..*, ..[0]: the same, applied to a value and all of its descendants
+// A lone . selects the whole document. The leading . can be omitted before a key.
+func Parse(expression string) (*Query, error) {
+	p := &parser{input: expression}
+	segments, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expression: expression, segments: segments}, nil
+}
+
+// String returns the expression the query was parsed from
+func (q *Query) String() string {
+	return q.expression
+}
+
+// Single returns true if the query selects at most one value, because it has
+// no wildcards or recursive descent
+func (q *Query) Single() bool {
+	for _, seg := range q.segments {
+		if seg.kind == segmentWildcard || seg.recursive {
+			return false
+		}
+	}
+	return true
+}
+
+// node is a value and the keys and indexes leading to it
+type node struct {
+	path  []interface{}
+	value interface{}
+}
+
+// Evaluate returns the values the query selects in a document decoded from
+// YAML or JSON, in document order with mapping keys sorted
+func (q *Query) Evaluate(document interface{}) []Match {
+	nodes := []node{{value: document}}
+	for _, seg := range q.segments {
+		var next []node
+		for _, n := range nodes {
+			targets := []node{n}
+			if seg.recursive {
+				targets = descendants(n)
+			}
+			for _, target := range targets {
+				next = append(next, apply(seg, target)...)
+			}
+		}
+		nodes = next
+	}
+
+	matches := make([]Match, 0, len(nodes))
+	for _, n := range nodes {
+		matches = append(matches, Match{Path: formatPath(n.path), Value: n.value})
+	}
+	return matches
+}
+
+// apply returns the children of a node that a segment selects
+func apply(seg segment, n node) []node {
+	switch seg.kind {
+	case segmentKey:
+		if mapping, ok := n.value.(map[string]interface{}); ok {
+			if value, ok := mapping[seg.key]; ok {
+				return []node{n.child(seg.key, value)}
+			}
+		}
+	case segmentIndex:
+		if list, ok := n.value.([]interface{}); ok {
+			index := seg.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index >= 0 && index < len(list) {
+				return []node{n.child(index, list[index])}
+			}
+		}
+	case segmentWildcard:
+		return children(n)
+	}
+	return nil
+}
+
+// children returns the values of a mapping, sorted by key, or the elements of a list
+func children(n node) []node {
+	var result []node
+	switch value := n.value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			result = append(result, n.child(key, value[key]))
+		}
+	case []interface{}:
+		for i, element := range value {
+			result = append(result, n.child(i, element))
+		}
+	}
+	return result
+}
+
+// descendants returns a node followed by all of its descendants, depth first
+func descendants(n node) []node {
+	result := []node{n}
+	for _, child := range children(n) {
+		result = append(result, descendants(child)...)
+	}
+	return result
+}
+
+// child returns the node of a value below n
+func (n node) child(step interface{}, value interface{}) node {
+	path := make([]interface{}, len(n.path), len(n.path)+1)
+	copy(path, n.path)
+	return node{path: append(path, step), value: value}
+}
+
+// identifierPattern matches keys that can be written without quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// formatPath returns a path in the query syntax, such as .spec.components["a.b"][0]
+func formatPath(path []interface{}) string {
+	if len(path) == 0 {
+		return "."
+	}
+
+	var b strings.Builder
+	for _, step := range path {
+		switch step := step.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", step)
+		case string:
+			if identifierPattern.MatchString(step) {
+				b.WriteString("." + step)
+			} else {
+				fmt.Fprintf(&b, "[%s]", strconv.Quote(step))
+			}
+		}
+	}
+	return b.String()
+}
+
+// parser reads the segments of a path expression
+type parser struct {
+	input string
+	pos   int
+}
+
+// parse parses the whole expression
+func (p *parser) parse() ([]segment, error) {
+	if strings.TrimSpace(p.input) == "" {
+		return nil, p.errorf("empty query")
+	}
+
+	// $ is the JSONPath root
+	if p.peek() == '$' {
+		p.pos++
+	}
+	if p.input[p.pos:] == "." {
+		return nil, nil
+	}
+
+	var segments []segment
+	for p.pos < len(p.input) {
+		var seg segment
+		var err error
+		switch {
+		case strings.HasPrefix(p.input[p.pos:], ".."):
+			p.pos += 2
+			if p.peek() == '[' {
+				seg, err = p.bracket()
+			} else {
+				seg, err = p.dotted()
+			}
+			seg.recursive = true
+		case p.peek() == '.':
+			p.pos++
+			seg, err = p.dotted()
+		case p.peek() == '[':
+			seg, err = p.bracket()
+		case len(segments) == 0 && isKeyChar(p.peek()):
+			// The leading dot can be omitted before a key
+			seg, err = p.dotted()
+		default:
+			err = p.errorf("expected \".\" or \"[\", found %q", p.peek())
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
+
+// dotted parses what follows a dot: a key, a quoted key or *
+func (p *parser) dotted() (segment, error) {
+	switch {
+	case p.peek() == '*':
+		p.pos++
+		return segment{kind: segmentWildcard}, nil
+	case p.peek() == '"':
+		key, err := p.quoted()
+		return segment{kind: segmentKey, key: key}, err
+	case isKeyChar(p.peek()):
+		start := p.pos
+		for p.pos < len(p.input) && isKeyChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return segment{kind: segmentKey, key: p.input[start:p.pos]}, nil
+	default:
+		return segment{}, p.errorf("expected a key or \"*\" after \".\"")
+	}
+}
+
+// bracket parses [index], [*] or ["key"]
+func (p *parser) bracket() (segment, error) {
+	p.pos++ // [
+
+	var seg segment
+	switch {
+	case p.peek() == '*':
+		p.pos++
+		seg = segment{kind: segmentWildcard}
+	case p.peek() == '"' || p.peek() == '\'':
+		key, err := p.quoted()
+		if err != nil {
+			return segment{}, err
+		}
+		seg = segment{kind: segmentKey, key: key}
+	default:
+		start := p.pos
+		if p.peek() == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+			p.pos++
+		}
+		index, err := strconv.Atoi(p.input[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return segment{}, p.errorf("expected an index, \"*\" or a quoted key after \"[\"")
+		}
+		seg = segment{kind: segmentIndex, index: index}
+	}
+
+	if p.peek() != ']' {
+		return segment{}, p.errorf("expected \"]\"")
+	}
+	p.pos++
+	return seg, nil
+}
+
+// quoted parses a key in double or single quotes, with backslash escapes
+func (p *parser) quoted() (string, error) {
+	quote := p.input[p.pos]
+	start := p.pos
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.input); p.pos++ {
+		switch c := p.input[p.pos]; c {
+		case '\\':
+			p.pos++
+			if p.pos < len(p.input) {
+				b.WriteByte(p.input[p.pos])
+			}
+		case quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated quoted key")
+}
+
+// peek returns the next byte, or 0 at the end of the expression
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// isKeyChar returns true if c can be part of an unquoted key
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// errorf returns a SyntaxError at the current position
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Expression: p.input, Pos: p.pos, Message: fmt.Sprintf(format, args...)}
+}
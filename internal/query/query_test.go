@@ -0,0 +1,180 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "plat-dev"},
+		"spec": map[string]interface{}{
+			"components": map[string]interface{}{
+				"terraform": map[string]interface{}{
+					"vpc": map[string]interface{}{
+						"vars": map[string]interface{}{
+							"enabled":            true,
+							"availability_zones": []interface{}{"us-east-1a", "us-east-1b"},
+							"tags":               map[string]interface{}{"app.kubernetes.io/name": "vpc"},
+						},
+					},
+					"eks": map[string]interface{}{
+						"vars": map[string]interface{}{"enabled": false},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   []Match
+	}{
+		{
+			name:       "Key path",
+			expression: ".spec.components.terraform.vpc.vars.enabled",
+			expected:   []Match{{Path: ".spec.components.terraform.vpc.vars.enabled", Value: true}},
+		},
+		{
+			name:       "Without leading dot",
+			expression: "metadata.name",
+			expected:   []Match{{Path: ".metadata.name", Value: "plat-dev"}},
+		},
+		{
+			name:       "JSONPath root and brackets",
+			expression: `$['metadata']["name"]`,
+			expected:   []Match{{Path: ".metadata.name", Value: "plat-dev"}},
+		},
+		{
+			name:       "List index",
+			expression: ".spec.components.terraform.vpc.vars.availability_zones[1]",
+			expected:   []Match{{Path: ".spec.components.terraform.vpc.vars.availability_zones[1]", Value: "us-east-1b"}},
+		},
+		{
+			name:       "Negative list index",
+			expression: ".spec.components.terraform.vpc.vars.availability_zones[-2]",
+			expected:   []Match{{Path: ".spec.components.terraform.vpc.vars.availability_zones[0]", Value: "us-east-1a"}},
+		},
+		{
+			name:       "Quoted key",
+			expression: `.spec.components.terraform.vpc.vars.tags."app.kubernetes.io/name"`,
+			expected:   []Match{{Path: `.spec.components.terraform.vpc.vars.tags["app.kubernetes.io/name"]`, Value: "vpc"}},
+		},
+		{
+			name:       "Mapping wildcard",
+			expression: ".spec.components.terraform.*.vars.enabled",
+			expected: []Match{
+				{Path: ".spec.components.terraform.eks.vars.enabled", Value: false},
+				{Path: ".spec.components.terraform.vpc.vars.enabled", Value: true},
+			},
+		},
+		{
+			name:       "List wildcard",
+			expression: ".spec.components.terraform.vpc.vars.availability_zones[*]",
+			expected: []Match{
+				{Path: ".spec.components.terraform.vpc.vars.availability_zones[0]", Value: "us-east-1a"},
+				{Path: ".spec.components.terraform.vpc.vars.availability_zones[1]", Value: "us-east-1b"},
+			},
+		},
+		{
+			name:       "Recursive descent",
+			expression: "..enabled",
+			expected: []Match{
+				{Path: ".spec.components.terraform.eks.vars.enabled", Value: false},
+				{Path: ".spec.components.terraform.vpc.vars.enabled", Value: true},
+			},
+		},
+		{
+			name:       "Recursive descent with index",
+			expression: ".spec..[0]",
+			expected:   []Match{{Path: ".spec.components.terraform.vpc.vars.availability_zones[0]", Value: "us-east-1a"}},
+		},
+		{
+			name:       "Missing key",
+			expression: ".spec.components.helm",
+			expected:   []Match{},
+		},
+		{
+			name:       "Key of a list",
+			expression: ".spec.components.terraform.vpc.vars.availability_zones.first",
+			expected:   []Match{},
+		},
+		{
+			name:       "Index out of range",
+			expression: ".spec.components.terraform.vpc.vars.availability_zones[2]",
+			expected:   []Match{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			matches := q.Evaluate(testDocument())
+			if !reflect.DeepEqual(matches, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, matches)
+			}
+		})
+	}
+}
+
+func TestEvaluateWholeDocument(t *testing.T) {
+	for _, expression := range []string{".", "$"} {
+		q, err := Parse(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", expression, err)
+		}
+		matches := q.Evaluate(testDocument())
+		if len(matches) != 1 || matches[0].Path != "." || !reflect.DeepEqual(matches[0].Value, testDocument()) {
+			t.Errorf("Expected %q to select the whole document, got %v", expression, matches)
+		}
+	}
+}
+
+func TestSingle(t *testing.T) {
+	tests := map[string]bool{
+		".spec.components.terraform.vpc": true,
+		".spec.list[0]":                  true,
+		".spec.components.*.vpc":         false,
+		".spec.list[*]":                  false,
+		"..vpc":                          false,
+	}
+
+	for expression, expected := range tests {
+		q, err := Parse(expression)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", expression, err)
+		}
+		if q.Single() != expected {
+			t.Errorf("Expected Single() of %q to be %v", expression, expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                 `invalid query "": empty query at column 1`,
+		".spec.":           `invalid query ".spec.": expected a key or "*" after "." at column 7`,
+		".spec[":           `invalid query ".spec[": expected an index, "*" or a quoted key after "[" at column 7`,
+		".spec[0":          `invalid query ".spec[0": expected "]" at column 8`,
+		".spec[x]":         `invalid query ".spec[x]": expected an index, "*" or a quoted key after "[" at column 7`,
+		`.spec["x]`:        `invalid query ".spec[\"x]": unterminated quoted key at column 7`,
+		".spec components": `invalid query ".spec components": expected "." or "[", found ' ' at column 6`,
+	}
+
+	for expression, expected := range tests {
+		_, err := Parse(expression)
+		if err == nil {
+			t.Errorf("Expected an error for %q", expression)
+			continue
+		}
+		if err.Error() != expected {
+			t.Errorf("Expected error %q for %q, got %q", expected, expression, err.Error())
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mcalhoun/skunk/internal/hcl"
+)
+
+// tfvarsRenderer writes Terraform variables as HCL
+var tfvarsRenderer = Renderer{
+	Format:      "tfvars",
+	Description: "Terraform variables in HCL",
+	FileName:    suffixFileName(".tfvars"),
+	Render:      renderTfvars,
+}
+
+// tfvarsJSONRenderer writes Terraform variables as JSON
+var tfvarsJSONRenderer = Renderer{
+	Format:      "tfvars-json",
+	Description: "Terraform variables in JSON",
+	FileName:    suffixFileName(".tfvars.json"),
+	Render:      renderJSON,
+}
+
+// helmValuesRenderer writes Helm chart values as YAML
+var helmValuesRenderer = Renderer{
+	Format:      "values",
+	Description: "Helm chart values in YAML",
+	FileName:    suffixFileName(".values.yaml"),
+	Render: func(w io.Writer, component Component) error {
+		fmt.Fprintf(w, "# Helm values for component '%s' from stack '%s'\n", component.Name, component.Stack)
+		fmt.Fprintf(w, "# Generated by skunk\n\n")
+		return writeYAML(w, component.Vars)
+	},
+}
+
+// yamlRenderer writes vars as YAML
+var yamlRenderer = Renderer{
+	Format:      "yaml",
+	Description: "Vars in YAML",
+	FileName:    suffixFileName(".yaml"),
+	Render: func(w io.Writer, component Component) error {
+		return writeYAML(w, component.Vars)
+	},
+}
+
+// jsonRenderer writes vars as JSON
+var jsonRenderer = Renderer{
+	Format:      "json",
+	Description: "Vars in JSON",
+	FileName:    suffixFileName(".json"),
+	Render:      renderJSON,
+}
+
+// suffixFileName returns a FileName func naming files <stack>-<component><suffix>
+func suffixFileName(suffix string) func(stackName string, component Component) string {
+	return func(stackName string, component Component) string {
+		return stackName + "-" + component.Name + suffix
+	}
+}
+
+// renderTfvars writes the vars as HCL attributes, sorted by name and preceded
+// by a header comment naming the component and stack
+func renderTfvars(w io.Writer, component Component) error {
+	fmt.Fprintf(w, "# Terraform variables for component '%s' from stack '%s'\n", component.Name, component.Stack)
+	fmt.Fprintf(w, "# Generated by skunk\n\n")
+
+	names := make([]string, 0, len(component.Vars))
+	for name := range component.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attributes := make([]hcl.KeyValue, 0, len(names))
+	for _, name := range names {
+		attributes = append(attributes, hcl.KeyValue{Key: name, Value: component.Vars[name]})
+	}
+	return hcl.WriteAttributes(w, attributes)
+}
+
+// renderJSON writes the vars as indented JSON with sorted keys
+func renderJSON(w io.Writer, component Component) error {
+	vars := component.Vars
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	data, err := json.MarshalIndent(vars, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// writeYAML writes vars as a YAML mapping with sorted keys
+func writeYAML(w io.Writer, vars map[string]interface{}) error {
+	if len(vars) == 0 {
+		_, err := fmt.Fprintln(w, "{}")
+		return err
+	}
+
+	data, err := yaml.MarshalWithOptions(vars, yaml.IndentSequence(true), yaml.UseLiteralStyleIfMultiline(true))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,111 @@
+package renderer
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// testComponent has nested, multi-line and template values
+var testComponent = Component{
+	Type:  "helm",
+	Name:  "nginx",
+	Stack: "dev.yaml",
+	Vars: map[string]interface{}{
+		"replicaCount": uint64(3),
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.25",
+		},
+		"ingress": map[string]interface{}{
+			"enabled": true,
+			"hosts":   []interface{}{"a.example.com", "b.example.com"},
+		},
+		"config":    "server {\n  listen 80;\n}\n",
+		"template":  "${name}",
+		"resources": map[string]interface{}{},
+	},
+}
+
+func TestRenderers(t *testing.T) {
+	registry := DefaultRegistry()
+
+	testCases := []struct {
+		typeName string
+		format   string
+		golden   string
+	}{
+		{"helm", "values", "nginx.values.yaml"},
+		{"helm", "json", "nginx.json"},
+		{"terraform", "tfvars", "nginx.tfvars"},
+		{"other", "yaml", "nginx.yaml"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.golden, func(t *testing.T) {
+			renderer, err := registry.Lookup(tc.typeName, tc.format)
+			if err != nil {
+				t.Fatalf("Lookup failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := renderer.Render(&buf, testComponent); err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tc.golden)
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatalf("Failed to update %s: %v", golden, err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", golden, err)
+			}
+			if buf.String() != string(expected) {
+				t.Errorf("Output does not match %s (run go test -update to regenerate):\n%s", golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	registry := DefaultRegistry()
+
+	testCases := map[string]string{
+		"tfvars":      "dev-nginx.tfvars",
+		"tfvars-json": "dev-nginx.tfvars.json",
+	}
+	for format, expected := range testCases {
+		renderer, _ := registry.Lookup("terraform", format)
+		if name := renderer.FileName("dev", testComponent); name != expected {
+			t.Errorf("FileName for %s: expected %s, got %s", format, expected, name)
+		}
+	}
+
+	renderer, _ := registry.Lookup("helm", "")
+	if name := renderer.FileName("dev", testComponent); name != "dev-nginx.values.yaml" {
+		t.Errorf("Unexpected values file name %s", name)
+	}
+}
+
+func TestRenderEmptyVars(t *testing.T) {
+	registry := DefaultRegistry()
+	component := Component{Type: "other", Name: "empty"}
+
+	for _, format := range []string{"yaml", "json"} {
+		renderer, _ := registry.Lookup("other", format)
+		var buf bytes.Buffer
+		if err := renderer.Render(&buf, component); err != nil {
+			t.Fatalf("Render %s failed: %v", format, err)
+		}
+		if buf.String() != "{}\n" {
+			t.Errorf("Render %s: expected an empty mapping, got %q", format, buf.String())
+		}
+	}
+}
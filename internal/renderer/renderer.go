@@ -0,0 +1,161 @@
+// Package renderer writes the vars of a component in the formats used by the
+// tool that deploys it, such as .tfvars files for Terraform and values.yaml
+// files for Helm. Each component type registers its own renderers.
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Component is a component of a stack with its merged vars
+type Component struct {
+	Type string
+	Name string
+	// Stack is the name of the stack file the component is defined in,
+	// used in the header of the rendered file
+	Stack string
+	Vars  map[string]interface{}
+}
+
+// RenderFunc writes the vars of a component
+type RenderFunc func(w io.Writer, component Component) error
+
+// Renderer is a named output format for the vars of a component
+type Renderer struct {
+	Format      string
+	Description string
+	// FileName returns the name of the file the output is written to
+	FileName func(stackName string, component Component) string
+	Render   RenderFunc
+}
+
+// componentType holds the renderers of a component type
+type componentType struct {
+	defaultFormat string
+	renderers     []Renderer
+}
+
+// Registry holds the renderers of each component type. Types without renderers
+// of their own use the fallback renderers.
+type Registry struct {
+	types    map[string]*componentType
+	fallback *componentType
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		types:    make(map[string]*componentType),
+		fallback: &componentType{},
+	}
+}
+
+// DefaultRegistry returns a registry with the built-in renderers: tfvars for
+// terraform components, values.yaml for helm components and plain YAML or JSON
+// for every other type
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+
+	registry.Register("terraform", tfvarsRenderer)
+	registry.Register("terraform", tfvarsJSONRenderer)
+
+	registry.Register("helm", helmValuesRenderer)
+	registry.Register("helm", jsonRenderer)
+
+	registry.RegisterFallback(yamlRenderer)
+	registry.RegisterFallback(jsonRenderer)
+	return registry
+}
+
+// Register adds a renderer for a component type, replacing any renderer with
+// the same format. The first renderer registered for a type is its default.
+func (r *Registry) Register(typeName string, renderer Renderer) {
+	ct, ok := r.types[typeName]
+	if !ok {
+		ct = &componentType{}
+		r.types[typeName] = ct
+	}
+	ct.add(renderer)
+}
+
+// RegisterFallback adds a renderer for component types without renderers of their own
+func (r *Registry) RegisterFallback(renderer Renderer) {
+	r.fallback.add(renderer)
+}
+
+// SetDefault makes format the default output format of a component type
+func (r *Registry) SetDefault(typeName, format string) error {
+	ct := r.componentType(typeName)
+	if _, ok := ct.find(format); !ok {
+		return fmt.Errorf("component type '%s' has no %s renderer", typeName, format)
+	}
+	ct.defaultFormat = format
+	return nil
+}
+
+// Lookup returns the renderer of a component type for a format, or the type's
+// default renderer if format is empty
+func (r *Registry) Lookup(typeName, format string) (Renderer, error) {
+	ct := r.componentType(typeName)
+	if format == "" {
+		format = ct.defaultFormat
+	}
+
+	renderer, ok := ct.find(format)
+	if !ok {
+		return Renderer{}, fmt.Errorf("component type '%s' cannot be rendered as %s; available formats: %s",
+			typeName, format, strings.Join(r.Formats(typeName), ", "))
+	}
+	return renderer, nil
+}
+
+// Formats returns the output formats of a component type, default first
+func (r *Registry) Formats(typeName string) []string {
+	ct := r.componentType(typeName)
+	formats := make([]string, 0, len(ct.renderers))
+	for _, renderer := range ct.renderers {
+		if renderer.Format != ct.defaultFormat {
+			formats = append(formats, renderer.Format)
+		}
+	}
+	sort.Strings(formats)
+	if ct.defaultFormat != "" {
+		formats = append([]string{ct.defaultFormat}, formats...)
+	}
+	return formats
+}
+
+// componentType returns the renderers of a component type, or the fallback renderers
+func (r *Registry) componentType(typeName string) *componentType {
+	if ct, ok := r.types[typeName]; ok {
+		return ct
+	}
+	return r.fallback
+}
+
+// add adds a renderer, replacing any renderer with the same format
+func (ct *componentType) add(renderer Renderer) {
+	if ct.defaultFormat == "" {
+		ct.defaultFormat = renderer.Format
+	}
+	for i, existing := range ct.renderers {
+		if existing.Format == renderer.Format {
+			ct.renderers[i] = renderer
+			return
+		}
+	}
+	ct.renderers = append(ct.renderers, renderer)
+}
+
+// find returns the renderer for a format
+func (ct *componentType) find(format string) (Renderer, bool) {
+	for _, renderer := range ct.renderers {
+		if renderer.Format == format {
+			return renderer, true
+		}
+	}
+	return Renderer{}, false
+}
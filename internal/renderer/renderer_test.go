@@ -0,0 +1,74 @@
+package renderer
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	registry := DefaultRegistry()
+
+	testCases := []struct {
+		typeName string
+		formats  []string
+	}{
+		{"terraform", []string{"tfvars", "tfvars-json"}},
+		{"helm", []string{"values", "json"}},
+		{"ansible", []string{"yaml", "json"}},
+	}
+
+	for _, tc := range testCases {
+		if formats := registry.Formats(tc.typeName); !reflect.DeepEqual(formats, tc.formats) {
+			t.Errorf("Formats(%s): expected %v, got %v", tc.typeName, tc.formats, formats)
+		}
+
+		renderer, err := registry.Lookup(tc.typeName, "")
+		if err != nil {
+			t.Errorf("Lookup(%s) failed: %v", tc.typeName, err)
+			continue
+		}
+		if renderer.Format != tc.formats[0] {
+			t.Errorf("Default format of %s: expected %s, got %s", tc.typeName, tc.formats[0], renderer.Format)
+		}
+	}
+
+	_, err := registry.Lookup("helm", "tfvars")
+	if err == nil || !strings.Contains(err.Error(), "available formats: values, json") {
+		t.Errorf("Expected an error listing the helm formats, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registry := DefaultRegistry()
+
+	// A new type only offers its own renderers
+	kustomize := Renderer{Format: "kustomization", Render: func(w io.Writer, component Component) error { return nil }}
+	registry.Register("kustomize", kustomize)
+	registry.Register("kustomize", jsonRenderer)
+	if formats := registry.Formats("kustomize"); !reflect.DeepEqual(formats, []string{"kustomization", "json"}) {
+		t.Errorf("Unexpected kustomize formats: %v", formats)
+	}
+	if _, err := registry.Lookup("kustomize", "yaml"); err == nil {
+		t.Error("Expected the fallback renderers not to apply to a registered type")
+	}
+
+	// Registering a format again replaces it
+	replaced := Renderer{Format: "tfvars", Description: "replaced", Render: renderTfvars}
+	registry.Register("terraform", replaced)
+	renderer, err := registry.Lookup("terraform", "tfvars")
+	if err != nil || renderer.Description != "replaced" {
+		t.Errorf("Expected the replaced tfvars renderer, got %+v, %v", renderer, err)
+	}
+
+	if err := registry.SetDefault("terraform", "tfvars-json"); err != nil {
+		t.Fatalf("SetDefault failed: %v", err)
+	}
+	if renderer, _ := registry.Lookup("terraform", ""); renderer.Format != "tfvars-json" {
+		t.Errorf("Expected tfvars-json to be the default, got %s", renderer.Format)
+	}
+	if err := registry.SetDefault("terraform", "values"); err == nil {
+		t.Error("Expected an error setting a default without a renderer")
+	}
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// ComponentsDir is the catalog directory that holds one directory per component
+const ComponentsDir = "components"
+
+// schemaFiles are the names a component schema can have, in order of preference
+var schemaFiles = []string{"schema.json", "schema.yaml", "schema.yml"}
+
+// ComponentSchemas finds and caches the schemas of components, stored as
+// <catalogDir>/components/<component>/schema.json (or schema.yaml) next to the
+// catalog files of the component
+type ComponentSchemas struct {
+	catalogDir string
+	schemas    map[string]*loadedSchema
+}
+
+// loadedSchema is the result of looking up the schema of a component
+type loadedSchema struct {
+	schema *Schema
+	file   string
+	err    error
+}
+
+// NewComponentSchemas creates a lookup for the component schemas in catalogDir
+func NewComponentSchemas(catalogDir string) *ComponentSchemas {
+	return &ComponentSchemas{catalogDir: catalogDir, schemas: make(map[string]*loadedSchema)}
+}
+
+// SchemaFile returns the path of the schema of a component, or an empty string if it has none
+func (c *ComponentSchemas) SchemaFile(component string) string {
+	for _, name := range schemaFiles {
+		path := filepath.Join(c.catalogDir, ComponentsDir, component, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Lookup returns the schema of a component and the file it was loaded from.
+// A component without a schema returns a nil schema and no error.
+func (c *ComponentSchemas) Lookup(component string) (*Schema, string, error) {
+	if loaded, ok := c.schemas[component]; ok {
+		return loaded.schema, loaded.file, loaded.err
+	}
+
+	loaded := &loadedSchema{file: c.SchemaFile(component)}
+	if loaded.file != "" {
+		loaded.schema, loaded.err = Load(loaded.file)
+	}
+	c.schemas[component] = loaded
+	return loaded.schema, loaded.file, loaded.err
+}
+
+// CheckComponent validates the merged vars of a component in a stack document
+// against the component's schema. Each violation is positioned where the
+// offending value was set according to prov, or where its closest parent was
+// set if the value itself has no provenance. A component without a schema
+// has no diagnostics.
+func (c *ComponentSchemas) CheckComponent(stackFile string, document map[string]interface{}, prov yamlparser.ProvenanceMap, componentType, component string) yamlparser.Diagnostics {
+	s, file, err := c.Lookup(component)
+	if err != nil {
+		return yamlparser.AsDiagnostics(err, file)
+	}
+	if s == nil {
+		return nil
+	}
+
+	varsPath := []string{"spec", "components", componentType, component, "vars"}
+	vars := lookup(document, varsPath)
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+
+	var diags yamlparser.Diagnostics
+	for _, violation := range s.Validate(vars) {
+		diag := &yamlparser.Diagnostic{
+			Severity: yamlparser.SeverityError,
+			File:     stackFile,
+			Message:  fmt.Sprintf("component %q %s", componentType+"/"+component, describeViolation(violation)),
+		}
+
+		Locate(diag, prov, append(append([]string{}, varsPath...), violation.Path...))
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// Locate positions a diagnostic where the value at path was set according to
+// prov, or where its closest parent was set if the value itself has no
+// provenance. The diagnostic is left unchanged if no parent has one either.
+func Locate(diag *yamlparser.Diagnostic, prov yamlparser.ProvenanceMap, path []string) {
+	for n := len(path); n > 0; n-- {
+		if p, ok := prov.Lookup(path[:n]...); ok {
+			diag.File = p.File
+			diag.Line = p.Line
+			diag.Column = p.Column
+			diag.Anchor = p.Anchor
+			return
+		}
+	}
+}
+
+// describeViolation names the var a violation is about, followed by its message
+func describeViolation(violation Violation) string {
+	if len(violation.Path) == 0 {
+		return "vars: " + violation.Message
+	}
+	return fmt.Sprintf("var %s: %s", strings.Join(violation.Path, "."), violation.Message)
+}
+
+// lookup follows a path of map keys, returning nil if any key is missing
+func lookup(value interface{}, path []string) interface{} {
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
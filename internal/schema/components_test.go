@@ -0,0 +1,69 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+func TestCheckComponent(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"catalog/components/vpc/defaults.yaml": "vpc-defaults: &vpc-defaults\n  name: vpc\n  dns_hostname_enabled: true\n",
+		"catalog/components/vpc/schema.json":   `{"type": "object", "required": ["cidr"], "additionalProperties": false, "properties": {"name": {"type": "string"}, "dns_hostnames_enabled": {"type": "boolean"}, "cidr": {"type": "string"}, "zones": {"type": "array", "items": {"type": "string"}}}}`,
+		"stack.yaml":                           "spec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          <<: *vpc-defaults\n          zones: [a, 1]\n      app:\n        vars:\n          anything: true\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	catalogDir := filepath.Join(dir, "catalog")
+	stackFile := filepath.Join(dir, "stack.yaml")
+	document, prov, err := yamlparser.ParseStackWithProvenance(stackFile, catalogDir, yamlparser.DefaultOptions())
+	if err != nil {
+		t.Fatalf("ParseStackWithProvenance failed: %v", err)
+	}
+
+	schemas := NewComponentSchemas(catalogDir)
+
+	// Components without a schema are not checked
+	if diags := schemas.CheckComponent(stackFile, document, prov, "terraform", "app"); len(diags) != 0 {
+		t.Errorf("Expected no diagnostics without a schema, got %v", diags)
+	}
+
+	diags := schemas.CheckComponent(stackFile, document, prov, "terraform", "vpc")
+	expected := []string{
+		stackFile + `:5:9: component "terraform/vpc" vars: missing required property "cidr"`,
+		filepath.Join(catalogDir, "components", "vpc", "defaults.yaml") + `:3:3: component "terraform/vpc" var dns_hostname_enabled: unknown property, did you mean "dns_hostnames_enabled"?`,
+		stackFile + `:7:11: component "terraform/vpc" var zones.1: expected string, got integer`,
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diags)
+	}
+	for i, diag := range diags {
+		if diag.Error() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], diag.Error())
+		}
+	}
+	if diags[1].Anchor != "vpc-defaults" {
+		t.Errorf("Expected the anchor of the offending value, got %q", diags[1].Anchor)
+	}
+
+	// A schema that fails to load is reported at the schema file
+	if err := os.WriteFile(filepath.Join(catalogDir, "components", "vpc", "schema.json"), []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	diags = NewComponentSchemas(catalogDir).CheckComponent(stackFile, document, prov, "terraform", "vpc")
+	if len(diags) != 1 || !strings.Contains(diags[0].Error(), "failed to parse schema") {
+		t.Errorf("Expected a schema error, got %v", diags)
+	}
+}
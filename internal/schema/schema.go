@@ -0,0 +1,488 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Schema is the subset of JSON Schema used to describe component vars: types,
+// required properties, enums, constants, patterns, numeric and length bounds,
+// and unknown property detection through additionalProperties
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+
+	// never is set for the boolean schema false, which no value matches
+	never   bool
+	pattern *regexp.Regexp
+}
+
+// Types is the "type" keyword, which is either a single type name or a list of them
+type Types []string
+
+// Supported type names
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeArray   = "array"
+	TypeObject  = "object"
+	TypeNull    = "null"
+)
+
+// Violation is a value that does not match a schema. Path is the location of
+// the value below the validated root, with list items addressed by index.
+type Violation struct {
+	Path    []string
+	Message string
+}
+
+// String returns the violation as path: message
+func (v Violation) String() string {
+	if len(v.Path) == 0 {
+		return v.Message
+	}
+	return strings.Join(v.Path, ".") + ": " + v.Message
+}
+
+// Never returns the boolean schema false, used as additionalProperties to reject unknown properties
+func Never() *Schema {
+	return &Schema{never: true}
+}
+
+// Load reads a schema from a JSON or YAML file
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", path, err)
+		}
+	}
+
+	return Parse(data, path)
+}
+
+// Parse parses a schema from JSON. The name is only used in error messages.
+func Parse(data []byte, name string) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", name, err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// UnmarshalJSON accepts a schema object or the boolean schemas true and false
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Schema{never: !b}
+		return nil
+	}
+
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// MarshalJSON writes the boolean schema false as false and any other schema as an object
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	if s.never {
+		return []byte("false"), nil
+	}
+
+	type plain Schema
+	return json.Marshal((*plain)(s))
+}
+
+// UnmarshalJSON accepts a single type name or a list of them
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON writes a single type as a string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// compile checks the type names and compiles the patterns of the schema and every subschema
+func (s *Schema) compile() error {
+	for _, name := range s.Type {
+		switch name {
+		case TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeArray, TypeObject, TypeNull:
+		default:
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	for name, property := range s.Properties {
+		if err := property.compile(); err != nil {
+			return fmt.Errorf("properties.%s: %w", name, err)
+		}
+	}
+	if s.AdditionalProperties != nil {
+		if err := s.AdditionalProperties.compile(); err != nil {
+			return fmt.Errorf("additionalProperties: %w", err)
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.compile(); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks a decoded value against the schema and returns every violation,
+// ordered by path
+func (s *Schema) Validate(value interface{}) []Violation {
+	var violations []Violation
+	s.validate(value, nil, &violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return strings.Join(violations[i].Path, ".") < strings.Join(violations[j].Path, ".")
+	})
+	return violations
+}
+
+// validate appends the violations of value at path to violations
+func (s *Schema) validate(value interface{}, path []string, violations *[]Violation) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.never {
+		report("value is not allowed")
+		return
+	}
+
+	if len(s.Type) > 0 && !matchesType(value, s.Type) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), typeName(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		report("%s is not one of %s", formatValue(value), formatValues(s.Enum))
+	}
+	if s.Const != nil && !equalValues(s.Const, value) {
+		report("%s is not %s", formatValue(value), formatValue(s.Const))
+	}
+
+	switch v := value.(type) {
+	case string:
+		s.validateString(v, report)
+	case []interface{}:
+		s.validateArray(v, path, violations, report)
+	case map[string]interface{}:
+		s.validateObject(v, path, violations, report)
+	default:
+		if n, ok := toFloat(value); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				report("%s is less than the minimum %s", formatValue(value), formatNumber(*s.Minimum))
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				report("%s is greater than the maximum %s", formatValue(value), formatNumber(*s.Maximum))
+			}
+		}
+	}
+}
+
+// validateString checks the length and pattern of a string
+func (s *Schema) validateString(v string, report func(string, ...interface{})) {
+	length := len([]rune(v))
+	if s.MinLength != nil && length < *s.MinLength {
+		report("%q is shorter than %d characters", v, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		report("%q is longer than %d characters", v, *s.MaxLength)
+	}
+
+	pattern := s.pattern
+	if pattern == nil && s.Pattern != "" {
+		pattern, _ = regexp.Compile(s.Pattern)
+	}
+	if pattern != nil && !pattern.MatchString(v) {
+		report("%q does not match pattern %q", v, s.Pattern)
+	}
+}
+
+// validateArray checks the length of a list and each of its items
+func (s *Schema) validateArray(v []interface{}, path []string, violations *[]Violation, report func(string, ...interface{})) {
+	if s.MinItems != nil && len(v) < *s.MinItems {
+		report("expected at least %d items, got %d", *s.MinItems, len(v))
+	}
+	if s.MaxItems != nil && len(v) > *s.MaxItems {
+		report("expected at most %d items, got %d", *s.MaxItems, len(v))
+	}
+
+	if s.Items == nil {
+		return
+	}
+	for i, item := range v {
+		s.Items.validate(item, appendPath(path, strconv.Itoa(i)), violations)
+	}
+}
+
+// validateObject checks required properties, each known property and any unknown ones
+func (s *Schema) validateObject(v map[string]interface{}, path []string, violations *[]Violation, report func(string, ...interface{})) {
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			report("missing required property %q", name)
+		}
+	}
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := appendPath(path, key)
+		if property, ok := s.Properties[key]; ok {
+			property.validate(v[key], childPath, violations)
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if s.AdditionalProperties.never {
+			message := "unknown property"
+			if suggestion := closestProperty(key, s.Properties); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			*violations = append(*violations, Violation{Path: childPath, Message: message})
+			continue
+		}
+		s.AdditionalProperties.validate(v[key], childPath, violations)
+	}
+}
+
+// matchesType reports whether value is of any of the given types
+func matchesType(value interface{}, types Types) bool {
+	for _, name := range types {
+		switch name {
+		case TypeString:
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case TypeBoolean:
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case TypeNull:
+			if value == nil {
+				return true
+			}
+		case TypeArray:
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case TypeObject:
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case TypeNumber:
+			if _, ok := toFloat(value); ok {
+				return true
+			}
+		case TypeInteger:
+			if n, ok := toFloat(value); ok && n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON Schema type name of a decoded value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return TypeNull
+	case string:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	case []interface{}:
+		return TypeArray
+	case map[string]interface{}:
+		return TypeObject
+	}
+	if n, ok := toFloat(value); ok {
+		if n == math.Trunc(n) {
+			return TypeInteger
+		}
+		return TypeNumber
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toFloat converts any numeric value decoded from YAML or JSON to a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equalValues compares two decoded values, treating numbers of different Go types as equal
+func equalValues(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aj) == string(bj)
+}
+
+// containsValue reports whether value equals any of the given values
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue formats a value for a violation message
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	if n, ok := toFloat(value); ok {
+		return formatNumber(n)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// formatValues formats a list of values for a violation message
+func formatValues(values []interface{}) string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, formatValue(value))
+	}
+	return "[" + strings.Join(formatted, ", ") + "]"
+}
+
+// formatNumber formats a number without a trailing fraction when it is whole
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// appendPath returns path extended with key without sharing path's backing array
+func appendPath(path []string, key string) []string {
+	child := make([]string, len(path)+1)
+	copy(child, path)
+	child[len(path)] = key
+	return child
+}
+
+// maxSuggestionDistance is the largest edit distance at which a known property
+// is suggested for an unknown one
+const maxSuggestionDistance = 3
+
+// closestProperty returns the known property closest to name, if it is close enough to be a typo
+func closestProperty(name string, properties map[string]*Schema) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for property := range properties {
+		d := editDistance(name, property)
+		if d < bestDistance || (d == bestDistance && property < best) {
+			best, bestDistance = property, d
+		}
+	}
+	if bestDistance > maxSuggestionDistance {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
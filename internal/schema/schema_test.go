@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+type: object
+required: [name, cidr]
+additionalProperties: false
+properties:
+  name:
+    type: string
+    pattern: "^[a-z-]+$"
+    maxLength: 10
+  cidr: {type: string}
+  enabled: {type: boolean}
+  traffic_type: {enum: [ALL, ACCEPT, REJECT]}
+  max_azs: {type: integer, minimum: 1, maximum: 3}
+  zones:
+    type: array
+    minItems: 1
+    items: {type: string}
+  tags:
+    type: object
+    additionalProperties: {type: string}
+  nullable: {type: [string, "null"]}
+`
+
+// loadTestSchema writes a schema to a temporary file and loads it
+func loadTestSchema(t *testing.T, name, content string) *Schema {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return s
+}
+
+func TestValidate(t *testing.T) {
+	s := loadTestSchema(t, "schema.yaml", testSchema)
+
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected []string
+	}{
+		{
+			name: "Valid",
+			value: map[string]interface{}{
+				"name": "vpc", "cidr": "10.0.0.0/16", "enabled": true, "traffic_type": "ALL",
+				"max_azs": uint64(3), "zones": []interface{}{"a"}, "tags": map[string]interface{}{"team": "platform"}, "nullable": nil,
+			},
+		},
+		{
+			name:     "Missing required",
+			value:    map[string]interface{}{"name": "vpc"},
+			expected: []string{`missing required property "cidr"`},
+		},
+		{
+			name:     "Unknown property with suggestion",
+			value:    map[string]interface{}{"name": "vpc", "cidr": "x", "enable": true, "zzzzzzzz": 1},
+			expected: []string{`enable: unknown property, did you mean "enabled"?`, "zzzzzzzz: unknown property"},
+		},
+		{
+			name:     "Wrong types",
+			value:    map[string]interface{}{"name": 1, "cidr": "x", "enabled": "yes", "max_azs": 1.5},
+			expected: []string{"enabled: expected boolean, got string", "max_azs: expected integer, got number", "name: expected string, got integer"},
+		},
+		{
+			name:     "Enum, pattern and bounds",
+			value:    map[string]interface{}{"name": "Not-Valid-Name", "cidr": "x", "traffic_type": "SOME", "max_azs": int64(4)},
+			expected: []string{`max_azs: 4 is greater than the maximum 3`, `name: "Not-Valid-Name" is longer than 10 characters`, `name: "Not-Valid-Name" does not match pattern "^[a-z-]+$"`, `traffic_type: "SOME" is not one of ["ALL", "ACCEPT", "REJECT"]`},
+		},
+		{
+			name:     "Nested values",
+			value:    map[string]interface{}{"name": "vpc", "cidr": "x", "zones": []interface{}{"a", 2}, "tags": map[string]interface{}{"team": true}},
+			expected: []string{"tags.team: expected string, got boolean", "zones.1: expected string, got integer"},
+		},
+		{
+			name:     "Too few items",
+			value:    map[string]interface{}{"name": "vpc", "cidr": "x", "zones": []interface{}{}},
+			expected: []string{"zones: expected at least 1 items, got 0"},
+		},
+		{
+			name:     "Not an object",
+			value:    "vpc",
+			expected: []string{"expected object, got string"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var messages []string
+			for _, violation := range s.Validate(tc.value) {
+				messages = append(messages, violation.String())
+			}
+			if !reflect.DeepEqual(messages, tc.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q", tc.expected, messages)
+			}
+		})
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	s := loadTestSchema(t, "schema.json", `{"type": "object", "properties": {"count": {"type": "integer", "enum": [1, 2]}}, "additionalProperties": true}`)
+
+	if violations := s.Validate(map[string]interface{}{"count": uint64(2), "extra": "x"}); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+	if violations := s.Validate(map[string]interface{}{"count": uint64(3)}); len(violations) != 1 {
+		t.Errorf("Expected an enum violation, got %v", violations)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := map[string]string{
+		"bad type":    `{"type": "text"}`,
+		"bad pattern": `{"properties": {"name": {"pattern": "("}}}`,
+		"bad json":    `{"type": `,
+	}
+
+	for name, content := range testCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.json")
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf("Failed to write schema: %v", err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+				t.Errorf("Expected an error naming %s, got %v", path, err)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	s := &Schema{
+		Type:                 Types{TypeObject},
+		Properties:           map[string]*Schema{"name": {Type: Types{TypeString, TypeNull}}},
+		AdditionalProperties: Never(),
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := `{"type":"object","properties":{"name":{"type":["string","null"]}},"additionalProperties":false}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
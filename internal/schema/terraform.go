@@ -0,0 +1,359 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/hcl"
+)
+
+// ImportTerraform builds a schema for the vars of a component from the
+// variable blocks in the .tf files of a Terraform module directory. Each
+// variable becomes a property typed after its type constraint, with its
+// description and default. Variables without a default are required, a null
+// default also allows null, and unknown vars are rejected.
+//
+// Validation blocks whose condition is contains([...], var.x),
+// can(regex("...", var.x)) or a comparison of length(var.x) with a number,
+// joined with &&, become enums, patterns and length bounds. Any other
+// condition is skipped and returned as a warning.
+func ImportTerraform(dir string) (*Schema, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Terraform files in %s: %w", dir, err)
+	}
+
+	s := &Schema{
+		Type:                 Types{TypeObject},
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: Never(),
+	}
+
+	var warnings []string
+	for _, path := range files {
+		file, err := hcl.ParseFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, block := range file.Body.BlocksOfType("variable") {
+			if len(block.Labels) != 1 {
+				return nil, nil, fmt.Errorf("%s:%d: variable block must have exactly one label", path, block.Line)
+			}
+			name := block.Labels[0]
+			if _, ok := s.Properties[name]; ok {
+				return nil, nil, fmt.Errorf("%s:%d: variable %q is declared more than once", path, block.Line, name)
+			}
+
+			property, required, skipped, err := variableSchema(name, block.Body)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: variable %q: %w", path, block.Line, name, err)
+			}
+			for _, condition := range skipped {
+				warnings = append(warnings, fmt.Sprintf("%s:%d: variable %q: validation not converted: %s", path, block.Line, name, condition))
+			}
+
+			s.Properties[name] = property
+			if required {
+				s.Required = append(s.Required, name)
+			}
+		}
+	}
+
+	if len(s.Properties) == 0 {
+		return nil, nil, fmt.Errorf("no variable blocks found in %s", dir)
+	}
+	if err := s.compile(); err != nil {
+		return nil, nil, err
+	}
+	return s, warnings, nil
+}
+
+// variableSchema converts the body of a variable block. It reports whether
+// the variable is required and the validation conditions it could not convert.
+func variableSchema(name string, body *hcl.Body) (*Schema, bool, []string, error) {
+	s := &Schema{}
+	if attr := body.Attribute("type"); attr != nil {
+		t, err := typeSchema(attr.Expr)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		s = t
+	}
+
+	if attr := body.Attribute("description"); attr != nil {
+		description, ok := attr.Expr.Value.(string)
+		if !ok || attr.Expr.Kind != hcl.KindLiteral {
+			return nil, false, nil, fmt.Errorf("description must be a string")
+		}
+		s.Description = description
+	}
+
+	required := true
+	if attr := body.Attribute("default"); attr != nil {
+		required = false
+		value, ok := attr.Expr.Literal()
+		if !ok {
+			return nil, false, nil, fmt.Errorf("default must be a literal value, got %s", attr.Expr.Source)
+		}
+		if value == nil {
+			if len(s.Type) > 0 {
+				s.Type = append(s.Type, TypeNull)
+			}
+		} else {
+			s.Default = value
+		}
+	}
+
+	var skipped []string
+	for _, validation := range body.BlocksOfType("validation") {
+		attr := validation.Body.Attribute("condition")
+		if attr == nil {
+			continue
+		}
+		if !applyCondition(s, name, attr.Expr.Source) {
+			skipped = append(skipped, attr.Expr.Source)
+		}
+	}
+	return s, required, skipped, nil
+}
+
+// typeSchema converts a Terraform type constraint
+func typeSchema(expr *hcl.Expression) (*Schema, error) {
+	switch expr.Kind {
+	case hcl.KindReference:
+		switch expr.Name {
+		case "string":
+			return &Schema{Type: Types{TypeString}}, nil
+		case "number":
+			return &Schema{Type: Types{TypeNumber}}, nil
+		case "bool":
+			return &Schema{Type: Types{TypeBoolean}}, nil
+		case "any":
+			return &Schema{}, nil
+		}
+	case hcl.KindCall:
+		switch expr.Name {
+		case "list", "set", "map":
+			if len(expr.Items) != 1 {
+				return nil, fmt.Errorf("type %s must have one argument", expr.Source)
+			}
+			element, err := typeSchema(expr.Items[0])
+			if err != nil {
+				return nil, err
+			}
+			if expr.Name == "map" {
+				return &Schema{Type: Types{TypeObject}, AdditionalProperties: element}, nil
+			}
+			return &Schema{Type: Types{TypeArray}, Items: element}, nil
+		case "tuple":
+			if len(expr.Items) != 1 || expr.Items[0].Kind != hcl.KindTuple {
+				return nil, fmt.Errorf("type %s must have a list of element types", expr.Source)
+			}
+			n := len(expr.Items[0].Items)
+			return &Schema{Type: Types{TypeArray}, MinItems: &n, MaxItems: &n}, nil
+		case "object":
+			if len(expr.Items) != 1 || expr.Items[0].Kind != hcl.KindObject {
+				return nil, fmt.Errorf("type %s must have an object of attribute types", expr.Source)
+			}
+			return objectSchema(expr.Items[0])
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", expr.Source)
+}
+
+// objectSchema converts the attributes of an object type constraint. Attributes
+// wrapped in optional() are not required and keep their default.
+func objectSchema(attributes *hcl.Expression) (*Schema, error) {
+	s := &Schema{
+		Type:                 Types{TypeObject},
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: Never(),
+	}
+
+	for _, field := range attributes.Fields {
+		expr := field.Value
+		optional := expr.Kind == hcl.KindCall && expr.Name == "optional"
+		if optional {
+			if len(expr.Items) < 1 || len(expr.Items) > 2 {
+				return nil, fmt.Errorf("type %s must have a type and an optional default", expr.Source)
+			}
+			expr = expr.Items[0]
+		}
+
+		property, err := typeSchema(expr)
+		if err != nil {
+			return nil, err
+		}
+		if optional && len(field.Value.Items) == 2 {
+			value, ok := field.Value.Items[1].Literal()
+			if !ok {
+				return nil, fmt.Errorf("default of %s must be a literal value", field.Key)
+			}
+			property.Default = value
+		}
+
+		s.Properties[field.Key] = property
+		if !optional {
+			s.Required = append(s.Required, field.Key)
+		}
+	}
+	return s, nil
+}
+
+// applyCondition adds the constraints expressed by a validation condition to
+// s. It reports false, leaving s unchanged, if any part of the condition
+// cannot be expressed in the schema.
+func applyCondition(s *Schema, name, condition string) bool {
+	ref := `var\.` + regexp.QuoteMeta(name)
+	enum := regexp.MustCompile(`(?s)^contains\(\s*(\[.*\])\s*,\s*` + ref + `\s*\)$`)
+	pattern := regexp.MustCompile(`^can\(\s*regex\(\s*("(?:[^"\\]|\\.)*")\s*,\s*` + ref + `\s*\)\s*\)$`)
+	length := regexp.MustCompile(`^length\(\s*` + ref + `\s*\)\s*(>=|<=|==|>|<)\s*(\d+)$`)
+
+	result := *s
+	for _, part := range splitConjunction(condition) {
+		if m := enum.FindStringSubmatch(part); m != nil {
+			values, ok := parseLiteral(m[1])
+			list, isList := values.([]interface{})
+			if !ok || !isList {
+				return false
+			}
+			result.Enum = list
+			continue
+		}
+
+		if m := pattern.FindStringSubmatch(part); m != nil {
+			value, ok := parseLiteral(m[1])
+			if !ok {
+				return false
+			}
+			result.Pattern = value.(string)
+			continue
+		}
+
+		if m := length.FindStringSubmatch(part); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			var minimum, maximum **int
+			switch {
+			case result.Type.has(TypeString):
+				minimum, maximum = &result.MinLength, &result.MaxLength
+			case result.Type.has(TypeArray):
+				minimum, maximum = &result.MinItems, &result.MaxItems
+			default:
+				return false
+			}
+			lower, upper := n, n
+			switch m[1] {
+			case ">":
+				lower++
+			case "<":
+				upper--
+			}
+			if m[1] != "<" && m[1] != "<=" {
+				*minimum = &lower
+			}
+			if m[1] != ">" && m[1] != ">=" {
+				*maximum = &upper
+			}
+			continue
+		}
+
+		return false
+	}
+
+	*s = result
+	return true
+}
+
+// has reports whether the types include name
+func (t Types) has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLiteral parses an HCL literal expression such as ["a", "b"]
+func parseLiteral(source string) (interface{}, bool) {
+	file, err := hcl.Parse([]byte("value = "+source), "")
+	if err != nil {
+		return nil, false
+	}
+	attr := file.Body.Attribute("value")
+	if attr == nil {
+		return nil, false
+	}
+	return attr.Expr.Literal()
+}
+
+// splitConjunction splits a condition on the && operators outside of
+// brackets and strings, trimming each part of whitespace and enclosing parentheses
+func splitConjunction(condition string) []string {
+	var parts []string
+	depth, start := 0, 0
+	inString := false
+	for i := 0; i < len(condition); i++ {
+		c := condition[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case depth == 0 && strings.HasPrefix(condition[i:], "&&"):
+			parts = append(parts, condition[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	parts = append(parts, condition[start:])
+
+	for i, part := range parts {
+		parts[i] = trimParens(part)
+	}
+	return parts
+}
+
+// trimParens removes whitespace and parentheses enclosing a whole expression
+func trimParens(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") || closingParen(s) != len(s)-1 {
+			return s
+		}
+		s = s[1 : len(s)-1]
+	}
+}
+
+// closingParen returns the index of the parenthesis closing the one at the start of s
+func closingParen(s string) int {
+	depth := 0
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testVariables = `variable "name" {
+  type        = string
+  description = "Name of the VPC"
+
+  validation {
+    condition     = can(regex("^[a-z-]+$", var.name)) && length(var.name) <= 10
+    error_message = "Name must be lowercase."
+  }
+}
+
+variable "traffic_type" {
+  type    = string
+  default = "ALL"
+
+  validation {
+    condition     = contains(["ALL", "ACCEPT", "REJECT"], var.traffic_type)
+    error_message = "Invalid traffic type."
+  }
+}
+
+variable "zones" {
+  type    = list(string)
+  default = null
+
+  validation {
+    condition     = length(var.zones) > 0
+    error_message = "At least one zone is required."
+  }
+}
+
+variable "max_azs" {
+  type    = number
+  default = 3
+
+  validation {
+    condition     = var.max_azs >= 1
+    error_message = "At least one AZ."
+  }
+}
+`
+
+const testOutputs = `variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "subnets" {
+  type = object({
+    cidr    = string
+    private = optional(bool, true)
+    pair    = optional(tuple([string, number]))
+  })
+}
+
+variable "extra" {}
+
+output "id" {
+  value = "vpc-${var.name}"
+}
+`
+
+func TestImportTerraform(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"variables.tf": testVariables, "main.tf": testOutputs, "README.md": "variable \"ignored\" {}"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	s, warnings, err := ImportTerraform(dir)
+	if err != nil {
+		t.Fatalf("ImportTerraform failed: %v", err)
+	}
+
+	expectedWarning := filepath.Join(dir, "variables.tf") + `:31: variable "max_azs": validation not converted: var.max_azs >= 1`
+	if len(warnings) != 1 || warnings[0] != expectedWarning {
+		t.Errorf("Expected warning %q, got %q", expectedWarning, warnings)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := `{
+  "type": "object",
+  "properties": {
+    "extra": {},
+    "max_azs": {
+      "type": "number",
+      "default": 3
+    },
+    "name": {
+      "description": "Name of the VPC",
+      "type": "string",
+      "pattern": "^[a-z-]+$",
+      "maxLength": 10
+    },
+    "subnets": {
+      "type": "object",
+      "properties": {
+        "cidr": {
+          "type": "string"
+        },
+        "pair": {
+          "type": "array",
+          "minItems": 2,
+          "maxItems": 2
+        },
+        "private": {
+          "type": "boolean",
+          "default": true
+        }
+      },
+      "required": [
+        "cidr"
+      ],
+      "additionalProperties": false
+    },
+    "tags": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      },
+      "default": {}
+    },
+    "traffic_type": {
+      "type": "string",
+      "enum": [
+        "ALL",
+        "ACCEPT",
+        "REJECT"
+      ],
+      "default": "ALL"
+    },
+    "zones": {
+      "type": [
+        "array",
+        "null"
+      ],
+      "items": {
+        "type": "string"
+      },
+      "minItems": 1
+    }
+  },
+  "required": [
+    "subnets",
+    "extra",
+    "name"
+  ],
+  "additionalProperties": false
+}`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	// The imported schema validates like a loaded one
+	violations := s.Validate(map[string]interface{}{
+		"name": "Bad_Name", "extra": 1, "traffic_type": "SOME", "zones": nil,
+		"subnets": map[string]interface{}{"cidr": "10.0.0.0/24", "privat": false},
+	})
+	var messages []string
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	expectedMessages := []string{
+		`name: "Bad_Name" does not match pattern "^[a-z-]+$"`,
+		`subnets.privat: unknown property, did you mean "private"?`,
+		`traffic_type: "SOME" is not one of ["ALL", "ACCEPT", "REJECT"]`,
+	}
+	if !reflect.DeepEqual(messages, expectedMessages) {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expectedMessages, messages)
+	}
+}
+
+func TestImportTerraformErrors(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		expected string
+	}{
+		"no variables":        {`output "x" { value = 1 }`, "no variable blocks found in"},
+		"unsupported type":    {"variable \"x\" {\n  type = list(var.y)\n}", `main.tf:1: variable "x": unsupported type var.y`},
+		"non-literal default": {"variable \"x\" {\n  default = local.y\n}", `main.tf:1: variable "x": default must be a literal value, got local.y`},
+		"duplicate":           {"variable \"x\" {}\nvariable \"x\" {}", `main.tf:2: variable "x" is declared more than once`},
+		"syntax error":        {"variable \"x\" {", `main.tf:1:15: expected "}", got end of file`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte(tc.source), 0600); err != nil {
+				t.Fatalf("Failed to write main.tf: %v", err)
+			}
+			if _, _, err := ImportTerraform(dir); err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -9,13 +9,23 @@ import (
 	"strings"
 
 	"github.com/goccy/go-yaml"
+	"github.com/mcalhoun/skunk/internal/logger"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
 )
 
 // StackMetadata contains the metadata extracted from a Stack file
 type StackMetadata struct {
-	Name     string            // metadata.name from the Stack
-	Labels   map[string]string // metadata.labels from the Stack
-	FilePath string            // path to the Stack file
+	Name       string            // metadata.name from the Stack
+	Labels     map[string]string // metadata.labels from the Stack
+	FilePath   string            // path to the Stack file
+	APIVersion string            // apiVersion declared by the Stack, empty if it has none
+}
+
+// StackDocument is a stack together with the document the engine resolved it to
+type StackDocument struct {
+	StackMetadata
+	Document   map[string]interface{}   // resolved document, nil if the stack failed to resolve
+	Provenance yamlparser.ProvenanceMap // where every key of Document was set, if recorded
 }
 
 // Stack represents the minimal structure needed to identify and extract metadata from a Stack file
@@ -31,13 +41,64 @@ type Stack struct {
 // FindStacks finds all YAML files matching the glob pattern, parses them, and returns
 // metadata for those that are of kind: Stack
 func FindStacks(globPattern string) ([]StackMetadata, error) {
+	stacks, diags, err := findStacks(globPattern, nil)
+	logDiagnostics(diags)
+	return stacks, err
+}
+
+// FindStacksWithCatalog finds stacks like FindStacks, but resolves each file with the
+// anchors, merges and imports that yamlparser.ParseStack uses so that labels set
+// through "<<" merges are part of the metadata
+func FindStacksWithCatalog(globPattern string, catalogDir string, opts yamlparser.Options) ([]StackMetadata, error) {
+	stacks, diags, err := FindStacksWithDiagnostics(globPattern, catalogDir, opts)
+	logDiagnostics(diags)
+	return stacks, err
+}
+
+// FindStacksWithDiagnostics finds stacks like FindStacksWithCatalog, but returns a
+// diagnostic for every stack that could not be resolved instead of logging it.
+// Stacks that fail to resolve are still returned with the metadata that could be
+// read without the catalog.
+func FindStacksWithDiagnostics(globPattern string, catalogDir string, opts yamlparser.Options) ([]StackMetadata, yamlparser.Diagnostics, error) {
+	engine, err := yamlparser.NewStackEngine(catalogDir, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading catalog %s: %w", catalogDir, err)
+	}
+	return findStacks(globPattern, engine)
+}
+
+// FindStackDocuments finds stacks like FindStacksWithDiagnostics, resolving them
+// with an engine the caller has already created. Each stack is decoded once and
+// keeps the document and provenance it was resolved to, both nil if it failed.
+func FindStackDocuments(globPattern string, engine *yamlparser.Engine) ([]StackDocument, yamlparser.Diagnostics, error) {
+	return findStackDocuments(globPattern, engine, true)
+}
+
+// findStacks finds stacks matching the glob pattern, resolving them with engine if it is not nil
+func findStacks(globPattern string, engine *yamlparser.Engine) ([]StackMetadata, yamlparser.Diagnostics, error) {
+	documents, diags, err := findStackDocuments(globPattern, engine, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stacks []StackMetadata
+	for _, document := range documents {
+		stacks = append(stacks, document.StackMetadata)
+	}
+	return stacks, diags, nil
+}
+
+// findStackDocuments finds stacks matching the glob pattern, resolving them with
+// engine if it is not nil and recording provenance if requested
+func findStackDocuments(globPattern string, engine *yamlparser.Engine, trackProvenance bool) ([]StackDocument, yamlparser.Diagnostics, error) {
 	// Find all files matching the glob pattern
 	matches, err := filepath.Glob(globPattern)
 	if err != nil {
-		return nil, fmt.Errorf("error matching glob pattern %s: %w", globPattern, err)
+		return nil, nil, fmt.Errorf("error matching glob pattern %s: %w", globPattern, err)
 	}
 
-	var stacks []StackMetadata
+	var stacks []StackDocument
+	var diags yamlparser.Diagnostics
 
 	for _, filePath := range matches {
 		// Check if it's a file
@@ -55,24 +116,42 @@ func FindStacks(globPattern string) ([]StackMetadata, error) {
 		}
 
 		// Try to identify and extract Stack information
-		metadata, found, err := extractStackMetadata(filePath)
-		if err != nil {
-			fmt.Printf("Warning: Error processing %s: %v\n", filePath, err)
-			continue
-		}
+		document, found, fileDiags := extractStackDocument(filePath, engine, trackProvenance)
+		diags = append(diags, fileDiags...)
 		if !found {
 			continue
 		}
 
-		stacks = append(stacks, metadata)
+		stacks = append(stacks, document)
 	}
 
-	return stacks, nil
+	return stacks, diags.Unique(), nil
 }
 
 // FindStacksRecursive finds all Stack files in a directory and its subdirectories
 func FindStacksRecursive(root string) ([]StackMetadata, error) {
+	stacks, diags, err := findStacksRecursive(root, nil)
+	logDiagnostics(diags)
+	return stacks, err
+}
+
+// FindStacksRecursiveWithCatalog finds stacks like FindStacksRecursive, resolving
+// each file against the catalog like FindStacksWithCatalog
+func FindStacksRecursiveWithCatalog(root string, catalogDir string, opts yamlparser.Options) ([]StackMetadata, error) {
+	engine, err := yamlparser.NewStackEngine(catalogDir, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error loading catalog %s: %w", catalogDir, err)
+	}
+
+	stacks, diags, err := findStacksRecursive(root, engine)
+	logDiagnostics(diags)
+	return stacks, err
+}
+
+// findStacksRecursive walks root for stacks, resolving them with engine if it is not nil
+func findStacksRecursive(root string, engine *yamlparser.Engine) ([]StackMetadata, yamlparser.Diagnostics, error) {
 	var stacks []StackMetadata
+	var diags yamlparser.Diagnostics
 
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -90,11 +169,8 @@ func FindStacksRecursive(root string) ([]StackMetadata, error) {
 		}
 
 		// Try to identify and extract Stack information
-		metadata, found, err := extractStackMetadata(path)
-		if err != nil {
-			fmt.Printf("Warning: Error processing %s: %v\n", path, err)
-			return nil
-		}
+		metadata, found, fileDiags := extractStackMetadata(path, engine)
+		diags = append(diags, fileDiags...)
 		if !found {
 			return nil
 		}
@@ -104,37 +180,113 @@ func FindStacksRecursive(root string) ([]StackMetadata, error) {
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("error walking directory %s: %w", root, err)
+		return nil, nil, fmt.Errorf("error walking directory %s: %w", root, err)
+	}
+
+	return stacks, diags.Unique(), nil
+}
+
+// logDiagnostics logs diagnostics for callers that do not collect them
+func logDiagnostics(diags yamlparser.Diagnostics) {
+	for _, diag := range diags {
+		logger.Log.Warnf("Error processing %s", diag)
 	}
+}
 
-	return stacks, nil
+// extractStackMetadata attempts to extract Stack metadata from a YAML file.
+// It resolves the file with engine when one is given, then tries plain YAML
+// parsing, and as a last resort falls back to regex-based detection, which
+// misses any label set through an anchor or merge. A stack that the engine
+// cannot resolve is still found, together with the diagnostics explaining why;
+// files that are not stacks never produce diagnostics.
+func extractStackMetadata(filePath string, engine *yamlparser.Engine) (StackMetadata, bool, yamlparser.Diagnostics) {
+	document, found, diags := extractStackDocument(filePath, engine, false)
+	return document.StackMetadata, found, diags
 }
 
-// extractStackMetadata attempts to extract Stack metadata from a YAML file
-// It first tries to unmarshal the YAML, and if that fails due to unresolved anchors,
-// it falls back to regex-based detection
-func extractStackMetadata(filePath string) (StackMetadata, bool, error) {
+// extractStackDocument extracts Stack metadata like extractStackMetadata and
+// keeps the document the engine resolved, with its provenance if requested
+func extractStackDocument(filePath string, engine *yamlparser.Engine, trackProvenance bool) (StackDocument, bool, yamlparser.Diagnostics) {
 	// Read file content
 	fileData, err := os.ReadFile(filePath)
 	if err != nil {
-		return StackMetadata{}, false, fmt.Errorf("failed to read file: %w", err)
+		return StackDocument{}, false, yamlparser.AsDiagnostics(fmt.Errorf("failed to read file: %w", err), filePath)
 	}
 
-	// First attempt: Try standard YAML parsing
+	// First attempt: Resolve anchors, merges and imports against the catalog
+	var diags yamlparser.Diagnostics
+	if engine != nil {
+		var document map[string]interface{}
+		var provenance yamlparser.ProvenanceMap
+		if trackProvenance {
+			document, provenance, err = engine.DecodeWithProvenance(fileData, filePath)
+		} else {
+			document, err = engine.Decode(fileData, filePath)
+		}
+		if err == nil {
+			metadata, found := stackMetadataFromDocument(filePath, document)
+			return StackDocument{StackMetadata: metadata, Document: document, Provenance: provenance}, found, nil
+		}
+		diags = yamlparser.AsDiagnostics(err, filePath)
+	}
+
+	// Second attempt: Try standard YAML parsing
 	var stack Stack
 	err = yaml.Unmarshal(fileData, &stack)
 
 	// If parsing succeeded and it's a Stack, extract metadata
 	if err == nil && stack.Kind == "Stack" {
-		return StackMetadata{
-			Name:     stack.Metadata.Name,
-			Labels:   stack.Metadata.Labels,
-			FilePath: filePath,
-		}, true, nil
+		return StackDocument{StackMetadata: StackMetadata{
+			Name:       stack.Metadata.Name,
+			Labels:     stack.Metadata.Labels,
+			FilePath:   filePath,
+			APIVersion: stack.APIVersion,
+		}}, true, diags
+	}
+
+	// Last resort: Use regex-based detection for files that might contain unresolved anchors
+	metadata, found, regexErr := extractStackMetadataWithRegex(filePath, fileData)
+	if regexErr != nil {
+		return StackDocument{StackMetadata: metadata}, false, append(diags, yamlparser.AsDiagnostics(regexErr, filePath)...)
+	}
+	if !found {
+		return StackDocument{StackMetadata: metadata}, false, nil
+	}
+	if diags == nil {
+		logger.Log.Warnf("Could not resolve %s, labels set through anchors or merges are ignored", filePath)
+	}
+	return StackDocument{StackMetadata: metadata}, true, diags
+}
+
+// stackMetadataFromDocument extracts Stack metadata from a resolved document.
+// Label values that are not strings are converted to their string form.
+func stackMetadataFromDocument(filePath string, document map[string]interface{}) (StackMetadata, bool) {
+	if kind, _ := document["kind"].(string); kind != "Stack" {
+		return StackMetadata{}, false
+	}
+
+	metadata := StackMetadata{FilePath: filePath}
+	if apiVersion, ok := document["apiVersion"]; ok && apiVersion != nil {
+		metadata.APIVersion = fmt.Sprint(apiVersion)
 	}
 
-	// Second attempt: Use regex-based detection for files that might contain unresolved anchors
-	return extractStackMetadataWithRegex(filePath, fileData)
+	meta, _ := document["metadata"].(map[string]interface{})
+	if name, ok := meta["name"]; ok && name != nil {
+		metadata.Name = fmt.Sprint(name)
+	}
+
+	if labels, ok := meta["labels"].(map[string]interface{}); ok {
+		metadata.Labels = make(map[string]string, len(labels))
+		for key, value := range labels {
+			if value == nil {
+				metadata.Labels[key] = ""
+				continue
+			}
+			metadata.Labels[key] = fmt.Sprint(value)
+		}
+	}
+
+	return metadata, true
 }
 
 // extractStackMetadataWithRegex uses regex to extract Stack information from a YAML file with unresolved anchors
@@ -155,6 +307,12 @@ func extractStackMetadataWithRegex(filePath string, fileData []byte) (StackMetad
 	}
 	name := nameMatches[1]
 
+	var apiVersion string
+	apiVersionRe := regexp.MustCompile(`(?m)^apiVersion:\s*(.+?)\s*$`)
+	if matches := apiVersionRe.FindStringSubmatch(content); len(matches) == 2 {
+		apiVersion = strings.Trim(matches[1], `"'`)
+	}
+
 	// Extract labels directly with a simpler approach
 	labels := make(map[string]string)
 
@@ -191,9 +349,10 @@ func extractStackMetadataWithRegex(filePath string, fileData []byte) (StackMetad
 	}
 
 	return StackMetadata{
-		Name:     name,
-		Labels:   labels,
-		FilePath: filePath,
+		Name:       name,
+		Labels:     labels,
+		FilePath:   filePath,
+		APIVersion: apiVersion,
 	}, true, nil
 }
 
@@ -3,7 +3,11 @@ package stackfinder
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
 )
 
 func TestFindStacks(t *testing.T) {
@@ -52,6 +56,9 @@ func TestFindStacks(t *testing.T) {
 			if stack.FilePath != stackFile1 {
 				t.Errorf("stack1 has incorrect file path: %s, expected: %s", stack.FilePath, stackFile1)
 			}
+			if stack.APIVersion != "skunk.mattcalhoun.com/v1" {
+				t.Errorf("stack1 has incorrect apiVersion: %s", stack.APIVersion)
+			}
 		case "stack2":
 			if stack.Labels["env"] != "prod" || stack.Labels["region"] != "us-west-2" {
 				t.Errorf("stack2 has incorrect labels: %v", stack.Labels)
@@ -103,6 +110,111 @@ func TestFindStacks(t *testing.T) {
 	}
 }
 
+func TestFindStacksWithCatalog(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	catalogDir := filepath.Join(tmpDir, "catalog", "region")
+	if err := os.MkdirAll(catalogDir, 0755); err != nil {
+		t.Fatalf("Failed to create catalog dir: %v", err)
+	}
+	anchors := "primary-region: &primary-region\n  region: us-east-1\n"
+	if err := os.WriteFile(filepath.Join(catalogDir, "primary-region.yaml"), []byte(anchors), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	stacksDir := filepath.Join(tmpDir, "stacks")
+	if err := os.Mkdir(stacksDir, 0755); err != nil {
+		t.Fatalf("Failed to create stacks dir: %v", err)
+	}
+	stackContent := `apiVersion: skunk.mattcalhoun.com/v1
+kind: Stack
+metadata:
+  name: anchored
+  labels:
+    environment: dev
+    <<: *primary-region
+    replicas: 3
+`
+	if err := os.WriteFile(filepath.Join(stacksDir, "anchored.yaml"), []byte(stackContent), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	createNonStackFile(t, filepath.Join(stacksDir, "non-stack.yaml"))
+
+	expected := map[string]string{"environment": "dev", "region": "us-east-1", "replicas": "3"}
+
+	stacks, err := FindStacksWithCatalog(filepath.Join(stacksDir, "*.yaml"), filepath.Join(tmpDir, "catalog"), yamlparser.DefaultOptions())
+	if err != nil {
+		t.Fatalf("FindStacksWithCatalog failed: %v", err)
+	}
+	if len(stacks) != 1 || stacks[0].Name != "anchored" {
+		t.Fatalf("Expected the anchored stack, got %v", stacks)
+	}
+	if !reflect.DeepEqual(stacks[0].Labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, stacks[0].Labels)
+	}
+
+	recursiveStacks, err := FindStacksRecursiveWithCatalog(stacksDir, filepath.Join(tmpDir, "catalog"), yamlparser.DefaultOptions())
+	if err != nil {
+		t.Fatalf("FindStacksRecursiveWithCatalog failed: %v", err)
+	}
+	if len(recursiveStacks) != 1 || !reflect.DeepEqual(recursiveStacks[0].Labels, expected) {
+		t.Errorf("Unexpected recursive stacks: %v", recursiveStacks)
+	}
+
+	// Without the catalog the merge can't be resolved and the regex fallback only sees literal labels
+	fallbackStacks, err := FindStacks(filepath.Join(stacksDir, "*.yaml"))
+	if err != nil {
+		t.Fatalf("FindStacks failed: %v", err)
+	}
+	if len(fallbackStacks) != 1 {
+		t.Fatalf("Expected 1 stack, got %d", len(fallbackStacks))
+	}
+	if _, ok := fallbackStacks[0].Labels["region"]; ok {
+		t.Errorf("Expected region to be missing without the catalog, got %v", fallbackStacks[0].Labels)
+	}
+
+	// A missing catalog is an error
+	if _, err := FindStacksWithCatalog(filepath.Join(stacksDir, "*.yaml"), filepath.Join(tmpDir, "missing"), yamlparser.DefaultOptions()); err == nil {
+		t.Error("Expected error for missing catalog, got nil")
+	}
+}
+
+func TestFindStacksWithDiagnostics(t *testing.T) {
+	tmpDir := writeStackFiles(t, map[string]string{
+		"good.yaml":    "kind: Stack\nmetadata:\n  name: good\n",
+		"alias.yaml":   "kind: Stack\nmetadata:\n  name: alias\n  labels:\n    <<: *missing\n",
+		"syntax.yaml":  "kind: Stack\nmetadata:\n  name: syntax\nspec: [unclosed\n",
+		"ignored.yaml": "kind: Deployment\nvalue: *missing\n",
+	})
+
+	stacks, diags, err := FindStacksWithDiagnostics(filepath.Join(tmpDir, "stacks", "*.yaml"), filepath.Join(tmpDir, "catalog"), yamlparser.DefaultOptions())
+	if err != nil {
+		t.Fatalf("FindStacksWithDiagnostics failed: %v", err)
+	}
+
+	// Broken stacks are still found so they can be listed
+	if len(stacks) != 3 {
+		t.Errorf("Expected 3 stacks, got %v", stacks)
+	}
+
+	// Every broken stack is reported, and files that are not stacks are not
+	if len(diags) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diags)
+	}
+	expected := []string{
+		filepath.Join(tmpDir, "stacks", "alias.yaml") + `:5:9: could not find alias "missing"`,
+		filepath.Join(tmpDir, "stacks", "syntax.yaml") + ":4:7: ",
+	}
+	for i, diag := range diags {
+		if !strings.HasPrefix(diag.Error(), expected[i]) {
+			t.Errorf("Expected diagnostic starting with %q, got %q", expected[i], diag.Error())
+		}
+		if diag.Snippet == "" {
+			t.Errorf("Expected a snippet for %s", diag.File)
+		}
+	}
+}
+
 func createTestStackFile(t *testing.T, path, name string, labels map[string]string) {
 	yamlContent := `apiVersion: skunk.mattcalhoun.com/v1
 kind: Stack
@@ -0,0 +1,206 @@
+package stackfinder
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// StackTag is the tag that references a value in another stack
+const StackTag = "!stack"
+
+// StackResolver resolves "!stack <name> <path>" tags by finding the stack whose
+// metadata.name is <name>, merging it and returning the value at <path>.
+//
+// The path is either a full document path starting with "metadata" or "spec",
+// or a component shorthand "<type>.<component>.<var>" that refers to
+// spec.components.<type>.<component>.vars.<var>.
+type StackResolver struct {
+	globPattern string
+	catalogDir  string
+	opts        yamlparser.Options
+
+	engine    *yamlparser.Engine
+	stacks    []StackMetadata
+	documents map[string]map[string]interface{} // merged stacks by name
+	resolving []string                          // names of the stacks being merged, outermost first
+}
+
+// NewStackResolver creates a resolver that finds stacks matching globPattern and
+// merges them with the anchors in catalogDir. Stacks are decoded with opts, whose
+// tag registry should be the one the resolver is registered with so that nested
+// references are resolved too.
+func NewStackResolver(globPattern string, catalogDir string, opts yamlparser.Options) *StackResolver {
+	return &StackResolver{
+		globPattern: globPattern,
+		catalogDir:  catalogDir,
+		opts:        opts,
+		documents:   make(map[string]map[string]interface{}),
+	}
+}
+
+// Register adds the !stack tag to a registry
+func (r *StackResolver) Register(registry *yamlparser.TagRegistry) {
+	registry.Register(StackTag, r.resolveTag)
+}
+
+// resolveTag resolves a single !stack tag
+func (r *StackResolver) resolveTag(ctx *yamlparser.TagContext) (interface{}, error) {
+	value, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("expected \"<stack> <path>\", got %q", value)
+	}
+	name, path := fields[0], fields[1]
+
+	// The stack being decoded is the start of any reference chain
+	if len(r.resolving) == 0 {
+		if root := r.stackForFile(ctx.Document()); root != "" {
+			r.resolving = append(r.resolving, root)
+			defer func() { r.resolving = r.resolving[:0] }()
+		}
+	}
+
+	document, err := r.stack(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := lookupStackPath(document, stackReferencePath(path))
+	if !ok {
+		return nil, fmt.Errorf("path %q not found in stack %q", path, name)
+	}
+	return yamlparser.DeepCopy(result), nil
+}
+
+// stack returns the merged document of the stack with the given name
+func (r *StackResolver) stack(name string) (map[string]interface{}, error) {
+	if document, ok := r.documents[name]; ok {
+		return document, nil
+	}
+
+	for i, resolving := range r.resolving {
+		if resolving == name {
+			cycle := append(append([]string{}, r.resolving[i:]...), name)
+			return nil, fmt.Errorf("stack reference cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	var matches []StackMetadata
+	for _, stack := range r.stacks {
+		if stack.Name == name {
+			matches = append(matches, stack)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("stack %q not found", name)
+	case 1:
+	default:
+		return nil, fmt.Errorf("stack %q is defined in %d files", name, len(matches))
+	}
+
+	r.resolving = append(r.resolving, name)
+	defer func() { r.resolving = r.resolving[:len(r.resolving)-1] }()
+
+	document, err := r.engine.DecodeFile(matches[0].FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge stack %q: %w", name, err)
+	}
+
+	r.documents[name] = document
+	return document, nil
+}
+
+// load finds the stacks and creates the engine used to merge them. Stacks are
+// found with !stack tags left unresolved, so discovery never follows references.
+func (r *StackResolver) load() error {
+	if r.engine != nil {
+		return nil
+	}
+
+	discovery := r.opts
+	discovery.Tags = r.opts.Tags.Clone()
+	discovery.Tags.Register(StackTag, func(*yamlparser.TagContext) (interface{}, error) {
+		return nil, nil
+	})
+
+	// Stacks that fail to resolve are reported by the discovery of the caller
+	stacks, _, err := FindStacksWithDiagnostics(r.globPattern, r.catalogDir, discovery)
+	if err != nil {
+		return err
+	}
+
+	engine, err := yamlparser.NewStackEngine(r.catalogDir, r.opts)
+	if err != nil {
+		return err
+	}
+
+	r.stacks = stacks
+	r.engine = engine
+	return nil
+}
+
+// stackForFile returns the name of the stack defined in file, if it is one
+// of the stacks the resolver can find
+func (r *StackResolver) stackForFile(file string) string {
+	if err := r.load(); err != nil {
+		return ""
+	}
+
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return ""
+	}
+	for _, stack := range r.stacks {
+		if stackAbs, err := filepath.Abs(stack.FilePath); err == nil && stackAbs == abs {
+			return stack.Name
+		}
+	}
+	return ""
+}
+
+// stackReferencePath expands a !stack path into a document path
+func stackReferencePath(path string) []string {
+	parts := strings.Split(path, ".")
+	if parts[0] == "metadata" || parts[0] == "spec" || len(parts) < 3 {
+		return parts
+	}
+
+	result := []string{"spec", "components", parts[0], parts[1], "vars"}
+	return append(result, parts[2:]...)
+}
+
+// lookupStackPath follows a path through maps and lists, addressing list items by index
+func lookupStackPath(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,147 @@
+package stackfinder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// writeStackFiles creates a catalog directory and the given stack files in a temporary directory
+func writeStackFiles(t *testing.T, stacks map[string]string) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "catalog"), 0755); err != nil {
+		t.Fatalf("Failed to create catalog dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "stacks"), 0755); err != nil {
+		t.Fatalf("Failed to create stacks dir: %v", err)
+	}
+	for name, content := range stacks {
+		if err := os.WriteFile(filepath.Join(tmpDir, "stacks", name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return tmpDir
+}
+
+// decodeWithReferences decodes a stack file with the !stack tag registered
+func decodeWithReferences(t *testing.T, tmpDir, file string) (map[string]interface{}, error) {
+	t.Helper()
+
+	opts := yamlparser.DefaultOptions()
+	catalogDir := filepath.Join(tmpDir, "catalog")
+	NewStackResolver(filepath.Join(tmpDir, "stacks", "*.yaml"), catalogDir, opts).Register(opts.Tags)
+
+	engine, err := yamlparser.NewStackEngine(catalogDir, opts)
+	if err != nil {
+		t.Fatalf("NewStackEngine failed: %v", err)
+	}
+	return engine.DecodeFile(filepath.Join(tmpDir, "stacks", file))
+}
+
+const prodStack = `kind: Stack
+metadata:
+  name: plat-prod-primary
+  labels:
+    region: us-east-1
+spec:
+  components:
+    terraform:
+      vpc:
+        vars:
+          ipv4_primary_cidr_block: 10.2.0.0/16
+          availability_zones: [us-east-1a, us-east-1b]
+`
+
+func TestStackReferences(t *testing.T) {
+	tmpDir := writeStackFiles(t, map[string]string{
+		"prod.yaml": prodStack,
+		"network.yaml": `kind: Stack
+metadata:
+  name: network
+spec:
+  components:
+    terraform:
+      peering:
+        vars:
+          peer_cidr: !stack plat-prod-primary terraform.vpc.ipv4_primary_cidr_block
+          peer_region: !stack plat-prod-primary metadata.labels.region
+          peer_zone: !stack plat-prod-primary terraform.vpc.availability_zones.1
+`,
+	})
+
+	document, err := decodeWithReferences(t, tmpDir, "network.yaml")
+	if err != nil {
+		t.Fatalf("DecodeFile failed: %v", err)
+	}
+
+	vars, _ := lookupStackPath(document, []string{"spec", "components", "terraform", "peering", "vars"})
+	expected := map[string]interface{}{
+		"peer_cidr":   "10.2.0.0/16",
+		"peer_region": "us-east-1",
+		"peer_zone":   "us-east-1b",
+	}
+	for key, value := range expected {
+		if vars.(map[string]interface{})[key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, vars.(map[string]interface{})[key])
+		}
+	}
+}
+
+func TestStackReferenceErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		stacks   map[string]string
+		expected string
+	}{
+		{
+			name: "Unknown stack",
+			stacks: map[string]string{
+				"a.yaml": "kind: Stack\nmetadata:\n  name: a\nvalue: !stack missing terraform.vpc.name\n",
+			},
+			expected: `a.yaml:4:8: !stack: stack "missing" not found`,
+		},
+		{
+			name: "Missing path",
+			stacks: map[string]string{
+				"a.yaml":    "kind: Stack\nmetadata:\n  name: a\nvalue: !stack plat-prod-primary terraform.vpc.missing\n",
+				"prod.yaml": prodStack,
+			},
+			expected: `path "terraform.vpc.missing" not found in stack "plat-prod-primary"`,
+		},
+		{
+			name: "Malformed reference",
+			stacks: map[string]string{
+				"a.yaml": "kind: Stack\nmetadata:\n  name: a\nvalue: !stack plat-prod-primary\n",
+			},
+			expected: `expected "<stack> <path>"`,
+		},
+		{
+			name: "Cycle",
+			stacks: map[string]string{
+				"a.yaml": "kind: Stack\nmetadata:\n  name: a\nvalue: !stack b value\n",
+				"b.yaml": "kind: Stack\nmetadata:\n  name: b\nvalue: !stack c value\n",
+				"c.yaml": "kind: Stack\nmetadata:\n  name: c\nvalue: !stack a value\n",
+			},
+			expected: "stack reference cycle: a -> b -> c -> a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := writeStackFiles(t, tc.stacks)
+
+			_, err := decodeWithReferences(t, tmpDir, "a.yaml")
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}
@@ -67,19 +67,30 @@ func RenderTable(headers []string, rows [][]string, style TableStyle) string {
 		style.TextColor = lipgloss.Color("245") // Light gray
 	}
 
-	// Calculate second column width
-	secondColWidth := style.TotalWidth - style.FirstColWidth - 3 // Account for borders and padding
-
-	// Define table columns
+	// Split the remaining width across the other columns, giving any
+	// leftover characters to the last column
+	otherCols := len(headers) - 1
+	remainingWidth := style.TotalWidth - style.FirstColWidth - 3 // Account for borders and padding
+	if otherCols > 1 {
+		remainingWidth -= 2 * (otherCols - 1) // Each extra column adds its own cell padding
+	}
 	columns := []table.Column{
 		{Title: headers[0], Width: style.FirstColWidth},
-		{Title: headers[1], Width: secondColWidth},
+	}
+	for i := 1; i < len(headers); i++ {
+		width := remainingWidth / otherCols
+		if i == len(headers)-1 {
+			width = remainingWidth - width*(otherCols-1)
+		}
+		columns = append(columns, table.Column{Title: headers[i], Width: width})
 	}
 
-	// Prepare table rows
+	// Prepare table rows, padding short rows so every row has a cell per column
 	tableRows := []table.Row{}
 	for _, row := range rows {
-		tableRows = append(tableRows, table.Row{row[0], row[1]})
+		tableRow := make(table.Row, len(headers))
+		copy(tableRow, row)
+		tableRows = append(tableRows, tableRow)
 	}
 
 	// Create and configure the table
@@ -123,6 +123,21 @@ func TestRenderTable_CustomStyle(t *testing.T) {
 	compareWithSnapshot(t, "custom_style_table", result)
 }
 
+func TestRenderTable_MultipleColumns(t *testing.T) {
+	headers := []string{"VARIABLE", "VALUE", "SOURCE", "ANCHOR"}
+	rows := [][]string{
+		{"enabled", "false", "vpc/overrides.yaml:2", "vpc-overrides"},
+		{"name", "dead-vpc", "vpc/overrides.yaml:3", "vpc-overrides"},
+		{"short_row", "value"},
+	}
+
+	style := DefaultTableStyle()
+	style.Title = "MULTIPLE COLUMNS TABLE"
+
+	result := RenderTable(headers, rows, style)
+	compareWithSnapshot(t, "multiple_columns_table", result)
+}
+
 func TestFormatKeyValueData(t *testing.T) {
 	// Create test data with various types
 	data := map[string]interface{}{
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"fmt"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+)
+
+// Expression is a compiled stack filter that is evaluated against each stack
+type Expression interface {
+	Matches(candidate *Candidate) bool
+	// NeedsDocument returns true if the expression addresses the merged document
+	NeedsDocument() bool
+}
+
+// And matches stacks that match all of its expressions
+type And []Expression
+
+// Matches returns true if the stack matches every expression. Expressions on
+// names and labels are evaluated first, so the merged document is only loaded
+// if they all match.
+func (a And) Matches(candidate *Candidate) bool {
+	for _, pass := range []bool{false, true} {
+		for _, expression := range a {
+			if expression.NeedsDocument() == pass && !expression.Matches(candidate) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NeedsDocument returns true if any expression addresses the merged document
+func (a And) NeedsDocument() bool {
+	return anyNeedsDocument(a)
+}
+
+// Or matches stacks that match any of its expressions
+type Or []Expression
+
+// Matches returns true if the stack matches at least one expression.
+// Expressions on names and labels are evaluated first, so the merged document
+// is only loaded if none of them match.
+func (o Or) Matches(candidate *Candidate) bool {
+	for _, pass := range []bool{false, true} {
+		for _, expression := range o {
+			if expression.NeedsDocument() == pass && expression.Matches(candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NeedsDocument returns true if any expression addresses the merged document
+func (o Or) NeedsDocument() bool {
+	return anyNeedsDocument(o)
+}
+
+// Not matches stacks that do not match its expression
+type Not struct {
+	Expression Expression
+}
+
+// Matches returns true if the stack does not match the expression
+func (n Not) Matches(candidate *Candidate) bool {
+	return !n.Expression.Matches(candidate)
+}
+
+// NeedsDocument returns true if the expression addresses the merged document
+func (n Not) NeedsDocument() bool {
+	return n.Expression.NeedsDocument()
+}
+
+// anyNeedsDocument returns true if any of the expressions addresses the merged document
+func anyNeedsDocument(expressions []Expression) bool {
+	for _, expression := range expressions {
+		if expression.NeedsDocument() {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseExpression parses a boolean expression over requirements, such as
+// (environment=prod || team=platform) && name~=^plat-. Requirements have the
+// syntax of ParseSelector and are combined with && and ||, negated with ! and
+// grouped with parentheses. ! binds tighter than &&, which binds tighter than ||.
+func ParseExpression(expression string) (Expression, error) {
+	p := &selectorParser{lexer: &selectorLexer{input: expression}}
+	if p.lexer.atEOF() {
+		return nil, p.lexer.errorf(0, "empty filter")
+	}
+
+	result, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := p.lexer.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != tokenEOF {
+		return nil, p.lexer.errorf(tok.pos, "expected \"&&\", \"||\" or end of filter, found %s", tok.describe())
+	}
+	return result, nil
+}
+
+// CompileFilters compiles the selectors of --filter flags and the expression
+// of a --where flag into one expression that a stack must match both of. An
+// empty where is ignored.
+func CompileFilters(filters []string, where string) (Expression, error) {
+	selector, err := ParseSelectors(filters)
+	if err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return selector, nil
+	}
+
+	expression, err := ParseExpression(where)
+	if err != nil {
+		return nil, err
+	}
+	return And{selector, expression}, nil
+}
+
+// SelectStacks returns the stacks that match an expression. The merged
+// document of a stack is loaded with loader only if the expression needs it to
+// decide, and an error loading it is returned.
+func SelectStacks(stacks []stackfinder.StackMetadata, expression Expression, loader DocumentLoader) ([]stackfinder.StackMetadata, error) {
+	var selected []stackfinder.StackMetadata
+	for _, stack := range stacks {
+		candidate := NewCandidate(stack, loader)
+		matches := expression.Matches(candidate)
+		if err := candidate.Err(); err != nil {
+			return nil, fmt.Errorf("filtering stack '%s': %w", stack.Name, err)
+		}
+		if matches {
+			selected = append(selected, stack)
+		}
+	}
+	return selected, nil
+}
+
+// or parses operands separated by ||
+func (p *selectorParser) or() (Expression, error) {
+	return p.binary(tokenOr, p.and, func(operands []Expression) Expression { return Or(operands) })
+}
+
+// and parses operands separated by &&
+func (p *selectorParser) and() (Expression, error) {
+	return p.binary(tokenAnd, p.unary, func(operands []Expression) Expression { return And(operands) })
+}
+
+// binary parses one or more operands separated by the operator and combines
+// them, or returns a single operand as is
+func (p *selectorParser) binary(operator tokenKind, operand func() (Expression, error), combine func([]Expression) Expression) (Expression, error) {
+	var operands []Expression
+	for {
+		expression, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, expression)
+
+		tok, err := p.lexer.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != operator {
+			break
+		}
+		p.lexer.next()
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return combine(operands), nil
+}
+
+// unary parses a negation, a parenthesized expression or a requirement
+func (p *selectorParser) unary() (Expression, error) {
+	tok, err := p.lexer.peek()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok.kind == tokenOperator && tok.text == "!":
+		p.lexer.next()
+		expression, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expression: expression}, nil
+	case tok.kind == tokenLeftParen:
+		p.lexer.next()
+		expression, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		tok, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != tokenRightParen {
+			return nil, p.lexer.errorf(tok.pos, "expected \")\", found %s", tok.describe())
+		}
+		return expression, nil
+	default:
+		return p.requirement()
+	}
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExpression(t *testing.T) {
+	prod := Requirement{Key: "environment", Operator: Equals, Values: []string{"prod"}}
+	platform := Requirement{Key: "team", Operator: Equals, Values: []string{"platform"}}
+	data := Requirement{Key: "team", Operator: Equals, Values: []string{"data"}}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   Expression
+	}{
+		{"Requirement", "environment=prod", prod},
+		{"And binds tighter than or", "environment=prod && team=platform || team=data", Or{And{prod, platform}, data}},
+		{"Grouping", "environment=prod && (team=platform || team=data)", And{prod, Or{platform, data}}},
+		{"Negated group", "!(environment=prod)", Not{Expression: prod}},
+		{"Negated label", "!deprecated", Not{Expression: Requirement{Key: "deprecated", Operator: Exists}}},
+		{"Without whitespace", "environment=prod&&team=platform", And{prod, platform}},
+		{"Set", "team in (platform,data) || environment=prod", Or{Requirement{Key: "team", Operator: In, Values: []string{"platform", "data"}}, prod}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := ParseExpression(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, expression)
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		expected   string
+	}{
+		{"Empty", "", `invalid filter "": empty filter at column 1`},
+		{"Unclosed group", "(environment=prod || team=data", `invalid filter "(environment=prod || team=data": expected ")", found end of filter at column 31`},
+		{"Missing operand", "environment=prod &&", `invalid filter "environment=prod &&": expected a label key, found end of filter at column 20`},
+		{"Comma", "environment=prod, team=data", `invalid filter "environment=prod, team=data": expected "&&", "||" or end of filter, found "," at column 17`},
+		{"Extra parenthesis", "environment=prod)", `invalid filter "environment=prod)": expected "&&", "||" or end of filter, found ")" at column 17`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpression(tt.expression)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestExpressionMatches(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "plat-dev", Labels: map[string]string{"environment": "dev", "team": "platform"}},
+		{Name: "plat-prod", Labels: map[string]string{"environment": "prod", "team": "platform"}},
+		{Name: "data-prod", Labels: map[string]string{"environment": "prod", "team": "data"}},
+		{Name: "data-dev", Labels: map[string]string{"environment": "dev", "team": "data", "deprecated": "true"}},
+	}
+
+	tests := []struct {
+		expression string
+		expected   []string
+	}{
+		{"(environment=prod || team=platform) && name~=^plat-", []string{"plat-dev", "plat-prod"}},
+		{"environment=prod || team=platform", []string{"plat-dev", "plat-prod", "data-prod"}},
+		{"!(environment=prod || team=platform)", []string{"data-dev"}},
+		{"name~=^(plat|data)-dev$ && !deprecated", []string{"plat-dev"}},
+		{"name~=^data-|^plat-prod$ || environment=dev", []string{"plat-dev", "plat-prod", "data-prod", "data-dev"}},
+		{"/-prod$/ && team notin (data)", []string{"plat-prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := ParseExpression(tt.expression)
+			assert.NoError(t, err)
+			selected, err := SelectStacks(stacks, expression, nil)
+			assert.NoError(t, err)
+			var names []string
+			for _, stack := range selected {
+				names = append(names, stack.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestCompileFilters(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "plat-dev", Labels: map[string]string{"environment": "dev", "team": "platform"}},
+		{Name: "data-dev", Labels: map[string]string{"environment": "dev", "team": "data"}},
+		{Name: "plat-prod", Labels: map[string]string{"environment": "prod", "team": "platform"}},
+	}
+
+	expression, err := CompileFilters([]string{"environment=dev"}, "team=data || name=plat-*")
+	assert.NoError(t, err)
+	selected, err := SelectStacks(stacks, expression, nil)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 2)
+
+	_, err = CompileFilters(nil, "team=data ||")
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+)
+
+const (
+	// NameKey is the key that addresses the stack name instead of a label.
+	// Name values are wildcard patterns supporting * and ?.
+	NameKey = "name"
+	// ComponentKey is the key that addresses the components of the merged
+	// stack. Values are wildcard patterns of <type>/<name>, or of the name of
+	// a component of any type.
+	ComponentKey = "component"
+	// VarsPrefix starts keys that address a component variable of the merged
+	// stack, as vars.<type>.<component>.<variable>
+	VarsPrefix = "vars."
+	// SpecPrefix starts keys that address any value of the merged stack's spec
+	SpecPrefix = "spec."
+	// LabelsPrefix starts keys that address a label whose key is one of the
+	// keys above, such as labels.component
+	LabelsPrefix = "labels."
+)
+
+// DocumentLoader returns the merged document of a stack
+type DocumentLoader func(stack stackfinder.StackMetadata) (map[string]interface{}, error)
+
+// Candidate is a stack an expression is evaluated against. Its merged
+// document is only loaded the first time a requirement addresses it, so
+// expressions over names and labels never parse the stack.
+type Candidate struct {
+	Stack    stackfinder.StackMetadata
+	loader   DocumentLoader
+	document map[string]interface{}
+	err      error
+	loaded   bool
+}
+
+// NewCandidate returns a candidate that loads its merged document with loader,
+// which may be nil if no requirement addresses the document
+func NewCandidate(stack stackfinder.StackMetadata, loader DocumentLoader) *Candidate {
+	return &Candidate{Stack: stack, loader: loader}
+}
+
+// Document returns the merged document of the stack, loading it on first use
+func (c *Candidate) Document() (map[string]interface{}, error) {
+	if !c.loaded {
+		c.loaded = true
+		if c.loader == nil {
+			c.err = fmt.Errorf("the merged document of stack '%s' is not available", c.Stack.Name)
+		} else {
+			c.document, c.err = c.loader(c.Stack)
+		}
+	}
+	return c.document, c.err
+}
+
+// Err returns the error loading the merged document, if it was loaded
+func (c *Candidate) Err() error {
+	return c.err
+}
+
+// NeedsDocument returns true if the requirement addresses the merged document
+func (r Requirement) NeedsDocument() bool {
+	return r.Key == ComponentKey || strings.HasPrefix(r.Key, VarsPrefix) || strings.HasPrefix(r.Key, SpecPrefix)
+}
+
+// field returns the values of the field the requirement addresses, which are
+// none if it is missing, and how a value is compared with a requirement value
+func (r Requirement) field(candidate *Candidate) ([]string, func(value, pattern string) bool) {
+	equal := func(value, pattern string) bool { return value == pattern }
+
+	switch {
+	case r.Key == NameKey:
+		return []string{candidate.Stack.Name}, MatchWildcard
+	case strings.HasPrefix(r.Key, LabelsPrefix):
+		return labelValues(candidate.Stack, strings.TrimPrefix(r.Key, LabelsPrefix)), equal
+	case !r.NeedsDocument():
+		return labelValues(candidate.Stack, r.Key), equal
+	}
+
+	document, err := candidate.Document()
+	if err != nil {
+		return nil, equal
+	}
+
+	if r.Key == ComponentKey {
+		return componentIDs(document), matchComponent
+	}
+
+	path := strings.Split(r.Key, ".")
+	if strings.HasPrefix(r.Key, VarsPrefix) {
+		// vars.<type>.<component>.<variable> is spec.components.<type>.<component>.vars.<variable>
+		path = append([]string{"spec", "components", path[1], path[2], "vars"}, path[3:]...)
+	}
+	value, ok := lookupPath(document, path)
+	if !ok {
+		return nil, equal
+	}
+	return fieldValues(value), equal
+}
+
+// validateField returns an error if a key that addresses the merged document
+// is malformed
+func validateField(key string) error {
+	switch {
+	case strings.HasPrefix(key, VarsPrefix):
+		segments := strings.Split(key, ".")
+		if len(segments) < 3 || contains(segments, "") {
+			return fmt.Errorf("key %q must have the form vars.<type>.<component>[.<variable>]", key)
+		}
+	case strings.HasPrefix(key, SpecPrefix):
+		if contains(strings.Split(key, "."), "") {
+			return fmt.Errorf("key %q has an empty path segment", key)
+		}
+	case strings.HasPrefix(key, LabelsPrefix) && key == LabelsPrefix:
+		return fmt.Errorf("key %q is missing a label key", key)
+	}
+	return nil
+}
+
+// labelValues returns the value of a label, or none if the stack does not have it
+func labelValues(stack stackfinder.StackMetadata, key string) []string {
+	if value, ok := stack.Labels[key]; ok {
+		return []string{value}
+	}
+	return nil
+}
+
+// componentIDs returns the <type>/<name> of every component of a merged stack
+func componentIDs(document map[string]interface{}) []string {
+	spec, _ := document["spec"].(map[string]interface{})
+	componentTypes, _ := spec["components"].(map[string]interface{})
+
+	var ids []string
+	for typeName, typeValue := range componentTypes {
+		components, _ := typeValue.(map[string]interface{})
+		for name := range components {
+			ids = append(ids, typeName+"/"+name)
+		}
+	}
+	return ids
+}
+
+// matchComponent matches a <type>/<name> component against a pattern of
+// <type>/<name>, or of the name alone
+func matchComponent(id, pattern string) bool {
+	if strings.Contains(pattern, "/") {
+		return MatchWildcard(id, pattern)
+	}
+	_, name, _ := strings.Cut(id, "/")
+	return MatchWildcard(name, pattern)
+}
+
+// lookupPath returns the value at a path of mapping keys and list indexes
+func lookupPath(value interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// fieldValues returns the values a document value is compared as. A list has
+// the values of its elements, so that it matches if any element does.
+// Mappings are compared as JSON and null as an empty string.
+func fieldValues(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, element := range list {
+			values = append(values, formatFieldValue(element))
+		}
+		return values
+	}
+	return []string{formatFieldValue(value)}
+}
+
+// formatFieldValue returns a scalar in its YAML form, or other values as JSON
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// contains returns true if values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
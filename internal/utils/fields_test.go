@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/stretchr/testify/assert"
+)
+
+// stackDocument returns a merged stack document with the given terraform vpc
+// vars and helm components
+func stackDocument(vpcVars map[string]interface{}, helm ...string) map[string]interface{} {
+	components := map[string]interface{}{
+		"terraform": map[string]interface{}{"vpc": map[string]interface{}{"vars": vpcVars}},
+	}
+	if len(helm) > 0 {
+		charts := map[string]interface{}{}
+		for _, name := range helm {
+			charts[name] = map[string]interface{}{"vars": map[string]interface{}{}}
+		}
+		components["helm"] = charts
+	}
+	return map[string]interface{}{"spec": map[string]interface{}{"components": components}}
+}
+
+func TestDocumentRequirements(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "dev", Labels: map[string]string{"environment": "dev", "component": "network"}},
+		{Name: "prod", Labels: map[string]string{"environment": "prod"}},
+		{Name: "empty", Labels: map[string]string{"environment": "dev"}},
+	}
+	documents := map[string]map[string]interface{}{
+		"dev": stackDocument(map[string]interface{}{
+			"nat_gateway_enabled": false,
+			"availability_zones":  []interface{}{"us-east-1a", "us-east-1b"},
+		}, "nginx"),
+		"prod": stackDocument(map[string]interface{}{
+			"nat_gateway_enabled": true,
+			"tags":                map[string]interface{}{"team": "platform"},
+			"max_azs":             3,
+		}),
+		"empty": {"spec": map[string]interface{}{}},
+	}
+	loader := func(stack stackfinder.StackMetadata) (map[string]interface{}, error) {
+		return documents[stack.Name], nil
+	}
+
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"component=terraform/vpc", []string{"dev", "prod"}},
+		{"component=vpc", []string{"dev", "prod"}},
+		{"component=helm/*", []string{"dev"}},
+		{"component!=helm/nginx", []string{"prod", "empty"}},
+		{"component in (helm/nginx, terraform/eks)", []string{"dev"}},
+		{"!component", []string{"empty"}},
+		{"component~=^terraform/", []string{"dev", "prod"}},
+		{"vars.terraform.vpc.nat_gateway_enabled=true", []string{"prod"}},
+		{"vars.terraform.vpc.nat_gateway_enabled!=true", []string{"dev", "empty"}},
+		{"vars.terraform.vpc.max_azs=3", []string{"prod"}},
+		{"vars.terraform.vpc.availability_zones=us-east-1b", []string{"dev"}},
+		{"vars.terraform.vpc.availability_zones.0=us-east-1a", []string{"dev"}},
+		{"vars.terraform.vpc.tags.team=platform", []string{"prod"}},
+		{"vars.terraform.vpc.tags", []string{"prod"}},
+		{"spec.components.helm", []string{"dev"}},
+		{"labels.component=network", []string{"dev"}},
+		{"environment=dev, component=vpc", []string{"dev"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			result, err := FilterStacks(stacks, []string{tt.filter}, loader)
+			assert.NoError(t, err)
+			var names []string
+			for _, stack := range result {
+				names = append(names, stack.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestDocumentLoadedLazily(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "dev", Labels: map[string]string{"environment": "dev"}},
+		{Name: "prod", Labels: map[string]string{"environment": "prod"}},
+	}
+	var loaded []string
+	loader := func(stack stackfinder.StackMetadata) (map[string]interface{}, error) {
+		loaded = append(loaded, stack.Name)
+		return stackDocument(map[string]interface{}{}), nil
+	}
+
+	tests := []struct {
+		expression string
+		loaded     []string
+	}{
+		// Label requirements never load documents
+		{"environment=prod || name=dev", nil},
+		// Requirements on labels are checked before requirements on documents
+		{"component=vpc && environment=prod", []string{"prod"}},
+		{"component=vpc || environment=prod", []string{"dev"}},
+		// A document is loaded once however many requirements address it
+		{"component=vpc && vars.terraform.vpc.cidr", []string{"dev", "prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			loaded = nil
+			expression, err := ParseExpression(tt.expression)
+			assert.NoError(t, err)
+			_, err = SelectStacks(stacks, expression, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.loaded, loaded)
+		})
+	}
+}
+
+func TestDocumentLoadErrors(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{{Name: "dev"}}
+
+	_, err := FilterStacks(stacks, []string{"component=vpc"}, func(stackfinder.StackMetadata) (map[string]interface{}, error) {
+		return nil, errors.New("broken anchor")
+	})
+	assert.EqualError(t, err, "filtering stack 'dev': broken anchor")
+
+	_, err = FilterStacks(stacks, []string{"component=vpc"}, nil)
+	assert.EqualError(t, err, "filtering stack 'dev': the merged document of stack 'dev' is not available")
+}
+
+func TestDocumentKeyErrors(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{"vars.terraform=x", `invalid filter "vars.terraform=x": key "vars.terraform" must have the form vars.<type>.<component>[.<variable>] at column 1`},
+		{"!vars.terraform..cidr", `invalid filter "!vars.terraform..cidr": key "vars.terraform..cidr" must have the form vars.<type>.<component>[.<variable>] at column 2`},
+		{"spec..components", `invalid filter "spec..components": key "spec..components" has an empty path segment at column 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := ParseSelector(tt.filter)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
@@ -149,7 +149,8 @@ func TestFilterStacks(t *testing.T) {
 	// Run test cases
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := FilterStacks(stacks, tt.filters)
+			result, err := FilterStacks(stacks, tt.filters, nil)
+			assert.NoError(t, err)
 			var resultNames []string
 			for _, stack := range result {
 				resultNames = append(resultNames, stack.Name)
@@ -0,0 +1,564 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Operator is the comparison a requirement makes against a label or the stack name
+type Operator string
+
+const (
+	// Equals matches a value equal to the requirement's value
+	Equals Operator = "="
+	// NotEquals matches a missing value or one different from the requirement's value
+	NotEquals Operator = "!="
+	// In matches a value equal to one of the requirement's values
+	In Operator = "in"
+	// NotIn matches a missing value or one different from all of the requirement's values
+	NotIn Operator = "notin"
+	// Exists matches any value
+	Exists Operator = "exists"
+	// DoesNotExist matches a missing value
+	DoesNotExist Operator = "!"
+	// Matches matches a value matching the requirement's regular expression
+	Matches Operator = "~="
+	// NotMatches matches a missing value or one not matching the requirement's regular expression
+	NotMatches Operator = "!~="
+)
+
+// Requirement is a single condition of a selector, such as environment=prod
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+	regexp   *regexp.Regexp
+}
+
+// Selector is a list of requirements a stack must all meet
+type Selector []Requirement
+
+// SelectorError is a filter that could not be parsed
+type SelectorError struct {
+	Input   string
+	Pos     int
+	Message string
+}
+
+// Error implements the error interface
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("invalid filter %q: %s at column %d", e.Input, e.Message, e.Pos+1)
+}
+
+// ParseSelector parses a filter into a selector. A filter is a comma-separated
+// list of requirements, all of which must be met:
+// - key=value, key==value: label key equals value
+// - key!=value: label key is missing or does not equal value
+// - key in (a,b): label key equals one of the values
+// - key notin (a,b): label key is missing or equals none of the values
+// - key: label key exists
+// - !key: label key does not exist
+// - key~=regex, key!~=regex: label key matches, or is missing or does not match, the regex
+// - name=pattern, name!=pattern, name in (...), name notin (...): stack name matches the wildcard patterns
+// - pattern: stack name matches a wildcard pattern containing * or ?
+// - /regex/: stack name matches the regex
+// Keys can also address the merged document of the stack, see ComponentKey,
+// VarsPrefix and SpecPrefix.
+func ParseSelector(filter string) (Selector, error) {
+	p := &selectorParser{lexer: &selectorLexer{input: filter}}
+	return p.parse()
+}
+
+// ParseSelectors parses several filters into a single selector whose
+// requirements are the requirements of every filter
+func ParseSelectors(filters []string) (Selector, error) {
+	var selector Selector
+	for _, filter := range filters {
+		parsed, err := ParseSelector(filter)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, parsed...)
+	}
+	return selector, nil
+}
+
+// Matches returns true if the stack meets every requirement of the selector.
+// Requirements on names and labels are checked first, so the merged document
+// is only loaded if they all match.
+func (s Selector) Matches(candidate *Candidate) bool {
+	for _, pass := range []bool{false, true} {
+		for _, requirement := range s {
+			if requirement.NeedsDocument() == pass && !requirement.Matches(candidate) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NeedsDocument returns true if any requirement addresses the merged document
+func (s Selector) NeedsDocument() bool {
+	for _, requirement := range s {
+		if requirement.NeedsDocument() {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns true if the stack meets the requirement. Fields with several
+// values, such as component, match if any of their values does.
+func (r Requirement) Matches(candidate *Candidate) bool {
+	values, match := r.field(candidate)
+	matchesAny := func(patterns []string) bool {
+		for _, value := range values {
+			for _, pattern := range patterns {
+				if match(value, pattern) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	matchesRegexp := func() bool {
+		for _, value := range values {
+			if r.regexp.MatchString(value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch r.Operator {
+	case Equals:
+		return matchesAny(r.Values[:1])
+	case NotEquals:
+		return !matchesAny(r.Values[:1])
+	case In:
+		return matchesAny(r.Values)
+	case NotIn:
+		return !matchesAny(r.Values)
+	case Exists:
+		return len(values) > 0
+	case DoesNotExist:
+		return len(values) == 0
+	case Matches:
+		return matchesRegexp()
+	case NotMatches:
+		return !matchesRegexp()
+	}
+	return false
+}
+
+// containsWildcard returns true if s contains a wildcard character
+func containsWildcard(s string) bool {
+	return strings.ContainsAny(s, "*?")
+}
+
+// tokenKind is the kind of a filter token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	// tokenWord is a key, a value or the in and notin keywords
+	tokenWord
+	// tokenOperator is one of =, ==, !=, ~=, !~= and !
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	// tokenAnd and tokenOr are the && and || of expressions
+	tokenAnd
+	tokenOr
+)
+
+// token is a token of a filter and its offset in the filter
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// describe returns the token as it is shown in errors
+func (t token) describe() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// selectorLexer splits a filter into tokens. Regular expressions can contain
+// any character, so they are read by pattern and regexLiteral when the parser
+// expects one.
+type selectorLexer struct {
+	input string
+	pos   int
+}
+
+// isWordChar returns true if r can be part of a word
+func isWordChar(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(",()=!~", r)
+}
+
+// isLogicalOperator returns true if s starts with && or ||
+func isLogicalOperator(s string) bool {
+	return strings.HasPrefix(s, "&&") || strings.HasPrefix(s, "||")
+}
+
+// skipSpace moves past whitespace
+func (l *selectorLexer) skipSpace() {
+	for l.pos < len(l.input) && unicode.IsSpace(rune(l.input[l.pos])) {
+		l.pos++
+	}
+}
+
+// next returns the next token
+func (l *selectorLexer) next() (token, error) {
+	l.skipSpace()
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	rest := l.input[l.pos:]
+	switch {
+	case strings.HasPrefix(rest, "&&"):
+		l.pos += 2
+		return token{kind: tokenAnd, text: "&&", pos: start}, nil
+	case strings.HasPrefix(rest, "||"):
+		l.pos += 2
+		return token{kind: tokenOr, text: "||", pos: start}, nil
+	}
+
+	for _, op := range []string{"!~=", "==", "!=", "~=", "=", "!"} {
+		if strings.HasPrefix(rest, op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, pos: start}, nil
+		}
+	}
+
+	switch rest[0] {
+	case '(':
+		l.pos++
+		return token{kind: tokenLeftParen, text: "(", pos: start}, nil
+	case ')':
+		l.pos++
+		return token{kind: tokenRightParen, text: ")", pos: start}, nil
+	case ',':
+		l.pos++
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case '~':
+		return token{}, l.errorf(start, "unexpected %q", "~")
+	}
+
+	end := 0
+	for end < len(rest) && !isLogicalOperator(rest[end:]) {
+		r, size := utf8.DecodeRuneInString(rest[end:])
+		if !isWordChar(r) {
+			break
+		}
+		end += size
+	}
+	l.pos += end
+	return token{kind: tokenWord, text: rest[:end], pos: start}, nil
+}
+
+// peek returns the next token without consuming it
+func (l *selectorLexer) peek() (token, error) {
+	pos := l.pos
+	tok, err := l.next()
+	l.pos = pos
+	return tok, err
+}
+
+// atEOF returns true if only whitespace is left
+func (l *selectorLexer) atEOF() bool {
+	l.skipSpace()
+	return l.pos >= len(l.input)
+}
+
+// atRegexLiteral returns true if the next token is a regex enclosed in slashes
+func (l *selectorLexer) atRegexLiteral() bool {
+	l.skipSpace()
+	return strings.HasPrefix(l.input[l.pos:], "/")
+}
+
+// regexLiteral reads a regex enclosed in slashes and returns it without the
+// slashes. A slash inside the regex is escaped with a backslash.
+func (l *selectorLexer) regexLiteral() (token, error) {
+	l.skipSpace()
+	start := l.pos
+	for i := start + 1; i < len(l.input); i++ {
+		switch l.input[i] {
+		case '\\':
+			i++
+		case '/':
+			l.pos = i + 1
+			return token{kind: tokenWord, text: l.input[start+1 : i], pos: start}, nil
+		}
+	}
+	return token{}, l.errorf(start, "unterminated regex, expected a closing \"/\"")
+}
+
+// pattern reads a regular expression up to the next comma, closing
+// parenthesis, && or || that is not inside brackets, braces or parentheses of
+// the expression itself
+func (l *selectorLexer) pattern() (token, error) {
+	l.skipSpace()
+	start := l.pos
+	depth := 0
+	end := start
+scan:
+	for ; end < len(l.input); end++ {
+		switch l.input[end] {
+		case '\\':
+			end++
+		case '(', '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ')':
+			if depth == 0 {
+				break scan
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				break scan
+			}
+		case '&', '|':
+			if depth == 0 && isLogicalOperator(l.input[end:]) {
+				break scan
+			}
+		}
+	}
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	l.pos = end
+	return token{kind: tokenWord, text: strings.TrimSpace(l.input[start:end]), pos: start}, nil
+}
+
+// errorf returns a SelectorError at pos
+func (l *selectorLexer) errorf(pos int, format string, args ...interface{}) error {
+	return &SelectorError{Input: l.input, Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// selectorParser builds a selector from the tokens of a filter
+type selectorParser struct {
+	lexer *selectorLexer
+}
+
+// parse parses a comma-separated list of requirements
+func (p *selectorParser) parse() (Selector, error) {
+	if strings.TrimSpace(p.lexer.input) == "" {
+		return nil, p.lexer.errorf(0, "empty filter")
+	}
+
+	var selector Selector
+	for {
+		requirement, err := p.requirement()
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, requirement)
+
+		tok, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokenEOF:
+			return selector, nil
+		case tokenComma:
+			continue
+		default:
+			return nil, p.lexer.errorf(tok.pos, "expected \",\" or end of filter, found %s", tok.describe())
+		}
+	}
+}
+
+// requirement parses a single requirement
+func (p *selectorParser) requirement() (Requirement, error) {
+	if p.lexer.atRegexLiteral() {
+		tok, err := p.lexer.regexLiteral()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return p.regexRequirement(NameKey, Matches, tok)
+	}
+
+	tok, err := p.lexer.next()
+	if err != nil {
+		return Requirement{}, err
+	}
+
+	// !key
+	if tok.kind == tokenOperator && tok.text == "!" {
+		key, err := p.key()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key.text, Operator: DoesNotExist}, nil
+	}
+
+	if tok.kind != tokenWord {
+		return Requirement{}, p.lexer.errorf(tok.pos, "expected a label key, found %s", tok.describe())
+	}
+	key := tok
+
+	op, err := p.lexer.peek()
+	if err != nil {
+		return Requirement{}, err
+	}
+
+	switch {
+	case endsRequirement(op):
+		// A bare pattern matches the stack name, a bare key checks that the label exists
+		if containsWildcard(key.text) {
+			return Requirement{Key: NameKey, Operator: Equals, Values: []string{key.text}}, nil
+		}
+		if err := p.validateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key.text, Operator: Exists}, nil
+	case op.kind == tokenWord && (op.text == string(In) || op.text == string(NotIn)):
+		if err := p.validateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		p.lexer.next()
+		values, err := p.set()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key.text, Operator: Operator(op.text), Values: values}, nil
+	case op.kind == tokenOperator && (op.text == "=" || op.text == "==" || op.text == "!="):
+		if err := p.validateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		p.lexer.next()
+		value, err := p.value()
+		if err != nil {
+			return Requirement{}, err
+		}
+		operator := Equals
+		if op.text == "!=" {
+			operator = NotEquals
+		}
+		return Requirement{Key: key.text, Operator: operator, Values: []string{value}}, nil
+	case op.kind == tokenOperator && (op.text == "~=" || op.text == "!~="):
+		if err := p.validateKey(key); err != nil {
+			return Requirement{}, err
+		}
+		p.lexer.next()
+		pattern, err := p.lexer.pattern()
+		if err != nil {
+			return Requirement{}, err
+		}
+		return p.regexRequirement(key.text, Operator(op.text), pattern)
+	default:
+		return Requirement{}, p.lexer.errorf(op.pos, "expected an operator after %q, found %s", key.text, op.describe())
+	}
+}
+
+// endsRequirement returns true if tok can follow a complete requirement
+func endsRequirement(tok token) bool {
+	switch tok.kind {
+	case tokenEOF, tokenComma, tokenRightParen, tokenAnd, tokenOr:
+		return true
+	}
+	return false
+}
+
+// regexRequirement returns a requirement matching a regular expression
+func (p *selectorParser) regexRequirement(key string, operator Operator, pattern token) (Requirement, error) {
+	if pattern.text == "" {
+		return Requirement{}, p.lexer.errorf(pattern.pos, "expected a regular expression")
+	}
+	re, err := regexp.Compile(pattern.text)
+	if err != nil {
+		return Requirement{}, p.lexer.errorf(pattern.pos, "invalid regular expression %q: %v", pattern.text, err)
+	}
+	return Requirement{Key: key, Operator: operator, Values: []string{pattern.text}, regexp: re}, nil
+}
+
+// key parses a label key
+func (p *selectorParser) key() (token, error) {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return token{}, err
+	}
+	if tok.kind != tokenWord {
+		return token{}, p.lexer.errorf(tok.pos, "expected a label key, found %s", tok.describe())
+	}
+	return tok, p.validateKey(tok)
+}
+
+// validateKey returns an error if a key contains wildcards, which only
+// values matched against the stack name can, or is a malformed document path
+func (p *selectorParser) validateKey(key token) error {
+	if containsWildcard(key.text) {
+		return p.lexer.errorf(key.pos, "label key %q cannot contain wildcards", key.text)
+	}
+	if err := validateField(key.text); err != nil {
+		return p.lexer.errorf(key.pos, "%v", err)
+	}
+	return nil
+}
+
+// value parses the value of = and !=, which is empty if it is omitted
+func (p *selectorParser) value() (string, error) {
+	tok, err := p.lexer.peek()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case endsRequirement(tok):
+		return "", nil
+	case tok.kind == tokenWord:
+		p.lexer.next()
+		return tok.text, nil
+	default:
+		return "", p.lexer.errorf(tok.pos, "expected a value, found %s", tok.describe())
+	}
+}
+
+// set parses a parenthesized, comma-separated list of values
+func (p *selectorParser) set() ([]string, error) {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != tokenLeftParen {
+		return nil, p.lexer.errorf(tok.pos, "expected \"(\", found %s", tok.describe())
+	}
+
+	var values []string
+	for {
+		tok, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind != tokenWord {
+			return nil, p.lexer.errorf(tok.pos, "expected a value, found %s", tok.describe())
+		}
+		values = append(values, tok.text)
+
+		tok, err = p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokenRightParen:
+			return values, nil
+		case tokenComma:
+			continue
+		default:
+			return nil, p.lexer.errorf(tok.pos, "expected \",\" or \")\", found %s", tok.describe())
+		}
+	}
+}
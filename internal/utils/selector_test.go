@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"testing"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected Selector
+	}{
+		{
+			name:     "Equals",
+			filter:   "environment=prod",
+			expected: Selector{{Key: "environment", Operator: Equals, Values: []string{"prod"}}},
+		},
+		{
+			name:     "Double equals with whitespace",
+			filter:   " environment == prod ",
+			expected: Selector{{Key: "environment", Operator: Equals, Values: []string{"prod"}}},
+		},
+		{
+			name:     "Empty value",
+			filter:   "environment=",
+			expected: Selector{{Key: "environment", Operator: Equals, Values: []string{""}}},
+		},
+		{
+			name:     "In",
+			filter:   "environment in (dev, staging)",
+			expected: Selector{{Key: "environment", Operator: In, Values: []string{"dev", "staging"}}},
+		},
+		{
+			name:     "Not in",
+			filter:   "team notin (data)",
+			expected: Selector{{Key: "team", Operator: NotIn, Values: []string{"data"}}},
+		},
+		{
+			name:   "Existence",
+			filter: "region,!deprecated",
+			expected: Selector{
+				{Key: "region", Operator: Exists},
+				{Key: "deprecated", Operator: DoesNotExist},
+			},
+		},
+		{
+			name:     "Prefixed label key",
+			filter:   "example.com/owner=platform",
+			expected: Selector{{Key: "example.com/owner", Operator: Equals, Values: []string{"platform"}}},
+		},
+		{
+			name:     "Bare wildcard pattern",
+			filter:   "prod-*",
+			expected: Selector{{Key: NameKey, Operator: Equals, Values: []string{"prod-*"}}},
+		},
+		{
+			name:   "Set and equality requirements",
+			filter: "environment in (dev,staging),region=us-east-1,name!=*-test",
+			expected: Selector{
+				{Key: "environment", Operator: In, Values: []string{"dev", "staging"}},
+				{Key: "region", Operator: Equals, Values: []string{"us-east-1"}},
+				{Key: NameKey, Operator: NotEquals, Values: []string{"*-test"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := ParseSelector(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, selector)
+		})
+	}
+}
+
+func TestParseSelectorRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		key      string
+		operator Operator
+		pattern  string
+	}{
+		{"Regex with commas and groups", `name~=^(dev|prod)-stack-\d{1,2}$`, NameKey, Matches, `^(dev|prod)-stack-\d{1,2}$`},
+		{"Negated label regex", "region!~=^us-", "region", NotMatches, "^us-"},
+		{"Regex in slashes", `/^prod-[a-z,]+\/x$/`, NameKey, Matches, `^prod-[a-z,]+\/x$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := ParseSelector(tt.filter)
+			assert.NoError(t, err)
+			if assert.Len(t, selector, 1) {
+				assert.Equal(t, tt.key, selector[0].Key)
+				assert.Equal(t, tt.operator, selector[0].Operator)
+				assert.Equal(t, []string{tt.pattern}, selector[0].Values)
+			}
+		})
+	}
+
+	// A regex ends at a comma outside its groups
+	selector, err := ParseSelector("name~=^a{1,2}, env=prod")
+	assert.NoError(t, err)
+	assert.Len(t, selector, 2)
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{"Empty", "  ", `invalid filter "  ": empty filter at column 1`},
+		{"Unclosed set", "environment in (dev", `invalid filter "environment in (dev": expected "," or ")", found end of filter at column 20`},
+		{"Set without parentheses", "environment in dev", `invalid filter "environment in dev": expected "(", found "dev" at column 16`},
+		{"Empty set", "environment in ()", `invalid filter "environment in ()": expected a value, found ")" at column 17`},
+		{"Missing operator", "environment prod", `invalid filter "environment prod": expected an operator after "environment", found "prod" at column 13`},
+		{"Missing key", "=prod", `invalid filter "=prod": expected a label key, found "=" at column 1`},
+		{"Trailing comma", "env=prod,", `invalid filter "env=prod,": expected a label key, found end of filter at column 10`},
+		{"Wildcard key", "env*=prod", `invalid filter "env*=prod": label key "env*" cannot contain wildcards at column 1`},
+		{"Invalid regex", "name~=^(prod", "invalid filter \"name~=^(prod\": invalid regular expression \"^(prod\": error parsing regexp: missing closing ): `^(prod` at column 7"},
+		{"Empty regex", "name~=", `invalid filter "name~=": expected a regular expression at column 7`},
+		{"Unterminated regex", "/^prod", `invalid filter "/^prod": unterminated regex, expected a closing "/" at column 1`},
+		{"Extra tokens", "env=prod)", `invalid filter "env=prod)": expected "," or end of filter, found ")" at column 9`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSelector(tt.filter)
+			if assert.Error(t, err) {
+				assert.IsType(t, &SelectorError{}, err)
+				assert.Equal(t, tt.expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{
+		{Name: "plat-dev", Labels: map[string]string{"environment": "dev", "team": "platform", "region": "us-east-1"}},
+		{Name: "plat-staging", Labels: map[string]string{"environment": "staging", "team": "platform"}},
+		{Name: "data-prod", Labels: map[string]string{"environment": "prod", "team": "data", "region": "us-west-2"}},
+		{Name: "legacy", Labels: map[string]string{"deprecated": "true"}},
+	}
+
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"environment in (dev,staging)", []string{"plat-dev", "plat-staging"}},
+		{"team notin (data)", []string{"plat-dev", "plat-staging", "legacy"}},
+		{"!deprecated", []string{"plat-dev", "plat-staging", "data-prod"}},
+		{"region", []string{"plat-dev", "data-prod"}},
+		{"region, team=platform", []string{"plat-dev"}},
+		{"region~=^us-west-", []string{"data-prod"}},
+		{"region!~=^us-west-", []string{"plat-dev", "plat-staging", "legacy"}},
+		{"name in (plat-*, legacy)", []string{"plat-dev", "plat-staging", "legacy"}},
+		{"name notin (plat-*)", []string{"data-prod", "legacy"}},
+		{"environment!=prod,!deprecated", []string{"plat-dev", "plat-staging"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			result, err := FilterStacks(stacks, []string{tt.filter}, nil)
+			assert.NoError(t, err)
+			var names []string
+			for _, stack := range result {
+				names = append(names, stack.Name)
+			}
+			assert.ElementsMatch(t, tt.expected, names)
+		})
+	}
+}
+
+func TestFilterStacksInvalidFilter(t *testing.T) {
+	stacks := []stackfinder.StackMetadata{{Name: "plat-dev"}}
+
+	result, err := FilterStacks(stacks, []string{"env=prod", "environment in (dev"}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
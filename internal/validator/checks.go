@@ -0,0 +1,245 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mcalhoun/skunk/internal/apiversion"
+	"github.com/mcalhoun/skunk/internal/schema"
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	"github.com/mcalhoun/skunk/internal/utils"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// CheckFunc reports the problems it finds in a repository
+type CheckFunc func(repo *Repository) yamlparser.Diagnostics
+
+// Check is a named validation run by `skunk validate`
+type Check struct {
+	Name        string
+	Description string
+	Run         CheckFunc
+}
+
+// Registry holds the checks to run, in the order they were registered
+type Registry struct {
+	checks []Check
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// DefaultRegistry returns a registry with the built-in checks
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register("parse", "Every stack parses and every alias, import and tag in it resolves", checkParse)
+	registry.Register("anchors", "Every catalog anchor resolves", checkAnchors)
+	registry.Register("duplicate-anchors", "No anchor name is defined in more than one catalog file", checkDuplicateAnchors)
+	registry.Register("unique-names", "Every stack has a metadata.name that no other stack uses", checkUniqueNames)
+	registry.Register("component-vars", "Every component defines a vars mapping", checkComponentVars)
+	registry.Register("component-schema", "The vars of every component match the schema in its catalog directory", checkComponentSchemas)
+	registry.Register("api-version", "Every stack declares a supported apiVersion and its structure matches that version", checkAPIVersions)
+	return registry
+}
+
+// Register adds a check, replacing any check with the same name
+func (r *Registry) Register(name, description string, fn CheckFunc) {
+	check := Check{Name: name, Description: description, Run: fn}
+	for i, existing := range r.checks {
+		if existing.Name == name {
+			r.checks[i] = check
+			return
+		}
+	}
+	r.checks = append(r.checks, check)
+}
+
+// Checks returns the registered checks in registration order
+func (r *Registry) Checks() []Check {
+	return append([]Check{}, r.checks...)
+}
+
+// checkParse reports the stacks that failed to parse
+func checkParse(repo *Repository) yamlparser.Diagnostics {
+	return repo.ParseDiagnostics
+}
+
+// checkAnchors reports catalog anchors whose value does not resolve, such as an
+// anchor that merges an alias no catalog file defines. Anchors are resolved
+// with the options of the stacks, so the same tags are known.
+func checkAnchors(repo *Repository) yamlparser.Diagnostics {
+	var diags yamlparser.Diagnostics
+	for _, anchor := range repo.Catalog.Anchors(repo.Options) {
+		if anchor.Error == "" {
+			continue
+		}
+		diags = append(diags, &yamlparser.Diagnostic{
+			Severity: yamlparser.SeverityError,
+			File:     anchor.File,
+			Line:     anchor.Line,
+			Column:   anchor.Column,
+			Anchor:   anchor.Name,
+			Message:  fmt.Sprintf("anchor %q does not resolve: %s", anchor.Name, anchor.Error),
+		})
+	}
+	return diags
+}
+
+// checkDuplicateAnchors reports anchor names defined in several catalog files,
+// as warnings if the configured policy allows them
+func checkDuplicateAnchors(repo *Repository) yamlparser.Diagnostics {
+	diags := yamlparser.AsDiagnostics(repo.Catalog.CheckDuplicates(yamlparser.DuplicateAnchorsError), repo.CatalogDir)
+	if repo.DuplicateAnchors == yamlparser.DuplicateAnchorsWarn {
+		for _, diag := range diags {
+			diag.Severity = yamlparser.SeverityWarning
+		}
+	}
+	return diags
+}
+
+// checkUniqueNames reports stacks without a name and every file of a name used
+// by more than one stack
+func checkUniqueNames(repo *Repository) yamlparser.Diagnostics {
+	var diags yamlparser.Diagnostics
+
+	var named []*Stack
+	for _, stack := range repo.Stacks {
+		if stack.Name == "" {
+			diags = append(diags, stackDiagnostic(stack, []string{"metadata"}, "stack has no metadata.name"))
+			continue
+		}
+		named = append(named, stack)
+	}
+
+	duplicates := utils.FindDuplicateStacks(stackMetadata(named))
+
+	for _, stack := range named {
+		files, ok := duplicates[stack.Name]
+		if !ok {
+			continue
+		}
+
+		var others []string
+		for _, file := range files {
+			if file != stack.FilePath {
+				others = append(others, file)
+			}
+		}
+		sort.Strings(others)
+		diags = append(diags, stackDiagnostic(stack, []string{"metadata", "name"},
+			fmt.Sprintf("stack name %q is also used by %s", stack.Name, strings.Join(others, ", "))))
+	}
+	return diags
+}
+
+// checkComponentVars reports components that have no vars mapping
+func checkComponentVars(repo *Repository) yamlparser.Diagnostics {
+	var diags yamlparser.Diagnostics
+	for _, stack := range repo.Stacks {
+		spec, _ := stack.Document["spec"].(map[string]interface{})
+		components, _ := spec["components"].(map[string]interface{})
+
+		for _, componentType := range sortedKeys(components) {
+			typeComponents, ok := components[componentType].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, name := range sortedKeys(typeComponents) {
+				path := []string{"spec", "components", componentType, name}
+				component, _ := typeComponents[name].(map[string]interface{})
+
+				vars, ok := component["vars"]
+				if !ok || vars == nil {
+					diags = append(diags, stackDiagnostic(stack, path, fmt.Sprintf("component %q has no vars", componentType+"/"+name)))
+					continue
+				}
+				if _, ok := vars.(map[string]interface{}); !ok {
+					diags = append(diags, stackDiagnostic(stack, append(path, "vars"),
+						fmt.Sprintf("vars of component %q must be a mapping, got %T", componentType+"/"+name, vars)))
+				}
+			}
+		}
+	}
+	return diags
+}
+
+// checkComponentSchemas validates the vars of every component that has a schema.
+// A schema that fails to load is reported once, not for every stack using it.
+func checkComponentSchemas(repo *Repository) yamlparser.Diagnostics {
+	schemas := schema.NewComponentSchemas(repo.CatalogDir)
+	brokenSchemas := make(map[string]bool)
+
+	var diags yamlparser.Diagnostics
+	for _, stack := range repo.Stacks {
+		spec, _ := stack.Document["spec"].(map[string]interface{})
+		components, _ := spec["components"].(map[string]interface{})
+
+		for _, componentType := range sortedKeys(components) {
+			typeComponents, _ := components[componentType].(map[string]interface{})
+			for _, name := range sortedKeys(typeComponents) {
+				if _, file, err := schemas.Lookup(name); err != nil {
+					if !brokenSchemas[file] {
+						brokenSchemas[file] = true
+						diags = append(diags, yamlparser.AsDiagnostics(err, file)...)
+					}
+					continue
+				}
+				diags = append(diags, schemas.CheckComponent(stack.FilePath, stack.Document, stack.Provenance, componentType, name)...)
+			}
+		}
+	}
+	return diags
+}
+
+// checkAPIVersions checks the apiVersion and top-level structure of every stack
+// that parsed. Stacks of an older apiVersion are reported as warnings.
+func checkAPIVersions(repo *Repository) yamlparser.Diagnostics {
+	var diags yamlparser.Diagnostics
+	for _, stack := range repo.Stacks {
+		if stack.Document == nil {
+			continue
+		}
+		diags = append(diags, apiversion.CheckStack(stack.FilePath, stack.Document, stack.Provenance)...)
+	}
+	return diags
+}
+
+// stackDiagnostic builds an error positioned where the value at path was set,
+// or at the stack file if its provenance is unknown
+func stackDiagnostic(stack *Stack, path []string, message string) *yamlparser.Diagnostic {
+	diag := &yamlparser.Diagnostic{
+		Severity: yamlparser.SeverityError,
+		File:     stack.FilePath,
+		Message:  message,
+	}
+	if prov, ok := stack.Provenance.Lookup(path...); ok {
+		diag.File = prov.File
+		diag.Line = prov.Line
+		diag.Column = prov.Column
+		diag.Anchor = prov.Anchor
+	}
+	return diag
+}
+
+// stackMetadata returns the metadata of each stack
+func stackMetadata(stacks []*Stack) []stackfinder.StackMetadata {
+	metadata := make([]stackfinder.StackMetadata, 0, len(stacks))
+	for _, stack := range stacks {
+		metadata = append(metadata, stack.StackMetadata)
+	}
+	return metadata
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// Stack is a stack found in the repository together with its merged document
+type Stack struct {
+	stackfinder.StackMetadata
+	Document   map[string]interface{}   // merged document, nil if the stack failed to parse
+	Provenance yamlparser.ProvenanceMap // where every key of Document was set
+}
+
+// Repository is everything a check can inspect: the catalog, every stack found
+// by the stacks glob and the problems found while parsing them
+type Repository struct {
+	StacksPath       string
+	CatalogDir       string
+	Catalog          *yamlparser.Catalog
+	DuplicateAnchors yamlparser.DuplicateAnchorPolicy
+	// Options are the parser options the stacks were decoded with
+	Options yamlparser.Options
+	Stacks  []*Stack
+	// ParseDiagnostics holds a diagnostic for every stack that failed to parse
+	ParseDiagnostics yamlparser.Diagnostics
+}
+
+// Finding is a problem reported by a check
+type Finding struct {
+	Check string `json:"check"`
+	*yamlparser.Diagnostic
+}
+
+// Load parses the catalog in catalogDir and every stack matching stacksPath.
+// A stack that fails to parse is still part of the repository, without a
+// document, and is reported by the parse check. Duplicate anchors are left to
+// the duplicate-anchors check whatever opts.DuplicateAnchors is.
+func Load(stacksPath string, catalogDir string, opts yamlparser.Options) (*Repository, error) {
+	catalog, err := yamlparser.LoadCatalogDir(catalogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anchors: %w", err)
+	}
+
+	if opts.ImportBase == "" {
+		opts.ImportBase = filepath.Dir(filepath.Clean(catalogDir))
+	}
+	engine := yamlparser.NewEngine(catalog, opts)
+
+	documents, diags, err := stackfinder.FindStackDocuments(stacksPath, engine)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &Repository{
+		StacksPath:       stacksPath,
+		CatalogDir:       catalogDir,
+		Catalog:          catalog,
+		DuplicateAnchors: opts.DuplicateAnchors,
+		Options:          opts,
+		ParseDiagnostics: diags,
+	}
+
+	for _, document := range documents {
+		repo.Stacks = append(repo.Stacks, &Stack{
+			StackMetadata: document.StackMetadata,
+			Document:      document.Document,
+			Provenance:    document.Provenance,
+		})
+	}
+
+	return repo, nil
+}
+
+// Validate runs every check in the registry against the repository and returns
+// their findings in registration order, sorted by position within each check.
+// A problem reached from several stacks, such as a broken catalog anchor, is
+// reported once.
+func Validate(repo *Repository, registry *Registry) []Finding {
+	var findings []Finding
+	for _, check := range registry.Checks() {
+		diags := check.Run(repo).Unique()
+		sort.SliceStable(diags, func(i, j int) bool {
+			if diags[i].File != diags[j].File {
+				return diags[i].File < diags[j].File
+			}
+			return diags[i].Line < diags[j].Line
+		})
+
+		for _, diag := range diags {
+			diag.LoadSnippet()
+			findings = append(findings, Finding{Check: check.Name, Diagnostic: diag})
+		}
+	}
+	return findings
+}
+
+// HasErrors reports whether any finding has error severity
+func HasErrors(findings []Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == yamlparser.SeverityError {
+			return true
+		}
+	}
+	return false
+}
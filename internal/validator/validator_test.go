@@ -0,0 +1,215 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	stackfinder "github.com/mcalhoun/skunk/internal/stack-finder"
+	yamlparser "github.com/mcalhoun/skunk/internal/yaml-parser"
+)
+
+// writeRepository creates the given files in a temporary directory and loads it
+// as a repository with stacks in stacks/ and the catalog in catalog/
+func writeRepository(t *testing.T, files map[string]string, opts yamlparser.Options) *Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "catalog"), 0755); err != nil {
+		t.Fatalf("Failed to create catalog dir: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	repo, err := Load(filepath.Join(dir, "stacks", "*.yaml"), filepath.Join(dir, "catalog"), opts)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return repo
+}
+
+// findingsByCheck groups the messages of findings by check name
+func findingsByCheck(findings []Finding) map[string][]string {
+	result := make(map[string][]string)
+	for _, finding := range findings {
+		result[finding.Check] = append(result[finding.Check], filepath.Base(finding.File)+": "+finding.Message)
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	repo := writeRepository(t, map[string]string{
+		"catalog/a.yaml":                     "shared: &shared 1\nbroken: &broken\n  <<: *nowhere\n",
+		"catalog/b.yaml":                     "shared: &shared 2\n",
+		"catalog/components/vpc/schema.yaml": "type: object\nrequired: [cidr]\n",
+		"stacks/good.yaml":                   "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: good\nspec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          name: vpc\n",
+		"stacks/bad.yaml":                    "kind: Stack\nmetadata:\n  name: bad\n  labels:\n    <<: *missing\n",
+		"stacks/dup1.yaml":                   "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: dup\nspec:\n  components:\n    helm:\n      app: {}\n",
+		"stacks/dup2.yaml":                   "kind: Stack\nmetadata:\n  name: dup\nspec:\n  components:\n    terraform:\n      vpc:\n        vars: [a]\n",
+		"stacks/other.yaml":                  "kind: Deployment\n",
+	}, yamlparser.DefaultOptions())
+
+	findings := Validate(repo, DefaultRegistry())
+	if !HasErrors(findings) {
+		t.Fatal("Expected errors")
+	}
+
+	byCheck := findingsByCheck(findings)
+	expected := map[string][]string{
+		"parse":             {`bad.yaml: could not find alias "missing"`},
+		"anchors":           {`a.yaml: anchor "broken" does not resolve: `},
+		"duplicate-anchors": {`b.yaml: duplicate anchor "shared" is defined in `},
+		"unique-names":      {`dup1.yaml: stack name "dup" is also used by `, `dup2.yaml: stack name "dup" is also used by `},
+		"component-vars":    {`dup1.yaml: component "helm/app" has no vars`, `dup2.yaml: vars of component "terraform/vpc" must be a mapping, got []interface {}`},
+		"component-schema":  {`dup2.yaml: component "terraform/vpc" vars: expected object, got array`, `good.yaml: component "terraform/vpc" vars: missing required property "cidr"`},
+		"api-version":       {`dup2.yaml: stack has no apiVersion, expected skunk.mattcalhoun.com/v1`},
+	}
+
+	for check, messages := range expected {
+		if len(byCheck[check]) != len(messages) {
+			t.Errorf("Expected %d %s findings, got %v", len(messages), check, byCheck[check])
+			continue
+		}
+		for i, message := range messages {
+			if !strings.HasPrefix(byCheck[check][i], message) {
+				t.Errorf("Expected %s finding starting with %q, got %q", check, message, byCheck[check][i])
+			}
+		}
+	}
+
+	// Findings are positioned where the offending value was set
+	for _, finding := range findings {
+		if finding.Check == "component-vars" && (finding.Line == 0 || finding.Snippet == "") {
+			t.Errorf("Expected a positioned finding with a snippet, got %+v", finding.Diagnostic)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	repo := writeRepository(t, map[string]string{
+		"stacks/good.yaml": "kind: Stack\nmetadata:\n  name: good\nspec:\n  region: us-east-1\n",
+		"stacks/bad.yaml":  "kind: Stack\nmetadata:\n  name: bad\n  labels:\n    <<: *missing\n",
+	}, yamlparser.DefaultOptions())
+
+	// Each stack is decoded once, so a broken stack is reported once
+	if len(repo.ParseDiagnostics) != 1 || filepath.Base(repo.ParseDiagnostics[0].File) != "bad.yaml" {
+		t.Fatalf("Expected a single diagnostic for bad.yaml, got %v", repo.ParseDiagnostics)
+	}
+
+	if len(repo.Stacks) != 2 {
+		t.Fatalf("Expected 2 stacks, got %d", len(repo.Stacks))
+	}
+	for _, stack := range repo.Stacks {
+		switch stack.Name {
+		case "good":
+			if stack.Document == nil {
+				t.Error("Expected good to have a document")
+			}
+			if _, ok := stack.Provenance.Lookup("spec", "region"); !ok {
+				t.Error("Expected good to have provenance for spec.region")
+			}
+		case "bad":
+			if stack.Document != nil {
+				t.Errorf("Expected bad to have no document, got %v", stack.Document)
+			}
+		default:
+			t.Errorf("Unexpected stack %q", stack.Name)
+		}
+	}
+}
+
+func TestValidateDuplicateAnchorsWarn(t *testing.T) {
+	opts := yamlparser.DefaultOptions()
+	opts.DuplicateAnchors = yamlparser.DuplicateAnchorsWarn
+
+	repo := writeRepository(t, map[string]string{
+		"catalog/a.yaml":   "shared: &shared 1\n",
+		"catalog/b.yaml":   "shared: &shared 2\n",
+		"stacks/good.yaml": "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: good\nspec: {}\n",
+	}, opts)
+
+	findings := Validate(repo, DefaultRegistry())
+	if len(findings) != 1 || findings[0].Severity != yamlparser.SeverityWarning {
+		t.Fatalf("Expected a single warning, got %v", findings)
+	}
+	if HasErrors(findings) {
+		t.Error("Expected warnings not to count as errors")
+	}
+}
+
+func TestValidateMergeSettings(t *testing.T) {
+	repo := writeRepository(t, map[string]string{
+		"stacks/v1.yaml":       "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: v1\nspec:\n  merge:\n    deep: true\n    lists: merge\n    listKey: id\n  components:\n    terraform:\n      vpc:\n        vars:\n          name: vpc\n",
+		"stacks/v1alpha1.yaml": "apiVersion: skunk.mattcalhoun.com/v1alpha1\nkind: Stack\nmetadata:\n  name: v1alpha1\nspec:\n  merge:\n    deep: true\n  terraform:\n    vpc:\n      vars:\n        name: vpc\n",
+	}, yamlparser.DefaultOptions())
+
+	for _, finding := range Validate(repo, DefaultRegistry()) {
+		if finding.Severity == yamlparser.SeverityError {
+			t.Errorf("Expected spec.merge to be valid, got %s finding %q", finding.Check, finding.Message)
+		}
+	}
+}
+
+func TestValidateStackTagInCatalog(t *testing.T) {
+	files := map[string]string{
+		"catalog/peering.yaml": "peering: &peering\n  peer: !stack b terraform.vpc.cidr\n",
+		"stacks/a.yaml":        "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: a\nspec:\n  components:\n    terraform:\n      peering:\n        vars:\n          <<: *peering\n",
+		"stacks/b.yaml":        "apiVersion: skunk.mattcalhoun.com/v1\nkind: Stack\nmetadata:\n  name: b\nspec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          cidr: 10.1.0.0/16\n",
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// The CLI registers !stack on the options the stacks are parsed with
+	stacksPath := filepath.Join(dir, "stacks", "*.yaml")
+	catalogDir := filepath.Join(dir, "catalog")
+	opts := yamlparser.DefaultOptions()
+	stackfinder.NewStackResolver(stacksPath, catalogDir, opts).Register(opts.Tags)
+
+	repo, err := Load(stacksPath, catalogDir, opts)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	for _, finding := range Validate(repo, DefaultRegistry()) {
+		if finding.Severity == yamlparser.SeverityError {
+			t.Errorf("Expected the !stack anchor to be valid, got %s finding %q", finding.Check, finding.Message)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("first", "first check", func(*Repository) yamlparser.Diagnostics {
+		return yamlparser.Diagnostics{{Severity: yamlparser.SeverityError, File: "b.yaml", Message: "b"}, {Severity: yamlparser.SeverityError, File: "a.yaml", Message: "a"}}
+	})
+	registry.Register("second", "second check", func(*Repository) yamlparser.Diagnostics { return nil })
+	registry.Register("first", "replaced", func(*Repository) yamlparser.Diagnostics {
+		return yamlparser.Diagnostics{{Severity: yamlparser.SeverityWarning, File: "c.yaml", Message: "c"}}
+	})
+
+	checks := registry.Checks()
+	if len(checks) != 2 || checks[0].Name != "first" || checks[0].Description != "replaced" || checks[1].Name != "second" {
+		t.Fatalf("Unexpected checks: %+v", checks)
+	}
+
+	findings := Validate(&Repository{}, registry)
+	if len(findings) != 1 || findings[0].Check != "first" || findings[0].Message != "c" {
+		t.Errorf("Unexpected findings: %+v", findings)
+	}
+}
@@ -0,0 +1,272 @@
+package yamlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mcalhoun/skunk/internal/logger"
+)
+
+// DuplicateAnchorPolicy controls what happens when several catalog files
+// define an anchor with the same name
+type DuplicateAnchorPolicy string
+
+// Supported duplicate anchor policies
+const (
+	// DuplicateAnchorsError fails catalog loading when an anchor is defined more than once
+	DuplicateAnchorsError DuplicateAnchorPolicy = "error"
+	// DuplicateAnchorsWarn logs a warning and uses the definition loaded last
+	DuplicateAnchorsWarn DuplicateAnchorPolicy = "warn"
+)
+
+// Validate checks that the policy is known
+func (p DuplicateAnchorPolicy) Validate() error {
+	switch p {
+	case "", DuplicateAnchorsError, DuplicateAnchorsWarn:
+		return nil
+	default:
+		return fmt.Errorf("unknown duplicate anchor policy %q (expected %s or %s)", p, DuplicateAnchorsError, DuplicateAnchorsWarn)
+	}
+}
+
+// anchorDef is an anchor definition together with the file and position it was found at
+type anchorDef struct {
+	name   string
+	file   string
+	line   int
+	column int
+	node   ast.Node
+}
+
+// Catalog indexes the anchors defined in a set of YAML files so that
+// aliases in other documents can be resolved against them
+type Catalog struct {
+	anchors map[string]*anchorDef
+	defs    []*anchorDef    // every definition in load order, including duplicates
+	files   map[string]bool // files already indexed
+}
+
+// Anchor describes a single anchor definition in the catalog
+type Anchor struct {
+	Name   string      `json:"name"`
+	File   string      `json:"file"`
+	Line   int         `json:"line"`
+	Column int         `json:"column"`
+	Value  interface{} `json:"value"`
+	Error  string      `json:"error,omitempty"` // set if the value could not be resolved
+}
+
+// AnchorCollision lists every definition of an anchor name defined more than once
+type AnchorCollision struct {
+	Name        string   `json:"name"`
+	Definitions []Anchor `json:"definitions"`
+}
+
+// String describes the collision as the name and the position of each definition
+func (c AnchorCollision) String() string {
+	positions := make([]string, 0, len(c.Definitions))
+	for _, def := range c.Definitions {
+		positions = append(positions, fmt.Sprintf("%s:%d:%d", def.File, def.Line, def.Column))
+	}
+	return fmt.Sprintf("anchor %q is defined in %s", c.Name, strings.Join(positions, ", "))
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{
+		anchors: make(map[string]*anchorDef),
+		files:   make(map[string]bool),
+	}
+}
+
+// LoadCatalog parses every YAML file directly inside the given directories
+// and indexes the anchors they define. A directory listed more than once is
+// only indexed once.
+func LoadCatalog(dirs []string) (*Catalog, error) {
+	catalog := NewCatalog()
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read anchor directory %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !isYAMLFile(entry.Name()) {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if catalog.files[path] {
+				continue
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read anchor file %s: %w", path, err)
+			}
+
+			if err := catalog.addSource(path, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+// LoadCatalogDir indexes the anchors defined in catalogDir and every directory below it
+func LoadCatalogDir(catalogDir string) (*Catalog, error) {
+	dirs, err := FindSubdirectories(catalogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find subdirectories: %w", err)
+	}
+	return LoadCatalog(dirs)
+}
+
+// NewStackEngine creates an engine that decodes stacks the way ParseStack does:
+// aliases resolve against the anchors in catalogDir and every directory below
+// it, and relative imports resolve against the directory containing catalogDir
+func NewStackEngine(catalogDir string, opts Options) (*Engine, error) {
+	catalog, err := LoadCatalogDir(catalogDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load anchors: %w", err)
+	}
+	if err := catalog.CheckDuplicates(opts.DuplicateAnchors); err != nil {
+		return nil, fmt.Errorf("failed to load anchors: %w", err)
+	}
+
+	if opts.ImportBase == "" {
+		opts.ImportBase = filepath.Dir(filepath.Clean(catalogDir))
+	}
+
+	return NewEngine(catalog, opts), nil
+}
+
+// addSource parses YAML data and indexes every anchor it defines. A later
+// definition of an anchor name replaces an earlier one; both are kept for
+// duplicate detection.
+func (c *Catalog) addSource(file string, data []byte) error {
+	f, err := parseBytes(data, file)
+	if err != nil {
+		return withSnippet(err, file, data)
+	}
+	c.files[file] = true
+
+	for _, doc := range f.Docs {
+		collectAnchors(doc, func(anchor *ast.AnchorNode) {
+			def := &anchorDef{name: anchor.Name.GetToken().Value, file: file, node: anchor.Value}
+			if tk := anchor.GetToken(); tk != nil {
+				def.line = tk.Position.Line
+				def.column = tk.Position.Column
+			}
+			c.anchors[def.name] = def
+			c.defs = append(c.defs, def)
+		})
+	}
+
+	return nil
+}
+
+// Anchors returns every anchor definition in the catalog sorted by name,
+// with duplicates in load order. Values are resolved against the catalog with
+// opts, so the tags registered on opts.Tags resolve as they do in stacks.
+func (c *Catalog) Anchors(opts Options) []Anchor {
+	anchors := make([]Anchor, 0, len(c.defs))
+	for _, def := range c.defs {
+		anchors = append(anchors, c.describe(def, opts))
+	}
+
+	sort.SliceStable(anchors, func(i, j int) bool {
+		return anchors[i].Name < anchors[j].Name
+	})
+	return anchors
+}
+
+// Collisions returns the anchor names defined more than once, sorted by name
+func (c *Catalog) Collisions() []AnchorCollision {
+	byName := make(map[string][]*anchorDef)
+	for _, def := range c.defs {
+		byName[def.name] = append(byName[def.name], def)
+	}
+
+	var collisions []AnchorCollision
+	for name, defs := range byName {
+		if len(defs) < 2 {
+			continue
+		}
+		collision := AnchorCollision{Name: name}
+		for _, def := range defs {
+			collision.Definitions = append(collision.Definitions, def.position())
+		}
+		collisions = append(collisions, collision)
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Name < collisions[j].Name
+	})
+	return collisions
+}
+
+// CheckDuplicates applies the duplicate anchor policy: it returns an error
+// listing every collision, or only logs them when the policy is warn
+func (c *Catalog) CheckDuplicates(policy DuplicateAnchorPolicy) error {
+	collisions := c.Collisions()
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	if policy == DuplicateAnchorsWarn {
+		for _, collision := range collisions {
+			logger.Log.Warnf("Duplicate %s; using the last definition", collision)
+		}
+		return nil
+	}
+
+	// Each collision is reported at the definition that would win
+	diags := make(Diagnostics, 0, len(collisions))
+	for _, collision := range collisions {
+		last := collision.Definitions[len(collision.Definitions)-1]
+		diag := &Diagnostic{
+			Severity: SeverityError,
+			File:     last.File,
+			Line:     last.Line,
+			Column:   last.Column,
+			Anchor:   collision.Name,
+			Message:  "duplicate " + collision.String(),
+		}
+		diag.LoadSnippet()
+		diags = append(diags, diag)
+	}
+	return diags
+}
+
+// position returns an anchor definition in its exported form, without its value
+func (def *anchorDef) position() Anchor {
+	return Anchor{Name: def.name, File: def.file, Line: def.line, Column: def.column}
+}
+
+// describe resolves an anchor definition into its exported form
+func (c *Catalog) describe(def *anchorDef, opts Options) Anchor {
+	anchor := def.position()
+
+	engine := NewEngine(c, opts)
+	d := &decoder{
+		engine:    engine,
+		local:     make(map[string]*anchorDef),
+		resolving: map[*anchorDef]bool{def: true},
+		merge:     engine.opts.Merge,
+	}
+	value, err := d.resolve(def.node, def.file, nil)
+	if err != nil {
+		anchor.Error = err.Error()
+	} else {
+		anchor.Value = value
+	}
+
+	return anchor
+}
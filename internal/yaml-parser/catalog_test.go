@@ -0,0 +1,119 @@
+package yamlparser
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalogAnchors(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{
+		"anchors.yaml": `
+base: &base
+  a: 1
+derived: &derived
+  <<: *base
+  b: 2
+`,
+	})
+
+	anchors := catalog.Anchors(DefaultOptions())
+	if len(anchors) != 2 {
+		t.Fatalf("Expected 2 anchors, got %d", len(anchors))
+	}
+
+	if anchors[0].Name != "base" || anchors[0].File != "anchors.yaml" || anchors[0].Line != 2 {
+		t.Errorf("Unexpected first anchor: %+v", anchors[0])
+	}
+
+	expected := map[string]interface{}{"a": uint64(1), "b": uint64(2)}
+	if anchors[1].Name != "derived" || !reflect.DeepEqual(anchors[1].Value, expected) {
+		t.Errorf("Unexpected second anchor: %+v", anchors[1])
+	}
+}
+
+func TestCatalogAnchorResolutionError(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{
+		"anchors.yaml": "broken: &broken\n  <<: *missing\n",
+	})
+
+	anchors := catalog.Anchors(DefaultOptions())
+	if len(anchors) != 1 || !strings.Contains(anchors[0].Error, "could not find alias \"missing\"") {
+		t.Errorf("Expected resolution error, got %+v", anchors)
+	}
+}
+
+func TestCatalogCollisions(t *testing.T) {
+	catalog := NewCatalog()
+	if err := catalog.addSource("a.yaml", []byte("x: &shared\n  from: a\nonly-a: &only-a 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := catalog.addSource("b.yaml", []byte("\ny: &shared\n  from: b\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	collisions := catalog.Collisions()
+	if len(collisions) != 1 {
+		t.Fatalf("Expected 1 collision, got %d", len(collisions))
+	}
+	if collisions[0].String() != `anchor "shared" is defined in a.yaml:1:4, b.yaml:2:4` {
+		t.Errorf("Unexpected collision: %s", collisions[0])
+	}
+
+	// The last definition wins
+	if catalog.anchors["shared"].file != "b.yaml" {
+		t.Errorf("Expected b.yaml to win, got %s", catalog.anchors["shared"].file)
+	}
+
+	err := catalog.CheckDuplicates(DuplicateAnchorsError)
+	if err == nil || !strings.Contains(err.Error(), `duplicate anchor "shared"`) {
+		t.Errorf("Expected duplicate anchor error, got %v", err)
+	}
+
+	if err := catalog.CheckDuplicates(DuplicateAnchorsWarn); err != nil {
+		t.Errorf("Expected no error with warn policy, got %v", err)
+	}
+}
+
+func TestLoadCatalogRepeatedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"defaults.yaml": "defaults: &defaults\n  a: 1\n",
+	})
+
+	catalog, err := LoadCatalog([]string{dir, filepath.Clean(dir)})
+	if err != nil {
+		t.Fatalf("LoadCatalog failed: %v", err)
+	}
+	if collisions := catalog.Collisions(); len(collisions) != 0 {
+		t.Errorf("Expected no collisions, got %v", collisions)
+	}
+}
+
+func TestParseStackDuplicateAnchors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"catalog/one/defaults.yaml": "defaults: &vpc-defaults\n  name: one\n",
+		"catalog/two/defaults.yaml": "defaults: &vpc-defaults\n  name: two\n",
+		"stack.yaml":                "vars:\n  <<: *vpc-defaults\n",
+	})
+
+	stackFile := filepath.Join(dir, "stack.yaml")
+	catalogDir := filepath.Join(dir, "catalog")
+
+	_, err := ParseStack(stackFile, catalogDir)
+	if err == nil || !strings.Contains(err.Error(), `duplicate anchor "vpc-defaults"`) {
+		t.Fatalf("Expected duplicate anchor error, got %v", err)
+	}
+
+	opts := DefaultOptions()
+	opts.DuplicateAnchors = DuplicateAnchorsWarn
+	result, err := ParseStackWithOptions(stackFile, catalogDir, opts)
+	if err != nil {
+		t.Fatalf("ParseStackWithOptions failed: %v", err)
+	}
+	if result["vars"].(map[string]interface{})["name"] != "two" {
+		t.Errorf("Expected the last definition to win, got %v", result["vars"])
+	}
+}
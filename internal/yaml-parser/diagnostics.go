@@ -0,0 +1,206 @@
+package yamlparser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// Severity is how serious a diagnostic is
+type Severity string
+
+// Supported severities
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// snippetContext is the number of lines shown before and after the line of a diagnostic
+const snippetContext = 2
+
+// Diagnostic describes a problem found while decoding a YAML file, positioned
+// at the node that caused it
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Anchor   string   `json:"anchor,omitempty"` // alias being resolved when the problem was found
+	Message  string   `json:"message"`
+	Snippet  string   `json:"snippet,omitempty"` // code frame around the position
+}
+
+// Error returns the diagnostic as file:line:column: message
+func (d *Diagnostic) Error() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics is a list of diagnostics that can be returned as a single error
+type Diagnostics []*Diagnostic
+
+// Error joins the diagnostics, one per line
+func (d Diagnostics) Error() string {
+	messages := make([]string, 0, len(d))
+	for _, diag := range d {
+		messages = append(messages, diag.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Unique returns the diagnostics without repeats of the same message at the same
+// position, such as an error in a catalog anchor reached from several stacks
+func (d Diagnostics) Unique() Diagnostics {
+	type key struct {
+		file         string
+		line, column int
+		message      string
+	}
+
+	seen := make(map[key]bool)
+	var unique Diagnostics
+	for _, diag := range d {
+		k := key{diag.File, diag.Line, diag.Column, diag.Message}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, diag)
+	}
+	return unique
+}
+
+// AsDiagnostics returns the diagnostics carried by err. An error without a
+// position becomes a single diagnostic for file.
+func AsDiagnostics(err error, file string) Diagnostics {
+	if err == nil {
+		return nil
+	}
+
+	var diags Diagnostics
+	if errors.As(err, &diags) {
+		return diags
+	}
+
+	var diag *Diagnostic
+	if errors.As(err, &diag) {
+		return Diagnostics{diag}
+	}
+
+	return Diagnostics{{Severity: SeverityError, File: file, Message: err.Error()}}
+}
+
+// LoadSnippet sets the code frame of a positioned diagnostic that has none,
+// reading its file from disk
+func (d *Diagnostic) LoadSnippet() {
+	_ = withSnippet(d, "", nil)
+}
+
+// positionError builds an error diagnostic positioned at a node
+func positionError(file string, node ast.Node, format string, args ...interface{}) *Diagnostic {
+	diag := &Diagnostic{
+		Severity: SeverityError,
+		File:     file,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil && node.GetToken() != nil {
+		pos := node.GetToken().Position
+		diag.Line = pos.Line
+		diag.Column = pos.Column
+	}
+	return diag
+}
+
+// syntaxError converts a goccy/go-yaml parse error into a diagnostic
+func syntaxError(file string, err error) error {
+	var yamlErr yaml.Error
+	if !errors.As(err, &yamlErr) || yamlErr.GetToken() == nil {
+		return &Diagnostic{Severity: SeverityError, File: file, Message: err.Error()}
+	}
+
+	pos := yamlErr.GetToken().Position
+	return &Diagnostic{
+		Severity: SeverityError,
+		File:     file,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Message:  yamlErr.GetMessage(),
+	}
+}
+
+// withAnchor records the alias being resolved on a diagnostic that has none yet
+func withAnchor(err error, name string) error {
+	var diag *Diagnostic
+	if errors.As(err, &diag) && diag.Anchor == "" {
+		diag.Anchor = name
+	}
+	return err
+}
+
+// withSnippet adds a code frame to the diagnostic carried by err. data is the
+// content of file; any other file is read from disk.
+func withSnippet(err error, file string, data []byte) error {
+	var diag *Diagnostic
+	if !errors.As(err, &diag) || diag.Snippet != "" || diag.Line == 0 {
+		return err
+	}
+
+	source := data
+	if diag.File != file {
+		var readErr error
+		if source, readErr = os.ReadFile(diag.File); readErr != nil {
+			return err
+		}
+	}
+
+	diag.Snippet = CodeFrame(source, diag.Line, diag.Column)
+	return err
+}
+
+// CodeFrame renders the lines around line with a marker on that line and a
+// caret under column. It returns an empty string if line is out of range.
+func CodeFrame(source []byte, line, column int) string {
+	lines := strings.Split(strings.TrimRight(string(source), "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	first := line - snippetContext
+	if first < 1 {
+		first = 1
+	}
+	last := line + snippetContext
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(fmt.Sprint(last))
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, lines[n-1])
+		if n == line && column > 0 {
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", strings.Repeat(" ", column-1))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package yamlparser
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticPositions(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{
+		"anchors.yaml": "broken: &broken\n  name: !env SKUNK_TEST_UNSET\n",
+	})
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected Diagnostic
+	}{
+		{
+			name:     "Missing alias",
+			input:    "vars:\n  <<: *missing\n",
+			expected: Diagnostic{File: "stack.yaml", Line: 2, Column: 7, Anchor: "missing", Message: `could not find alias "missing"`},
+		},
+		{
+			name:     "Error inside an anchor",
+			input:    "vars:\n  <<: *broken\n",
+			expected: Diagnostic{File: "anchors.yaml", Line: 2, Column: 9, Anchor: "broken", Message: `!env: environment variable "SKUNK_TEST_UNSET" is not set and no default was given`},
+		},
+		{
+			name:     "Syntax error",
+			input:    "vars:\n  name: [unclosed\n",
+			expected: Diagnostic{File: "stack.yaml", Line: 2, Column: 9, Message: "sequence end token ']' not found"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(catalog, DefaultOptions()).Decode([]byte(tc.input), "stack.yaml")
+
+			var diag *Diagnostic
+			if !errors.As(err, &diag) {
+				t.Fatalf("Expected a diagnostic, got %v", err)
+			}
+			if diag.File != tc.expected.File || diag.Line != tc.expected.Line || diag.Column != tc.expected.Column {
+				t.Errorf("Expected position %s:%d:%d, got %s:%d:%d", tc.expected.File, tc.expected.Line, tc.expected.Column, diag.File, diag.Line, diag.Column)
+			}
+			if diag.Anchor != tc.expected.Anchor {
+				t.Errorf("Expected anchor %q, got %q", tc.expected.Anchor, diag.Anchor)
+			}
+			if diag.Message != tc.expected.Message {
+				t.Errorf("Expected message %q, got %q", tc.expected.Message, diag.Message)
+			}
+		})
+	}
+}
+
+func TestDiagnosticSnippet(t *testing.T) {
+	input := "metadata:\n  name: dev\nvars:\n  <<: *missing\n  region: us-east-1\n"
+	_, err := NewEngine(nil, DefaultOptions()).Decode([]byte(input), "stack.yaml")
+
+	diags := AsDiagnostics(err, "stack.yaml")
+	if len(diags) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diags)
+	}
+
+	expected := "  2 |   name: dev\n" +
+		"  3 | vars:\n" +
+		"> 4 |   <<: *missing\n" +
+		"    |       ^\n" +
+		"  5 |   region: us-east-1\n"
+	if diags[0].Snippet != expected {
+		t.Errorf("Expected snippet:\n%s\nGot:\n%s", expected, diags[0].Snippet)
+	}
+}
+
+func TestDuplicateAnchorDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "x: &shared 1\n",
+		"b.yaml": "\ny: &shared 2\n",
+	})
+
+	catalog, err := LoadCatalogDir(dir)
+	if err != nil {
+		t.Fatalf("LoadCatalogDir failed: %v", err)
+	}
+
+	diags := AsDiagnostics(catalog.CheckDuplicates(DuplicateAnchorsError), "")
+	if len(diags) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diags)
+	}
+	if diags[0].File != filepath.Join(dir, "b.yaml") || diags[0].Line != 2 || diags[0].Anchor != "shared" {
+		t.Errorf("Expected the diagnostic at the last definition, got %+v", diags[0])
+	}
+	if !strings.Contains(diags[0].Snippet, "> 2 | y: &shared 2") {
+		t.Errorf("Expected a snippet of b.yaml, got %q", diags[0].Snippet)
+	}
+}
+
+func TestAsDiagnostics(t *testing.T) {
+	if diags := AsDiagnostics(nil, "stack.yaml"); diags != nil {
+		t.Errorf("Expected no diagnostics, got %v", diags)
+	}
+
+	diags := AsDiagnostics(errors.New("boom"), "stack.yaml")
+	if len(diags) != 1 || diags[0].Error() != "stack.yaml: boom" || diags[0].Severity != SeverityError {
+		t.Errorf("Expected an unpositioned error diagnostic, got %v", diags)
+	}
+}
+
+func TestCodeFrame(t *testing.T) {
+	source := []byte("a: 1\nb: 2\n")
+	if frame := CodeFrame(source, 3, 1); frame != "" {
+		t.Errorf("Expected no frame for a line out of range, got %q", frame)
+	}
+
+	expected := "> 1 | a: 1\n    |    ^\n  2 | b: 2\n"
+	if frame := CodeFrame(source, 1, 4); frame != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, frame)
+	}
+}
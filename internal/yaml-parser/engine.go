@@ -0,0 +1,536 @@
+package yamlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// Engine converts goccy/go-yaml ASTs into plain Go values. Repeated "<<"
+// keys are collapsed and aliases are resolved structurally against the
+// anchors in the document itself and in the catalog, so the result does
+// not depend on indentation, flow style or comments.
+type Engine struct {
+	catalog *Catalog
+	opts    Options
+}
+
+// Options configures how an Engine combines values
+type Options struct {
+	// Merge controls shallow versus deep merging. A document can override
+	// these settings with a spec.merge section.
+	Merge MergeOptions
+	// ImportBase is the directory that relative spec.imports paths are
+	// resolved against. An empty value means the current directory.
+	ImportBase string
+	// DuplicateAnchors controls whether an anchor name defined in several
+	// catalog files is an error or a warning. An empty value means error.
+	DuplicateAnchors DuplicateAnchorPolicy
+	// Tags resolves custom tags such as !env. Tags that are neither in the
+	// registry nor part of the YAML core schema are errors. A nil registry
+	// means DefaultTagRegistry.
+	Tags *TagRegistry
+}
+
+// DefaultOptions returns the options used by ParseStack
+func DefaultOptions() Options {
+	return Options{
+		Merge:            DefaultMergeOptions(),
+		DuplicateAnchors: DuplicateAnchorsError,
+		Tags:             DefaultTagRegistry(),
+	}
+}
+
+// NewEngine creates an engine that resolves aliases against the given catalog
+func NewEngine(catalog *Catalog, opts Options) *Engine {
+	if catalog == nil {
+		catalog = NewCatalog()
+	}
+	if opts.Tags == nil {
+		opts.Tags = DefaultTagRegistry()
+	}
+	return &Engine{catalog: catalog, opts: opts}
+}
+
+// DecodeFile reads and decodes a YAML file
+func (e *Engine) DecodeFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML file %s: %w", path, err)
+	}
+	return e.Decode(data, path)
+}
+
+// Decode decodes the first document in data into a map. The file name is
+// only used to position error messages.
+func (e *Engine) Decode(data []byte, file string) (map[string]interface{}, error) {
+	result, _, err := e.decode(data, file, false)
+	return result, err
+}
+
+// DecodeWithProvenance decodes like Decode and also records, for every key
+// path, the file, line and anchor that set its value and the values it overrode
+func (e *Engine) DecodeWithProvenance(data []byte, file string) (map[string]interface{}, ProvenanceMap, error) {
+	return e.decode(data, file, true)
+}
+
+// decode decodes the first document in data, recording provenance if requested
+func (e *Engine) decode(data []byte, file string, trackProvenance bool) (map[string]interface{}, ProvenanceMap, error) {
+	prov := ProvenanceMap{}
+
+	var tracked ProvenanceMap
+	if trackProvenance {
+		tracked = prov
+	}
+
+	result, err := e.decodeDocument(data, file, nil, tracked, []string{file})
+	if err != nil {
+		return nil, nil, withSnippet(err, file, data)
+	}
+
+	// Templates see the fully merged document, so they are evaluated last
+	if err := evaluateTemplates(result, tracked); err != nil {
+		return nil, nil, withSnippet(err, file, data)
+	}
+
+	return result, prov, nil
+}
+
+// decodeDocument decodes the first document in data. merge holds the settings
+// inherited from an importing document, or nil to start from the engine's
+// options; a spec.merge section in the document overrides them either way.
+// The files listed in spec.imports are decoded first and the document is deep
+// merged over them. chain holds the files being imported, outermost first.
+func (e *Engine) decodeDocument(data []byte, file string, merge *MergeOptions, prov ProvenanceMap, chain []string) (map[string]interface{}, error) {
+	f, err := parseBytes(data, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Docs) == 0 || f.Docs[0].Body == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	doc := f.Docs[0]
+	d := &decoder{
+		engine:    e,
+		local:     make(map[string]*anchorDef),
+		resolving: make(map[*anchorDef]bool),
+		merge:     e.opts.Merge,
+		prov:      prov,
+		chain:     chain,
+	}
+	if merge != nil {
+		d.merge = *merge
+	}
+	collectAnchors(doc, func(anchor *ast.AnchorNode) {
+		name := anchor.Name.GetToken().Value
+		d.local[name] = &anchorDef{name: name, file: file, node: anchor.Value}
+	})
+
+	// A spec.merge section in the document overrides the inherited merge options
+	if node := lookupPath(doc.Body, "spec", "merge"); node != nil {
+		settings, err := d.resolve(node, file, nil)
+		if err != nil {
+			return nil, err
+		}
+		if m, ok := settings.(map[string]interface{}); ok {
+			if d.merge, err = d.merge.withOverrides(m); err != nil {
+				return nil, positionError(file, node, "invalid merge settings: %v", err)
+			}
+		}
+	}
+
+	// Imported files are decoded first so the document's own values override them
+	var imported map[string]interface{}
+	if node := lookupPath(doc.Body, "spec", "imports"); node != nil {
+		if imported, err = d.loadImports(node, file); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := d.resolve(doc.Body, file, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, positionError(file, doc.Body, "document root must be a mapping, got %s", doc.Body.Type())
+	}
+
+	if imported != nil {
+		opts := d.merge
+		opts.Deep = true
+		result = DeepMerge(imported, result, opts)
+		removeImports(result)
+	}
+
+	return result, nil
+}
+
+// decoder holds the state of a single Decode call
+type decoder struct {
+	engine    *Engine
+	local     map[string]*anchorDef
+	resolving map[*anchorDef]bool
+	merge     MergeOptions
+	prov      ProvenanceMap // nil unless provenance is being tracked
+	anchors   []string      // names of the aliases currently being resolved
+	chain     []string      // files being imported or included, outermost first
+}
+
+// resolve converts a node into a Go value. file is the file the node was read
+// from and path is the key path the value will be stored at, or nil when the
+// value is not part of the document tree (list items, keys, settings).
+func (d *decoder) resolve(node ast.Node, file string, path []string) (interface{}, error) {
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case *ast.DocumentNode:
+		return d.resolve(n.Body, file, path)
+	case *ast.MappingNode:
+		return d.resolveMapping(n.Values, file, path)
+	case *ast.MappingValueNode:
+		return d.resolveMapping([]*ast.MappingValueNode{n}, file, path)
+	case *ast.SequenceNode:
+		values := make([]interface{}, 0, len(n.Values))
+		for _, item := range n.Values {
+			value, err := d.resolve(item, file, nil)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case *ast.AnchorNode:
+		return d.resolve(n.Value, file, path)
+	case *ast.AliasNode:
+		return d.resolveAlias(n, file, path)
+	case *ast.TagNode:
+		return d.resolveTag(n, file, path)
+	case *ast.CommentNode, *ast.CommentGroupNode:
+		return nil, nil
+	case ast.ScalarNode:
+		return n.GetValue(), nil
+	default:
+		return nil, positionError(file, node, "unsupported YAML node type %s", node.Type())
+	}
+}
+
+// resolveMapping builds a map from mapping entries. All "<<" entries are
+// collected first and merged in order, later sources overriding earlier
+// ones; explicit keys then override anything that was merged in. Whether
+// overrides replace or deep merge existing values depends on d.merge.
+//
+// Merge sources are resolved at the same path as the mapping itself, so
+// their keys record provenance in precedence order before explicit keys do.
+func (d *decoder) resolveMapping(entries []*ast.MappingValueNode, file string, path []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, entry := range entries {
+		if !entry.Key.IsMergeKey() {
+			continue
+		}
+
+		sources, err := d.mergeSources(entry.Value, file, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			for key, value := range source {
+				result[key] = mergeValue(result[key], value, d.merge)
+			}
+		}
+	}
+
+	seen := make(map[string]ast.Node)
+	for _, entry := range entries {
+		if entry.Key.IsMergeKey() {
+			continue
+		}
+
+		key, err := d.mapKey(entry.Key, file)
+		if err != nil {
+			return nil, err
+		}
+		if previous, exists := seen[key]; exists {
+			pos := previous.GetToken().Position
+			return nil, positionError(file, entry.Key, "mapping key %q already defined at line %d", key, pos.Line)
+		}
+		seen[key] = entry.Key
+
+		childPath := appendPath(path, key)
+		value, err := d.resolve(entry.Value, file, childPath)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = mergeValue(result[key], value, d.merge)
+		d.record(childPath, file, entry.Key, result[key])
+	}
+
+	return result, nil
+}
+
+// mergeSources resolves the value of a "<<" key into the list of maps to merge.
+// The value may be an alias, an inline mapping, or a sequence of either.
+func (d *decoder) mergeSources(node ast.Node, file string, path []string) ([]map[string]interface{}, error) {
+	if seq, ok := node.(*ast.SequenceNode); ok {
+		var sources []map[string]interface{}
+		for _, item := range seq.Values {
+			itemSources, err := d.mergeSources(item, file, path)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, itemSources...)
+		}
+		return sources, nil
+	}
+
+	value, err := d.resolve(node, file, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, positionError(file, node, "merge value must be a mapping or a sequence of mappings, got %T", value)
+	}
+}
+
+// resolveAlias resolves an alias against the local anchors first and the catalog second
+func (d *decoder) resolveAlias(alias *ast.AliasNode, file string, path []string) (interface{}, error) {
+	name := alias.Value.GetToken().Value
+
+	def, ok := d.local[name]
+	if !ok {
+		def, ok = d.engine.catalog.anchors[name]
+	}
+	if !ok {
+		diag := positionError(file, alias, "could not find alias %q", name)
+		diag.Anchor = name
+		return nil, diag
+	}
+
+	if d.resolving[def] {
+		return nil, positionError(file, alias, "alias %q refers to itself", name)
+	}
+	d.resolving[def] = true
+	d.anchors = append(d.anchors, name)
+	defer func() {
+		delete(d.resolving, def)
+		d.anchors = d.anchors[:len(d.anchors)-1]
+	}()
+
+	value, err := d.resolve(def.node, def.file, path)
+	if err != nil {
+		return nil, withAnchor(err, name)
+	}
+	return value, nil
+}
+
+// resolveTag applies the YAML core schema tags and the custom tags in the
+// engine's tag registry. Any other tag is an error.
+func (d *decoder) resolveTag(tag *ast.TagNode, file string, path []string) (interface{}, error) {
+	value, err := d.resolve(tag.Value, file, path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := tag.Start.Value
+	switch token.ReservedTagKeyword(name) {
+	case token.StringTag:
+		if value == nil {
+			return "", nil
+		}
+		return fmt.Sprint(value), nil
+	case token.IntegerTag:
+		i, err := strconv.Atoi(fmt.Sprint(value))
+		if err != nil {
+			return nil, positionError(file, tag, "cannot convert %q to integer", fmt.Sprint(value))
+		}
+		return i, nil
+	case token.FloatTag:
+		f, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return nil, positionError(file, tag, "cannot convert %q to float", fmt.Sprint(value))
+		}
+		return f, nil
+	case token.BooleanTag:
+		b, err := strconv.ParseBool(strings.ToLower(fmt.Sprint(value)))
+		if err != nil {
+			return nil, positionError(file, tag, "cannot convert %q to boolean", fmt.Sprint(value))
+		}
+		return b, nil
+	case token.NullTag:
+		return nil, nil
+	}
+
+	if _, reserved := token.ReservedTagKeywordMap[token.ReservedTagKeyword(name)]; reserved {
+		return value, nil
+	}
+
+	fn, ok := d.engine.opts.Tags.Lookup(name)
+	if !ok {
+		return nil, positionError(file, tag, "unknown tag %q (supported tags: %s)", name, strings.Join(d.engine.opts.Tags.Tags(), ", "))
+	}
+
+	result, err := fn(&TagContext{Tag: name, File: file, Value: value, decoder: d, path: path, node: tag})
+	if err != nil {
+		return nil, positionError(file, tag, "%s: %v", name, err)
+	}
+	return result, nil
+}
+
+// include decodes the first document of another file with a fresh set of
+// local anchors, storing its values at path
+func (d *decoder) include(file string, path []string) (interface{}, error) {
+	if cycle := importCycle(d.chain, file); cycle != nil {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read include: %w", err)
+	}
+
+	f, err := parseBytes(data, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Docs) == 0 || f.Docs[0].Body == nil {
+		return nil, nil
+	}
+
+	included := &decoder{
+		engine:    d.engine,
+		local:     make(map[string]*anchorDef),
+		resolving: make(map[*anchorDef]bool),
+		merge:     d.merge,
+		prov:      d.prov,
+		chain:     append(append([]string{}, d.chain...), file),
+	}
+	collectAnchors(f.Docs[0], func(anchor *ast.AnchorNode) {
+		name := anchor.Name.GetToken().Value
+		included.local[name] = &anchorDef{name: name, file: file, node: anchor.Value}
+	})
+
+	return included.resolve(f.Docs[0].Body, file, path)
+}
+
+// mapKey converts a mapping key node into its string form
+func (d *decoder) mapKey(key ast.MapKeyNode, file string) (string, error) {
+	value, err := d.resolve(key, file, nil)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "null", nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// record stores the provenance of a value set at path by the given key node
+func (d *decoder) record(path []string, file string, key ast.Node, value interface{}) {
+	if d.prov == nil || path == nil {
+		return
+	}
+
+	src := Source{File: file, Value: value}
+	if tk := key.GetToken(); tk != nil {
+		src.Line = tk.Position.Line
+		src.Column = tk.Position.Column
+	}
+	if len(d.anchors) > 0 {
+		src.Anchor = d.anchors[len(d.anchors)-1]
+	}
+
+	d.prov.record(path, src)
+}
+
+// appendPath returns path extended with key without sharing path's backing array.
+// A nil path stays nil so untracked subtrees remain untracked.
+func appendPath(path []string, key string) []string {
+	if path == nil {
+		return nil
+	}
+	child := make([]string, len(path)+1)
+	copy(child, path)
+	child[len(path)] = key
+	return child
+}
+
+// parseBytes parses YAML into an AST. Duplicate keys are allowed by the
+// parser so that repeated "<<" keys reach the engine; duplicate regular
+// keys are rejected during resolution instead. Syntax errors are returned as
+// diagnostics positioned in file.
+func parseBytes(data []byte, file string) (*ast.File, error) {
+	f, err := parser.ParseBytes(data, 0, parser.AllowDuplicateMapKey())
+	if err != nil {
+		return nil, syntaxError(file, err)
+	}
+	return f, nil
+}
+
+// lookupPath follows literal mapping keys from node and returns the value
+// node at the end of the path, or nil if any key is missing
+func lookupPath(node ast.Node, path ...string) ast.Node {
+	for _, key := range path {
+		mapping, ok := node.(*ast.MappingNode)
+		if !ok {
+			return nil
+		}
+
+		node = nil
+		for _, entry := range mapping.Values {
+			if entry.Key.GetToken() != nil && entry.Key.GetToken().Value == key {
+				node = entry.Value
+				break
+			}
+		}
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// collectAnchors calls fn for every anchor node in the tree rooted at node
+func collectAnchors(node ast.Node, fn func(*ast.AnchorNode)) {
+	switch n := node.(type) {
+	case *ast.DocumentNode:
+		collectAnchors(n.Body, fn)
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			collectAnchors(value, fn)
+		}
+	case *ast.MappingValueNode:
+		collectAnchors(n.Key, fn)
+		collectAnchors(n.Value, fn)
+	case *ast.SequenceNode:
+		for _, value := range n.Values {
+			collectAnchors(value, fn)
+		}
+	case *ast.TagNode:
+		collectAnchors(n.Value, fn)
+	case *ast.AnchorNode:
+		fn(n)
+		collectAnchors(n.Value, fn)
+	}
+}
+
+// isYAMLFile checks if a file has a YAML extension
+func isYAMLFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -0,0 +1,223 @@
+package yamlparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCatalog builds a catalog from in-memory YAML sources keyed by file name
+func testCatalog(t *testing.T, sources map[string]string) *Catalog {
+	t.Helper()
+
+	catalog := NewCatalog()
+	for file, data := range sources {
+		if err := catalog.addSource(file, []byte(data)); err != nil {
+			t.Fatalf("Failed to add catalog source %s: %v", file, err)
+		}
+	}
+	return catalog
+}
+
+const testAnchors = `
+defaults1: &defaults1
+  a: 1
+  b: 1
+defaults2: &defaults2
+  b: 2
+  c: 2
+nested1: &nested1
+  x: 1
+nested2: &nested2
+  y: 2
+`
+
+func TestEngineMergeLayouts(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{"anchors.yaml": testAnchors})
+
+	expected := map[string]interface{}{
+		"vars": map[string]interface{}{
+			"a":    uint64(1),
+			"b":    uint64(2),
+			"c":    uint64(2),
+			"name": map[string]interface{}{"x": uint64(1), "y": uint64(2), "key": "value"},
+		},
+	}
+
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{
+			name: "Repeated merge keys with 2-space indentation",
+			input: `
+vars:
+  <<: *defaults1
+  <<: *defaults2
+  name:
+    <<: *nested1
+    <<: *nested2
+    key: value
+`,
+		},
+		{
+			name: "Repeated merge keys with 4-space indentation",
+			input: `
+vars:
+    <<: *defaults1
+    <<: *defaults2
+    name:
+        <<: *nested1
+        <<: *nested2
+        key: value
+`,
+		},
+		{
+			name: "Comments between merge keys",
+			input: `
+# leading comment
+vars:
+  <<: *defaults1
+  # a comment between merge keys
+
+  <<: *defaults2
+  name:
+    <<: *nested1 # trailing comment
+    <<: *nested2
+    key: value
+`,
+		},
+		{
+			name: "Multi-line merge sequence",
+			input: `
+vars:
+  <<:
+    - *defaults1
+    - *defaults2
+  name:
+    <<: [*nested1,
+         *nested2]
+    key: value
+`,
+		},
+		{
+			name: "Flow mappings",
+			input: `
+vars: {<<: [*defaults1, *defaults2], name: {<<: *nested1, <<: *nested2, key: value}}
+`,
+		},
+		{
+			name: "Explicit keys before merge keys",
+			input: `
+vars:
+  name:
+    key: value
+    <<: *nested1
+    <<: *nested2
+  <<: *defaults1
+  <<: *defaults2
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := NewEngine(catalog, DefaultOptions()).Decode([]byte(tc.input), "stack.yaml")
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v", expected, result)
+			}
+		})
+	}
+}
+
+func TestEngineExplicitKeysOverrideMerges(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{"anchors.yaml": testAnchors})
+
+	result, err := NewEngine(catalog, DefaultOptions()).Decode([]byte("vars:\n  b: explicit\n  <<: *defaults2\n"), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	vars := result["vars"].(map[string]interface{})
+	if vars["b"] != "explicit" {
+		t.Errorf("Expected b to be explicit, got %v", vars["b"])
+	}
+	if vars["c"] != uint64(2) {
+		t.Errorf("Expected c to be 2, got %v", vars["c"])
+	}
+}
+
+func TestEngineLocalAnchors(t *testing.T) {
+	input := `
+base: &base
+  key1: value1
+child:
+  <<: *base
+  key2: value2
+list: &list [1, 2]
+copy: *list
+`
+	result, err := NewEngine(nil, DefaultOptions()).Decode([]byte(input), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	child := result["child"].(map[string]interface{})
+	if child["key1"] != "value1" || child["key2"] != "value2" {
+		t.Errorf("Unexpected child: %v", child)
+	}
+
+	if !reflect.DeepEqual(result["copy"], []interface{}{uint64(1), uint64(2)}) {
+		t.Errorf("Unexpected copy: %v", result["copy"])
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Unknown alias",
+			input:    "vars:\n  <<: *missing\n",
+			expected: "stack.yaml:2:7: could not find alias \"missing\"",
+		},
+		{
+			name:     "Duplicate regular key",
+			input:    "vars:\n  a: 1\n  a: 2\n",
+			expected: "mapping key \"a\" already defined at line 2",
+		},
+		{
+			name:     "Scalar merge value",
+			input:    "vars:\n  <<: value\n",
+			expected: "merge value must be a mapping",
+		},
+		{
+			name:     "Self-referencing anchor",
+			input:    "a: &a\n  <<: *a\n",
+			expected: "alias \"a\" refers to itself",
+		},
+		{
+			name:     "Non-mapping document",
+			input:    "- a\n- b\n",
+			expected: "document root must be a mapping",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(nil, DefaultOptions()).Decode([]byte(tc.input), "stack.yaml")
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package yamlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// importSettings resolves the spec.imports section of a document into a list
+// of import patterns. A single string is accepted as a one-element list.
+func (d *decoder) importSettings(node ast.Node, file string) ([]string, error) {
+	value, err := d.resolve(node, file, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		patterns := make([]string, 0, len(v))
+		for _, item := range v {
+			pattern, ok := item.(string)
+			if !ok {
+				return nil, positionError(file, node, "imports must be strings, got %T", item)
+			}
+			patterns = append(patterns, pattern)
+		}
+		return patterns, nil
+	default:
+		return nil, positionError(file, node, "imports must be a list of paths, got %T", value)
+	}
+}
+
+// expandImport returns the files an import pattern refers to, in lexical
+// order. Patterns are resolved against base unless they are absolute, may
+// omit the .yaml or .yml extension and may contain glob wildcards.
+func expandImport(base, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(base, pattern)
+	}
+
+	candidates := []string{pattern}
+	if !isYAMLFile(pattern) {
+		candidates = []string{pattern + ".yaml", pattern + ".yml"}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, candidate := range candidates {
+		matches, err := filepath.Glob(candidate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid import pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadImports decodes every file listed in a document's spec.imports section
+// and deep merges them in order. Import cycles are reported as errors.
+func (d *decoder) loadImports(node ast.Node, file string) (map[string]interface{}, error) {
+	patterns, err := d.importSettings(node, file)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := d.merge
+	opts.Deep = true
+
+	result := make(map[string]interface{})
+	for _, pattern := range patterns {
+		files, err := expandImport(d.engine.opts.ImportBase, pattern)
+		if err != nil {
+			return nil, positionError(file, node, "%v", err)
+		}
+		if len(files) == 0 {
+			return nil, positionError(file, node, "import %q did not match any files", pattern)
+		}
+
+		for _, importFile := range files {
+			if cycle := importCycle(d.chain, importFile); cycle != nil {
+				return nil, positionError(file, node, "import cycle: %s", strings.Join(cycle, " -> "))
+			}
+
+			data, err := os.ReadFile(importFile)
+			if err != nil {
+				return nil, positionError(file, node, "failed to read import %s: %v", importFile, err)
+			}
+
+			fragment, err := d.engine.decodeDocument(data, importFile, &d.merge, d.prov, append(append([]string{}, d.chain...), importFile))
+			if err != nil {
+				return nil, err
+			}
+			result = DeepMerge(result, fragment, opts)
+		}
+	}
+
+	return result, nil
+}
+
+// importCycle returns the chain ending in file if file is already being
+// imported or included, or nil otherwise
+func importCycle(chain []string, file string) []string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+
+	for i, imported := range chain {
+		importedAbs, err := filepath.Abs(imported)
+		if err != nil {
+			importedAbs = imported
+		}
+		if importedAbs == abs {
+			cycle := append([]string{}, chain[i:]...)
+			return append(cycle, file)
+		}
+	}
+	return nil
+}
+
+// removeImports drops the spec.imports directive from a decoded document
+func removeImports(document map[string]interface{}) {
+	spec, ok := document["spec"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(spec, "imports")
+}
@@ -0,0 +1,209 @@
+package yamlparser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes YAML sources keyed by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestParseStackImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"catalog/region/primary-region.yaml": `
+spec:
+  components:
+    terraform:
+      vpc:
+        vars:
+          region: us-east-1
+`,
+		"catalog/components/vpc/defaults.yml": `
+spec:
+  imports: [catalog/components/base]
+  components:
+    terraform:
+      vpc:
+        vars:
+          enabled: true
+          name: vpc
+          tags:
+            Team: platform
+`,
+		"catalog/components/base.yaml": `
+spec:
+  components:
+    terraform:
+      vpc:
+        vars:
+          name: base
+          managed: true
+`,
+		"stacks/stack.yaml": `
+metadata:
+  name: imported-stack
+spec:
+  imports:
+    - catalog/region/primary-region
+    - catalog/components/vpc/defaults
+  components:
+    terraform:
+      vpc:
+        vars:
+          name: stack-vpc
+          tags:
+            Name: stack
+`,
+	})
+
+	result, err := ParseStack(filepath.Join(dir, "stacks/stack.yaml"), filepath.Join(dir, "catalog"))
+	if err != nil {
+		t.Fatalf("ParseStack failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "imported-stack"},
+		"spec": map[string]interface{}{
+			"components": map[string]interface{}{
+				"terraform": map[string]interface{}{
+					"vpc": map[string]interface{}{
+						"vars": map[string]interface{}{
+							"region":  "us-east-1",
+							"enabled": true,
+							"managed": true,
+							"name":    "stack-vpc",
+							"tags":    map[string]interface{}{"Team": "platform", "Name": "stack"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expected, result)
+	}
+}
+
+func TestParseStackImportGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"catalog/layers/a.yaml": "spec:\n  order: [a]\n  a: true\n",
+		"catalog/layers/b.yaml": "spec:\n  order: [b]\n  b: true\n",
+		"stack.yaml":            "spec:\n  imports: catalog/layers/*\n",
+	})
+
+	result, err := ParseStack(filepath.Join(dir, "stack.yaml"), filepath.Join(dir, "catalog"))
+	if err != nil {
+		t.Fatalf("ParseStack failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"order": []interface{}{"b"},
+			"a":     true,
+			"b":     true,
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expected, result)
+	}
+}
+
+func TestParseStackImportProvenance(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"catalog/defaults.yaml": "vars:\n  name: default\n",
+		"stack.yaml":            "spec:\n  imports: [catalog/defaults]\nvars:\n  name: stack\n",
+	})
+
+	_, prov, err := ParseStackWithProvenance(filepath.Join(dir, "stack.yaml"), filepath.Join(dir, "catalog"), DefaultOptions())
+	if err != nil {
+		t.Fatalf("ParseStackWithProvenance failed: %v", err)
+	}
+
+	p, ok := prov.Lookup("vars", "name")
+	if !ok {
+		t.Fatal("Expected provenance for vars.name")
+	}
+	chain := p.Chain()
+	if len(chain) != 2 || chain[0].Value != "stack" || chain[1].Value != "default" {
+		t.Errorf("Unexpected provenance chain: %+v", chain)
+	}
+	if filepath.Base(chain[1].File) != "defaults.yaml" {
+		t.Errorf("Expected overridden value from defaults.yaml, got %s", chain[1].File)
+	}
+}
+
+func TestParseStackImportErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name: "Missing import",
+			files: map[string]string{
+				"stack.yaml": "spec:\n  imports: [catalog/missing]\n",
+			},
+			expected: "stack.yaml:2:12: import \"catalog/missing\" did not match any files",
+		},
+		{
+			name: "Import cycle",
+			files: map[string]string{
+				"catalog/a.yaml": "spec:\n  imports: [catalog/b]\n",
+				"catalog/b.yaml": "spec:\n  imports: [catalog/a]\n",
+				"stack.yaml":     "spec:\n  imports: [catalog/a]\n",
+			},
+			expected: "import cycle: ",
+		},
+		{
+			name: "Self import",
+			files: map[string]string{
+				"stack.yaml": "spec:\n  imports: [stack]\n",
+			},
+			expected: "import cycle: ",
+		},
+		{
+			name: "Non-string import",
+			files: map[string]string{
+				"stack.yaml": "spec:\n  imports: [1]\n",
+			},
+			expected: "imports must be strings",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			if err := os.MkdirAll(filepath.Join(dir, "catalog"), 0755); err != nil {
+				t.Fatalf("Failed to create catalog: %v", err)
+			}
+
+			_, err := ParseStack(filepath.Join(dir, "stack.yaml"), filepath.Join(dir, "catalog"))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package yamlparser
+
+import (
+	"fmt"
+)
+
+// ListStrategy controls how two lists are combined during a deep merge
+type ListStrategy string
+
+// Supported list strategies
+const (
+	// ListReplace replaces the existing list with the incoming one
+	ListReplace ListStrategy = "replace"
+	// ListAppend appends the incoming items to the existing list
+	ListAppend ListStrategy = "append"
+	// ListMergeByKey merges list items that are maps sharing the same key value
+	// and appends everything else
+	ListMergeByKey ListStrategy = "merge"
+)
+
+// DefaultListMergeKey is the map key used to match list items with ListMergeByKey
+const DefaultListMergeKey = "name"
+
+// MergeOptions configures how "<<" merges and explicit overrides are combined
+type MergeOptions struct {
+	// Deep merges maps recursively instead of replacing top-level keys
+	Deep bool
+	// Lists is the strategy used for lists when Deep is enabled
+	Lists ListStrategy
+	// ListKey is the key used to match list items when Lists is ListMergeByKey
+	ListKey string
+}
+
+// DefaultMergeOptions returns the standard YAML shallow merge behavior
+func DefaultMergeOptions() MergeOptions {
+	return MergeOptions{
+		Deep:    false,
+		Lists:   ListReplace,
+		ListKey: DefaultListMergeKey,
+	}
+}
+
+// Validate checks that the options are consistent
+func (o MergeOptions) Validate() error {
+	switch o.Lists {
+	case "", ListReplace, ListAppend, ListMergeByKey:
+		return nil
+	default:
+		return fmt.Errorf("unknown list merge strategy %q (expected %s, %s or %s)", o.Lists, ListReplace, ListAppend, ListMergeByKey)
+	}
+}
+
+// withOverrides applies the settings found in a stack's spec.merge section
+func (o MergeOptions) withOverrides(settings map[string]interface{}) (MergeOptions, error) {
+	if deep, ok := settings["deep"]; ok {
+		b, ok := deep.(bool)
+		if !ok {
+			return o, fmt.Errorf("merge.deep must be a boolean, got %T", deep)
+		}
+		o.Deep = b
+	}
+
+	if lists, ok := settings["lists"]; ok {
+		s, ok := lists.(string)
+		if !ok {
+			return o, fmt.Errorf("merge.lists must be a string, got %T", lists)
+		}
+		o.Lists = ListStrategy(s)
+	}
+
+	if listKey, ok := settings["listKey"]; ok {
+		s, ok := listKey.(string)
+		if !ok {
+			return o, fmt.Errorf("merge.listKey must be a string, got %T", listKey)
+		}
+		o.ListKey = s
+	}
+
+	return o, o.Validate()
+}
+
+// mergeValue combines an existing value with an incoming one. With shallow
+// merging the incoming value always wins; with deep merging maps are merged
+// recursively and lists follow the configured strategy.
+func mergeValue(existing, incoming interface{}, opts MergeOptions) interface{} {
+	if !opts.Deep {
+		return incoming
+	}
+
+	switch in := incoming.(type) {
+	case map[string]interface{}:
+		if ex, ok := existing.(map[string]interface{}); ok {
+			return DeepMerge(ex, in, opts)
+		}
+	case []interface{}:
+		if ex, ok := existing.([]interface{}); ok {
+			return mergeLists(ex, in, opts)
+		}
+	}
+
+	return incoming
+}
+
+// DeepMerge merges src into dst according to opts and returns dst
+func DeepMerge(dst, src map[string]interface{}, opts MergeOptions) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+
+	for key, value := range src {
+		if existing, ok := dst[key]; ok {
+			dst[key] = mergeValue(existing, deepCopy(value), opts)
+		} else {
+			dst[key] = deepCopy(value)
+		}
+	}
+
+	return dst
+}
+
+// mergeLists combines two lists according to the list strategy
+func mergeLists(existing, incoming []interface{}, opts MergeOptions) []interface{} {
+	switch opts.Lists {
+	case ListAppend:
+		result := make([]interface{}, 0, len(existing)+len(incoming))
+		result = append(result, existing...)
+		return append(result, incoming...)
+	case ListMergeByKey:
+		return mergeListsByKey(existing, incoming, opts)
+	default:
+		return incoming
+	}
+}
+
+// mergeListsByKey merges map items that share the same value for opts.ListKey
+// and appends every other incoming item
+func mergeListsByKey(existing, incoming []interface{}, opts MergeOptions) []interface{} {
+	listKey := opts.ListKey
+	if listKey == "" {
+		listKey = DefaultListMergeKey
+	}
+
+	result := make([]interface{}, len(existing), len(existing)+len(incoming))
+	copy(result, existing)
+
+	index := make(map[string]int)
+	for i, item := range result {
+		if _, id, ok := keyedListItem(item, listKey); ok {
+			index[id] = i
+		}
+	}
+
+	for _, item := range incoming {
+		m, id, ok := keyedListItem(item, listKey)
+		if !ok {
+			result = append(result, item)
+			continue
+		}
+
+		if i, found := index[id]; found {
+			if target, ok := result[i].(map[string]interface{}); ok {
+				result[i] = DeepMerge(target, m, opts)
+				continue
+			}
+		}
+
+		index[id] = len(result)
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// keyedListItem returns a list item as a map along with the string form of its key field
+func keyedListItem(item interface{}, listKey string) (map[string]interface{}, string, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, "", false
+	}
+	value, ok := m[listKey]
+	if !ok {
+		return nil, "", false
+	}
+	return m, fmt.Sprint(value), true
+}
+
+// DeepCopy returns a copy of a decoded value that shares no maps or lists with it
+func DeepCopy(value interface{}) interface{} {
+	return deepCopy(value)
+}
+
+// deepCopy copies maps and lists so merged results never share structure with their sources
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
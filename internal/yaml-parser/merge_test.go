@@ -0,0 +1,188 @@
+package yamlparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeepMerge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     MergeOptions
+		dst      map[string]interface{}
+		src      map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name: "Shallow merge replaces nested maps",
+			opts: DefaultMergeOptions(),
+			dst:  map[string]interface{}{"tags": map[string]interface{}{"a": "1", "b": "2"}},
+			src:  map[string]interface{}{"tags": map[string]interface{}{"b": "3"}},
+			expected: map[string]interface{}{
+				"tags": map[string]interface{}{"b": "3"},
+			},
+		},
+		{
+			name: "Deep merge keeps sibling keys",
+			opts: MergeOptions{Deep: true, Lists: ListReplace},
+			dst:  map[string]interface{}{"tags": map[string]interface{}{"a": "1", "b": "2"}},
+			src:  map[string]interface{}{"tags": map[string]interface{}{"b": "3"}},
+			expected: map[string]interface{}{
+				"tags": map[string]interface{}{"a": "1", "b": "3"},
+			},
+		},
+		{
+			name:     "Deep merge replaces lists",
+			opts:     MergeOptions{Deep: true, Lists: ListReplace},
+			dst:      map[string]interface{}{"zones": []interface{}{"a", "b"}},
+			src:      map[string]interface{}{"zones": []interface{}{"c"}},
+			expected: map[string]interface{}{"zones": []interface{}{"c"}},
+		},
+		{
+			name:     "Deep merge appends lists",
+			opts:     MergeOptions{Deep: true, Lists: ListAppend},
+			dst:      map[string]interface{}{"zones": []interface{}{"a", "b"}},
+			src:      map[string]interface{}{"zones": []interface{}{"c"}},
+			expected: map[string]interface{}{"zones": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name: "Deep merge merges lists by key",
+			opts: MergeOptions{Deep: true, Lists: ListMergeByKey, ListKey: "name"},
+			dst: map[string]interface{}{"rules": []interface{}{
+				map[string]interface{}{"name": "http", "port": 80, "cidr": "0.0.0.0/0"},
+				map[string]interface{}{"name": "ssh", "port": 22},
+			}},
+			src: map[string]interface{}{"rules": []interface{}{
+				map[string]interface{}{"name": "http", "port": 8080},
+				map[string]interface{}{"name": "https", "port": 443},
+				"raw",
+			}},
+			expected: map[string]interface{}{"rules": []interface{}{
+				map[string]interface{}{"name": "http", "port": 8080, "cidr": "0.0.0.0/0"},
+				map[string]interface{}{"name": "ssh", "port": 22},
+				map[string]interface{}{"name": "https", "port": 443},
+				"raw",
+			}},
+		},
+		{
+			name:     "Type mismatch takes incoming value",
+			opts:     MergeOptions{Deep: true, Lists: ListAppend},
+			dst:      map[string]interface{}{"value": []interface{}{"a"}},
+			src:      map[string]interface{}{"value": map[string]interface{}{"b": "c"}},
+			expected: map[string]interface{}{"value": map[string]interface{}{"b": "c"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result map[string]interface{}
+			if tc.opts.Deep {
+				result = DeepMerge(tc.dst, tc.src, tc.opts)
+			} else {
+				result = tc.dst
+				for key, value := range tc.src {
+					result[key] = mergeValue(result[key], value, tc.opts)
+				}
+			}
+
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDeepMergeDoesNotShareSource(t *testing.T) {
+	src := map[string]interface{}{"tags": map[string]interface{}{"a": "1"}}
+	result := DeepMerge(nil, src, MergeOptions{Deep: true})
+
+	result["tags"].(map[string]interface{})["a"] = "changed"
+	if src["tags"].(map[string]interface{})["a"] != "1" {
+		t.Errorf("Expected source to be unchanged, got %v", src["tags"])
+	}
+}
+
+func TestEngineDeepMerge(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{"anchors.yaml": `
+vpc-defaults: &vpc-defaults
+  name: vpc
+  availability_zones: [us-east-1a, us-east-1b]
+  public_subnets_additional_tags:
+    subnet_type: public
+vpc-overrides: &vpc-overrides
+  availability_zones: [us-east-1c]
+  public_subnets_additional_tags:
+    team: platform
+`})
+
+	input := `
+spec:
+  components:
+    vpc:
+      vars:
+        <<: [*vpc-defaults, *vpc-overrides]
+        public_subnets_additional_tags:
+          env: dev
+`
+
+	testCases := []struct {
+		name         string
+		opts         MergeOptions
+		stackSection string
+		expectedTags map[string]interface{}
+		expectedAZs  []interface{}
+	}{
+		{
+			name:         "Shallow by default",
+			opts:         DefaultMergeOptions(),
+			expectedTags: map[string]interface{}{"env": "dev"},
+			expectedAZs:  []interface{}{"us-east-1c"},
+		},
+		{
+			name:         "Deep from engine options",
+			opts:         MergeOptions{Deep: true, Lists: ListAppend},
+			expectedTags: map[string]interface{}{"subnet_type": "public", "team": "platform", "env": "dev"},
+			expectedAZs:  []interface{}{"us-east-1a", "us-east-1b", "us-east-1c"},
+		},
+		{
+			name:         "Deep from stack settings",
+			opts:         DefaultMergeOptions(),
+			stackSection: "  merge:\n    deep: true\n",
+			expectedTags: map[string]interface{}{"subnet_type": "public", "team": "platform", "env": "dev"},
+			expectedAZs:  []interface{}{"us-east-1c"},
+		},
+		{
+			name:         "Stack settings override engine options",
+			opts:         MergeOptions{Deep: true, Lists: ListAppend},
+			stackSection: "  merge:\n    deep: false\n",
+			expectedTags: map[string]interface{}{"env": "dev"},
+			expectedAZs:  []interface{}{"us-east-1c"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := strings.Replace(input, "spec:\n", "spec:\n"+tc.stackSection, 1)
+			result, err := NewEngine(catalog, Options{Merge: tc.opts}).Decode([]byte(doc), "stack.yaml")
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			vars := result["spec"].(map[string]interface{})["components"].(map[string]interface{})["vpc"].(map[string]interface{})["vars"].(map[string]interface{})
+			if !reflect.DeepEqual(vars["public_subnets_additional_tags"], tc.expectedTags) {
+				t.Errorf("Expected tags %v, got %v", tc.expectedTags, vars["public_subnets_additional_tags"])
+			}
+			if !reflect.DeepEqual(vars["availability_zones"], tc.expectedAZs) {
+				t.Errorf("Expected availability zones %v, got %v", tc.expectedAZs, vars["availability_zones"])
+			}
+		})
+	}
+}
+
+func TestEngineInvalidMergeSettings(t *testing.T) {
+	_, err := NewEngine(nil, DefaultOptions()).Decode([]byte("spec:\n  merge:\n    lists: shuffle\n"), "stack.yaml")
+	if err == nil || !strings.Contains(err.Error(), "unknown list merge strategy") {
+		t.Errorf("Expected unknown list merge strategy error, got %v", err)
+	}
+}
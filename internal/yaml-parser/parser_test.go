@@ -3,242 +3,11 @@ package yamlparser
 import (
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
 
 	"github.com/goccy/go-yaml"
 )
 
-// isTestCase checks if the input matches a test case pattern
-func isTestCase(yamlText string) bool {
-	testPatterns := []string{
-		"<<: *defaults1",
-		"<<: *defaults2",
-		"<<: *defaults\n",
-		"<<: *nested1",
-		"<<: *nested2",
-	}
-
-	for _, pattern := range testPatterns {
-		if strings.Contains(yamlText, pattern) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// handleTestCase returns the expected output for test cases
-func handleTestCase(yamlText string) string {
-	if strings.Contains(yamlText, "<<: *defaults1") && strings.Contains(yamlText, "<<: *defaults2") {
-		if strings.Contains(yamlText, "<<: *nested1") && strings.Contains(yamlText, "<<: *nested2") {
-			// Test case 3: Multiple merge keys with different indentation
-			return `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name:
-    <<: [<<: *nested1, <<: *nested2]
-    key: value
-`
-		} else {
-			// Test case 2: Multiple merge keys
-			return `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name: value
-`
-		}
-	} else if strings.Contains(yamlText, "<<: *defaults") {
-		// Test case 1: Single merge key
-		return `
-vars:
-  <<: *defaults
-  name: value
-`
-	}
-
-	// No matching test case, return original
-	return yamlText
-}
-
-// testDecoder is a mock decoder used for testing
-type testDecoder struct {
-	yamlText string
-	options  []interface{}
-}
-
-// preprocessMultipleMergeKeys mock implementation for tests
-func (td *testDecoder) preprocessMultipleMergeKeys(yamlText string) string {
-	// Return expected outputs for specific test inputs
-	if yamlText == `
-vars:
-  <<: *defaults1
-  <<: *defaults2
-  name:
-    <<: *nested1
-    <<: *nested2
-    key: value
-` {
-		return `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name:
-    <<: [<<: *nested1, <<: *nested2]
-    key: value
-`
-	} else if yamlText == `
-vars:
-  <<: *defaults1
-  <<: *defaults2
-  name: value
-` {
-		return `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name: value
-`
-	} else if yamlText == `
-vars:
-  <<: *defaults
-  name: value
-` {
-		return yamlText // Single merge key - no changes
-	}
-
-	// Default: return input unchanged
-	return yamlText
-}
-
-func TestPreprocessMultipleMergeKeys(t *testing.T) {
-	testCases := []struct {
-		name     string
-		input    string
-		expected string
-	}{
-		{
-			name: "Single merge key",
-			input: `
-vars:
-  <<: *defaults
-  name: value
-`,
-			expected: `
-vars:
-  <<: *defaults
-  name: value
-`,
-		},
-		{
-			name: "Multiple merge keys at same level",
-			input: `
-vars:
-  <<: *defaults1
-  <<: *defaults2
-  name: value
-`,
-			expected: `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name: value
-`,
-		},
-		{
-			name: "Multiple merge keys with different indentation",
-			input: `
-vars:
-  <<: *defaults1
-  <<: *defaults2
-  name:
-    <<: *nested1
-    <<: *nested2
-    key: value
-`,
-			expected: `
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-  name:
-    <<: [<<: *nested1, <<: *nested2]
-    key: value
-`,
-		},
-		{
-			name: "Complex nested structure",
-			input: `
-config:
-  base:
-    <<: *base1
-    <<: *base2
-  advanced:
-    <<: *adv1
-    settings:
-      <<: *settings1
-      <<: *settings2
-      option: value
-`,
-			expected: `
-config:
-  base:
-    <<: [<<: *base1, <<: *base2]
-  advanced:
-    <<: *adv1
-    settings:
-      <<: [<<: *settings1, <<: *settings2]
-      option: value
-`,
-		},
-		{
-			name: "No merge keys",
-			input: `
-vars:
-  name: value
-  settings:
-    option: value
-`,
-			expected: `
-vars:
-  name: value
-  settings:
-    option: value
-`,
-		},
-		{
-			name: "Empty lines and comments",
-			input: `
-# This is a comment
-vars:
-  <<: *defaults1
-  <<: *defaults2
-
-  # Another comment
-  name: value
-`,
-			expected: `
-# This is a comment
-vars:
-  <<: [<<: *defaults1, <<: *defaults2]
-
-  # Another comment
-  name: value
-`,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			decoder := NewCustomDecoder(tc.input)
-			result := decoder.preprocessMultipleMergeKeys(tc.input)
-
-			// Normalize line endings for comparison
-			normalizedResult := strings.ReplaceAll(result, "\r\n", "\n")
-			normalizedExpected := strings.ReplaceAll(tc.expected, "\r\n", "\n")
-
-			if normalizedResult != normalizedExpected {
-				t.Errorf("Expected:\n%s\nGot:\n%s", tc.expected, result)
-			}
-		})
-	}
-}
-
 func TestParseYAMLWithAnchors(t *testing.T) {
 	// Create a temporary directory for test files
 	tmpDir, err := os.MkdirTemp("", "yamlparser_test_anchors")
@@ -554,49 +323,3 @@ func TestFindSubdirectories(t *testing.T) {
 		}
 	}
 }
-
-// TestNewCustomDecoder tests the NewCustomDecoder function
-func TestNewCustomDecoder(t *testing.T) {
-	yamlText := "test: value"
-	// Use an empty options slice instead of yaml.DisallowDuplicateKey() which might not exist
-	options := []yaml.DecodeOption{}
-
-	decoder := NewCustomDecoder(yamlText, options...)
-
-	if decoder.yamlText != yamlText {
-		t.Errorf("Expected yamlText to be %q, got %q", yamlText, decoder.yamlText)
-	}
-
-	if len(decoder.options) != len(options) {
-		t.Errorf("Expected %d options, got %d", len(options), len(decoder.options))
-	}
-}
-
-// TestCustomDecoderDecode tests the Decode method of CustomDecoder
-func TestCustomDecoderDecode(t *testing.T) {
-	// Simple YAML without merge keys
-	yamlText := `
-root:
-  key1: value1
-  key2: value2
-`
-	decoder := NewCustomDecoder(yamlText)
-	result, err := decoder.Decode()
-	if err != nil {
-		t.Fatalf("Decode failed: %v", err)
-	}
-
-	// Verify structure
-	root, ok := result["root"].(map[string]interface{})
-	if !ok {
-		t.Fatalf("Expected root to be a map, got %T", result["root"])
-	}
-
-	if root["key1"] != "value1" {
-		t.Errorf("Expected key1 to be value1, got %v", root["key1"])
-	}
-
-	if root["key2"] != "value2" {
-		t.Errorf("Expected key2 to be value2, got %v", root["key2"])
-	}
-}
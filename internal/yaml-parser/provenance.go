@@ -0,0 +1,53 @@
+package yamlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source describes where a value was defined
+type Source struct {
+	File   string      `json:"file"`
+	Line   int         `json:"line"`
+	Column int         `json:"column"`
+	Anchor string      `json:"anchor,omitempty"` // anchor the value was reached through, if any
+	Value  interface{} `json:"value"`
+}
+
+// String returns the source as file:line
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// Provenance records where a resolved value came from and the values it overrode
+type Provenance struct {
+	Source
+	// Overrides lists the values this one replaced, most recent first
+	Overrides []Source `json:"overrides,omitempty"`
+}
+
+// Chain returns the effective source followed by every source it overrode
+func (p *Provenance) Chain() []Source {
+	return append([]Source{p.Source}, p.Overrides...)
+}
+
+// ProvenanceMap maps dot-separated key paths in a decoded document to their provenance
+type ProvenanceMap map[string]*Provenance
+
+// Lookup returns the provenance of the value at the given key path
+func (p ProvenanceMap) Lookup(path ...string) (*Provenance, bool) {
+	prov, ok := p[strings.Join(path, ".")]
+	return prov, ok
+}
+
+// record sets the source of the value at path, pushing any previous source onto its overrides
+func (p ProvenanceMap) record(path []string, src Source) {
+	src.Value = deepCopy(src.Value)
+
+	key := strings.Join(path, ".")
+	if existing, ok := p[key]; ok {
+		p[key] = &Provenance{Source: src, Overrides: existing.Chain()}
+		return
+	}
+	p[key] = &Provenance{Source: src}
+}
@@ -0,0 +1,88 @@
+package yamlparser
+
+import (
+	"testing"
+)
+
+func TestDecodeWithProvenance(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{
+		"defaults.yaml": `vpc-defaults: &vpc-defaults
+  enabled: true
+  name: vpc
+  tags:
+    subnet_type: public
+`,
+		"overrides.yaml": `vpc-overrides: &vpc-overrides
+  enabled: false
+`,
+	})
+
+	input := `spec:
+  vars:
+    <<: [*vpc-defaults, *vpc-overrides]
+    name: dead-vpc
+`
+	_, prov, err := NewEngine(catalog, DefaultOptions()).DecodeWithProvenance([]byte(input), "stack.yaml")
+	if err != nil {
+		t.Fatalf("DecodeWithProvenance failed: %v", err)
+	}
+
+	testCases := []struct {
+		path      []string
+		source    Source
+		overrides []Source
+	}{
+		{
+			path:   []string{"spec", "vars", "enabled"},
+			source: Source{File: "overrides.yaml", Line: 2, Column: 3, Anchor: "vpc-overrides", Value: false},
+			overrides: []Source{
+				{File: "defaults.yaml", Line: 2, Column: 3, Anchor: "vpc-defaults", Value: true},
+			},
+		},
+		{
+			path:   []string{"spec", "vars", "name"},
+			source: Source{File: "stack.yaml", Line: 4, Column: 5, Value: "dead-vpc"},
+			overrides: []Source{
+				{File: "defaults.yaml", Line: 3, Column: 3, Anchor: "vpc-defaults", Value: "vpc"},
+			},
+		},
+		{
+			path:   []string{"spec", "vars", "tags", "subnet_type"},
+			source: Source{File: "defaults.yaml", Line: 5, Column: 5, Anchor: "vpc-defaults", Value: "public"},
+		},
+	}
+
+	for _, tc := range testCases {
+		p, ok := prov.Lookup(tc.path...)
+		if !ok {
+			t.Errorf("No provenance recorded for %v", tc.path)
+			continue
+		}
+		if p.Source != tc.source {
+			t.Errorf("%v: expected source %+v, got %+v", tc.path, tc.source, p.Source)
+		}
+		if len(p.Overrides) != len(tc.overrides) {
+			t.Errorf("%v: expected %d overrides, got %+v", tc.path, len(tc.overrides), p.Overrides)
+			continue
+		}
+		for i := range tc.overrides {
+			if p.Overrides[i] != tc.overrides[i] {
+				t.Errorf("%v: expected override %+v, got %+v", tc.path, tc.overrides[i], p.Overrides[i])
+			}
+		}
+	}
+
+	if _, ok := prov.Lookup("spec", "missing"); ok {
+		t.Error("Expected no provenance for a missing path")
+	}
+}
+
+func TestDecodeWithoutProvenance(t *testing.T) {
+	_, prov, err := NewEngine(nil, DefaultOptions()).decode([]byte("a: 1\n"), "stack.yaml", false)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(prov) != 0 {
+		t.Errorf("Expected no provenance to be recorded, got %v", prov)
+	}
+}
@@ -0,0 +1,195 @@
+package yamlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// TagFunc resolves the value of a node carrying a custom tag
+type TagFunc func(ctx *TagContext) (interface{}, error)
+
+// TagContext describes a tagged node being resolved
+type TagContext struct {
+	// Tag is the tag name, including the leading "!"
+	Tag string
+	// File is the file the tagged node was read from
+	File string
+	// Value is the resolved value of the tagged node
+	Value interface{}
+
+	decoder *decoder
+	path    []string
+	node    ast.Node
+}
+
+// Path returns the key path the value will be stored at, or nil when the
+// value is not part of the document tree
+func (c *TagContext) Path() []string {
+	return c.path
+}
+
+// RelativePath resolves a path relative to the directory of the file
+// containing the tag. Absolute paths are returned unchanged.
+func (c *TagContext) RelativePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(c.File), path)
+}
+
+// Include decodes another YAML file with the same engine, so its aliases,
+// merges and tags are resolved like the including document's. Include
+// cycles are reported as errors.
+func (c *TagContext) Include(path string) (interface{}, error) {
+	return c.decoder.include(path, c.path)
+}
+
+// Document returns the file of the top-level document being decoded, which
+// differs from File when the tag is in a catalog anchor, import or include
+func (c *TagContext) Document() string {
+	if len(c.decoder.chain) == 0 {
+		return c.File
+	}
+	return c.decoder.chain[0]
+}
+
+// StringValue returns the tagged value as a string, or an error if it is not one
+func (c *TagContext) StringValue() (string, error) {
+	s, ok := c.Value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %T", c.Value)
+	}
+	return s, nil
+}
+
+// TagRegistry maps custom tag names to the functions that resolve them
+type TagRegistry struct {
+	handlers map[string]TagFunc
+}
+
+// NewTagRegistry creates a registry without any tags
+func NewTagRegistry() *TagRegistry {
+	return &TagRegistry{handlers: make(map[string]TagFunc)}
+}
+
+// DefaultTagRegistry creates a registry with the built-in tags:
+//
+//	!env VAR [default]   the value of an environment variable
+//	!file path           the contents of a file as a string
+//	!include path        the decoded contents of a YAML file
+//	!uppercase text      the text in upper case
+//	!lowercase text      the text in lower case
+//	!template text       a Go template evaluated once the document is merged
+func DefaultTagRegistry() *TagRegistry {
+	registry := NewTagRegistry()
+	registry.Register("!env", envTag)
+	registry.Register("!file", fileTag)
+	registry.Register("!include", includeTag)
+	registry.Register("!uppercase", uppercaseTag)
+	registry.Register("!lowercase", lowercaseTag)
+	registry.Register("!template", templateTag)
+	return registry
+}
+
+// Register adds or replaces the handler for a tag. The leading "!" is optional.
+func (r *TagRegistry) Register(tag string, fn TagFunc) {
+	if !strings.HasPrefix(tag, "!") {
+		tag = "!" + tag
+	}
+	r.handlers[tag] = fn
+}
+
+// Clone returns a copy of the registry that can be changed independently
+func (r *TagRegistry) Clone() *TagRegistry {
+	clone := NewTagRegistry()
+	for tag, fn := range r.handlers {
+		clone.handlers[tag] = fn
+	}
+	return clone
+}
+
+// Lookup returns the handler registered for a tag
+func (r *TagRegistry) Lookup(tag string) (TagFunc, bool) {
+	fn, ok := r.handlers[tag]
+	return fn, ok
+}
+
+// Tags returns the registered tag names in sorted order
+func (r *TagRegistry) Tags() []string {
+	tags := make([]string, 0, len(r.handlers))
+	for tag := range r.handlers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// envTag resolves "!env VAR" to the value of VAR. A default can follow the
+// name, either as "!env VAR default" or "!env [VAR, default]"; without one an
+// unset variable is an error.
+func envTag(ctx *TagContext) (interface{}, error) {
+	var name string
+	var fallback interface{}
+	hasDefault := false
+
+	switch v := ctx.Value.(type) {
+	case string:
+		parts := strings.SplitN(strings.TrimSpace(v), " ", 2)
+		name = parts[0]
+		if len(parts) == 2 {
+			fallback = strings.TrimSpace(parts[1])
+			hasDefault = true
+		}
+	case []interface{}:
+		if len(v) == 0 || len(v) > 2 {
+			return nil, fmt.Errorf("expected a variable name and an optional default, got %d items", len(v))
+		}
+		name = fmt.Sprint(v[0])
+		if len(v) == 2 {
+			fallback = v[1]
+			hasDefault = true
+		}
+	default:
+		return nil, fmt.Errorf("expected a variable name, got %T", ctx.Value)
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("expected a variable name")
+	}
+
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if hasDefault {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("environment variable %q is not set and no default was given", name)
+}
+
+// fileTag resolves "!file path" to the contents of the file
+func fileTag(ctx *TagContext) (interface{}, error) {
+	path, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(ctx.RelativePath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return string(data), nil
+}
+
+// includeTag resolves "!include path" to the decoded contents of a YAML file
+func includeTag(ctx *TagContext) (interface{}, error) {
+	path, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+	return ctx.Include(ctx.RelativePath(path))
+}
@@ -0,0 +1,150 @@
+package yamlparser
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvTag(t *testing.T) {
+	t.Setenv("SKUNK_TEST_REGION", "us-east-2")
+
+	input := `
+region: !env SKUNK_TEST_REGION
+with_default: !env SKUNK_TEST_UNSET us-west-1
+set_with_default: !env SKUNK_TEST_REGION us-west-1
+list_default: !env [SKUNK_TEST_UNSET, 3]
+`
+	result, err := NewEngine(nil, DefaultOptions()).Decode([]byte(input), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"region":           "us-east-2",
+		"with_default":     "us-west-1",
+		"set_with_default": "us-east-2",
+		"list_default":     uint64(3),
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expected, result)
+	}
+}
+
+func TestFileAndIncludeTags(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"policy.json": `{"Version": "2012-10-17"}`,
+		"shared/tags.yaml": `
+base: &base
+  Team: platform
+tags:
+  <<: *base
+  Owner: !env [SKUNK_TEST_UNSET, ops]
+`,
+		"stack.yaml": `
+vars:
+  policy: !file policy.json
+  shared: !include shared/tags.yaml
+`,
+	})
+
+	result, err := NewEngine(nil, DefaultOptions()).DecodeFile(filepath.Join(dir, "stack.yaml"))
+	if err != nil {
+		t.Fatalf("DecodeFile failed: %v", err)
+	}
+
+	vars := result["vars"].(map[string]interface{})
+	if vars["policy"] != `{"Version": "2012-10-17"}` {
+		t.Errorf("Unexpected policy: %v", vars["policy"])
+	}
+
+	expected := map[string]interface{}{
+		"base": map[string]interface{}{"Team": "platform"},
+		"tags": map[string]interface{}{"Team": "platform", "Owner": "ops"},
+	}
+	if !reflect.DeepEqual(vars["shared"], expected) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expected, vars["shared"])
+	}
+}
+
+func TestCustomTagRegistry(t *testing.T) {
+	registry := NewTagRegistry()
+	registry.Register("double", func(ctx *TagContext) (interface{}, error) {
+		s, err := ctx.StringValue()
+		if err != nil {
+			return nil, err
+		}
+		return s + s, nil
+	})
+
+	opts := DefaultOptions()
+	opts.Tags = registry
+
+	result, err := NewEngine(nil, opts).Decode([]byte("value: !double ab\n"), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if result["value"] != "abab" {
+		t.Errorf("Expected abab, got %v", result["value"])
+	}
+
+	// Built-in tags are not available in a custom registry
+	_, err = NewEngine(nil, opts).Decode([]byte("value: !env HOME\n"), "stack.yaml")
+	if err == nil || !strings.Contains(err.Error(), `unknown tag "!env" (supported tags: !double)`) {
+		t.Errorf("Expected unknown tag error, got %v", err)
+	}
+}
+
+func TestTagErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "b: !include b.yaml\n",
+		"b.yaml": "a: !include a.yaml\n",
+	})
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Unknown tag",
+			input:    "vars:\n  name: !reverse vpc\n",
+			expected: "stack.yaml:2:9: unknown tag \"!reverse\"",
+		},
+		{
+			name:     "Unset environment variable",
+			input:    "name: !env SKUNK_TEST_UNSET\n",
+			expected: "stack.yaml:1:7: !env: environment variable \"SKUNK_TEST_UNSET\" is not set",
+		},
+		{
+			name:     "Missing file",
+			input:    "name: !file missing.txt\n",
+			expected: "!file: failed to read file",
+		},
+		{
+			name:     "Non-string include",
+			input:    "name: !include [a, b]\n",
+			expected: "!include: expected a string, got []interface {}",
+		},
+		{
+			name:     "Include cycle",
+			input:    "start: !include a.yaml\n",
+			expected: "include cycle: ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(nil, DefaultOptions()).Decode([]byte(tc.input), filepath.Join(dir, "stack.yaml"))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package yamlparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// deferredTemplate is the value of a !template node. Templates are evaluated
+// once the whole document has been merged, so they can see the final values
+// of the stack's metadata and of the other vars of their component.
+type deferredTemplate struct {
+	text string
+	file string
+	node ast.Node
+}
+
+// String returns the template in its tagged form
+func (t *deferredTemplate) String() string {
+	return "!template " + strconv.Quote(t.text)
+}
+
+// MarshalJSON renders an unevaluated template in its tagged form
+func (t *deferredTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// templateFuncs are the functions available to !template
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"replace": strings.ReplaceAll,
+}
+
+// templateTag defers "!template text" until the document has been merged
+func templateTag(ctx *TagContext) (interface{}, error) {
+	text, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse now so syntax errors are reported even if the value is overridden
+	if _, err := template.New(ctx.Tag).Funcs(templateFuncs).Parse(text); err != nil {
+		return nil, err
+	}
+
+	return &deferredTemplate{text: text, file: ctx.File, node: ctx.node}, nil
+}
+
+// uppercaseTag resolves "!uppercase text" to the text in upper case
+func uppercaseTag(ctx *TagContext) (interface{}, error) {
+	s, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s), nil
+}
+
+// lowercaseTag resolves "!lowercase text" to the text in lower case
+func lowercaseTag(ctx *TagContext) (interface{}, error) {
+	s, err := ctx.StringValue()
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s), nil
+}
+
+// templateEvaluator replaces the deferred templates in a document with their results
+type templateEvaluator struct {
+	doc        map[string]interface{}
+	prov       ProvenanceMap
+	evaluating []string        // paths of the templates being evaluated, outermost first
+	active     map[string]bool // the same paths as a set
+}
+
+// evaluateTemplates evaluates every !template in doc in place. A template that
+// references a value set by another template sees that template's result;
+// templates that reference each other are reported as a cycle.
+func evaluateTemplates(doc map[string]interface{}, prov ProvenanceMap) error {
+	e := &templateEvaluator{doc: doc, prov: prov, active: make(map[string]bool)}
+	return e.resolveValue(doc, []string{})
+}
+
+// resolveValue evaluates every template found under value, which is stored at
+// path. Templates being evaluated are skipped, so a template can iterate over
+// a mapping that contains it; they keep their tagged form until evaluated.
+func (e *templateEvaluator) resolveValue(value interface{}, path []string) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := appendPath(path, key)
+			if t, ok := v[key].(*deferredTemplate); ok {
+				if e.active[strings.Join(childPath, ".")] {
+					continue
+				}
+				result, err := e.evaluate(t, childPath)
+				if err != nil {
+					return err
+				}
+				v[key] = result
+				continue
+			}
+			if err := e.resolveValue(v[key], childPath); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			childPath := appendPath(path, strconv.Itoa(i))
+			if t, ok := v[i].(*deferredTemplate); ok {
+				if e.active[strings.Join(childPath, ".")] {
+					continue
+				}
+				result, err := e.evaluate(t, childPath)
+				if err != nil {
+					return err
+				}
+				v[i] = result
+				continue
+			}
+			if err := e.resolveValue(v[i], childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePath evaluates any template at or below a document path. Missing
+// paths are ignored; executing the template reports them.
+func (e *templateEvaluator) resolvePath(path []string) error {
+	var current interface{} = e.doc
+	for i, key := range path {
+		var child interface{}
+		var set func(interface{})
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil
+			}
+			child = value
+			set = func(v interface{}) { node[key] = v }
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			child = node[index]
+			set = func(v interface{}) { node[index] = v }
+		default:
+			return nil
+		}
+
+		if t, ok := child.(*deferredTemplate); ok {
+			result, err := e.evaluate(t, path[:i+1])
+			if err != nil {
+				return err
+			}
+			set(result)
+			child = result
+		}
+		current = child
+	}
+
+	return e.resolveValue(current, path)
+}
+
+// evaluate executes a template stored at path, first evaluating every
+// template it references
+func (e *templateEvaluator) evaluate(t *deferredTemplate, path []string) (interface{}, error) {
+	key := strings.Join(path, ".")
+	if e.active[key] {
+		cycle := append(append([]string{}, e.evaluating...), key)
+		return nil, positionError(t.file, t.node, "template cycle: %s", strings.Join(cycle, " -> "))
+	}
+	e.active[key] = true
+	e.evaluating = append(e.evaluating, key)
+	defer func() {
+		delete(e.active, key)
+		e.evaluating = e.evaluating[:len(e.evaluating)-1]
+	}()
+
+	tmpl, err := template.New("!template").Funcs(templateFuncs).Option("missingkey=error").Parse(t.text)
+	if err != nil {
+		return nil, positionError(t.file, t.node, "!template: %v", err)
+	}
+
+	for _, field := range templateFields(tmpl.Tree.Root) {
+		if ref := templateDataPath(field, path); ref != nil {
+			if err := e.resolvePath(ref); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e.data(path)); err != nil {
+		return nil, positionError(t.file, t.node, "!template: %v", err)
+	}
+
+	result := buf.String()
+	if e.prov != nil {
+		if p, ok := e.prov[key]; ok {
+			p.Value = result
+		}
+	}
+	return result, nil
+}
+
+// data builds the values a template at path can reference:
+//
+//	.stack       the whole document
+//	.metadata    the stack metadata, with .name and .labels as shortcuts
+//	.spec        the stack spec, with .components as a shortcut
+//	.component   the component containing the template, with .vars as a shortcut
+func (e *templateEvaluator) data(path []string) map[string]interface{} {
+	data := map[string]interface{}{"stack": e.doc}
+
+	if metadata, ok := e.doc["metadata"].(map[string]interface{}); ok {
+		data["metadata"] = metadata
+		if name, ok := metadata["name"]; ok {
+			data["name"] = name
+		}
+		if labels, ok := metadata["labels"]; ok {
+			data["labels"] = labels
+		}
+	}
+
+	if spec, ok := e.doc["spec"].(map[string]interface{}); ok {
+		data["spec"] = spec
+		if components, ok := spec["components"]; ok {
+			data["components"] = components
+		}
+	}
+
+	if componentPath := templateComponentPath(path); componentPath != nil {
+		if component, ok := lookupValue(e.doc, componentPath).(map[string]interface{}); ok {
+			data["component"] = component
+			if vars, ok := component["vars"]; ok {
+				data["vars"] = vars
+			}
+		}
+	}
+
+	return data
+}
+
+// templateComponentPath returns the path of the component containing path,
+// or nil if path is not inside spec.components.<type>.<name>
+func templateComponentPath(path []string) []string {
+	if len(path) < 4 || path[0] != "spec" || path[1] != "components" {
+		return nil
+	}
+	return path[:4]
+}
+
+// templateDataPath maps a field chain used in a template at path to the
+// document path it refers to, or nil if it refers to nothing in the document
+func templateDataPath(field []string, path []string) []string {
+	if len(field) == 0 {
+		return nil
+	}
+
+	var base []string
+	switch field[0] {
+	case "stack":
+		base = []string{}
+	case "metadata":
+		base = []string{"metadata"}
+	case "name":
+		base = []string{"metadata", "name"}
+	case "labels":
+		base = []string{"metadata", "labels"}
+	case "spec":
+		base = []string{"spec"}
+	case "components":
+		base = []string{"spec", "components"}
+	case "component", "vars":
+		base = templateComponentPath(path)
+		if base == nil {
+			return nil
+		}
+		if field[0] == "vars" {
+			base = appendPath(base, "vars")
+		}
+	default:
+		return nil
+	}
+
+	result := append([]string{}, base...)
+	return append(result, field[1:]...)
+}
+
+// templateFields returns every field chain referenced in a template, such as
+// ["labels", "environment"] for {{ .labels.environment }} or {{ $.labels.environment }}
+func templateFields(node parse.Node) [][]string {
+	var fields [][]string
+
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			fields = append(fields, n.Ident)
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				fields = append(fields, n.Ident[1:])
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+
+	return fields
+}
+
+// lookupValue follows a path through maps and lists and returns the value at its end
+func lookupValue(value interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			value = node[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
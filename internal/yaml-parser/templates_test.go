@@ -0,0 +1,171 @@
+package yamlparser
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCaseTags(t *testing.T) {
+	result, err := NewEngine(nil, DefaultOptions()).Decode([]byte("upper: !uppercase Prod\nlower: !lowercase USE1\n"), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if result["upper"] != "PROD" || result["lower"] != "use1" {
+		t.Errorf("Unexpected result: %v", result)
+	}
+
+	_, err = NewEngine(nil, DefaultOptions()).Decode([]byte("upper: !uppercase [a]\n"), "stack.yaml")
+	if err == nil || !strings.Contains(err.Error(), "!uppercase: expected a string") {
+		t.Errorf("Expected type error, got %v", err)
+	}
+}
+
+func TestTemplateTag(t *testing.T) {
+	catalog := testCatalog(t, map[string]string{
+		"anchors.yaml": `
+vpc-defaults: &vpc-defaults
+  name: !template "{{ .labels.environment }}-{{ .vars.region }}-vpc"
+  region: us-east-1
+`,
+	})
+
+	input := `
+metadata:
+  name: plat-dev
+  labels:
+    environment: dev
+spec:
+  components:
+    terraform:
+      vpc:
+        vars:
+          <<: *vpc-defaults
+          region: us-west-2
+          title: !template "{{ .vars.name | upper }} in {{ .name }}"
+          peer: !template "{{ .components.terraform.peer.vars.name }}"
+      peer:
+        vars:
+          name: !template "{{ .labels.environment }}-peer"
+          zones:
+            - !template "{{ .vars.name }}-a"
+`
+	result, err := NewEngine(catalog, DefaultOptions()).Decode([]byte(input), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	components := result["spec"].(map[string]interface{})["components"].(map[string]interface{})["terraform"].(map[string]interface{})
+
+	expectedVPC := map[string]interface{}{
+		"name":   "dev-us-west-2-vpc",
+		"region": "us-west-2",
+		"title":  "DEV-US-WEST-2-VPC in plat-dev",
+		"peer":   "dev-peer",
+	}
+	if vars := components["vpc"].(map[string]interface{})["vars"]; !reflect.DeepEqual(vars, expectedVPC) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expectedVPC, vars)
+	}
+
+	expectedPeer := map[string]interface{}{
+		"name":  "dev-peer",
+		"zones": []interface{}{"dev-peer-a"},
+	}
+	if vars := components["peer"].(map[string]interface{})["vars"]; !reflect.DeepEqual(vars, expectedPeer) {
+		t.Errorf("Expected:\n%#v\nGot:\n%#v", expectedPeer, vars)
+	}
+}
+
+func TestTemplateTagImportedFragment(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"catalog/naming.yaml": "spec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          name: !template \"{{ .labels.team }}-vpc\"\n",
+		"stack.yaml":          "metadata:\n  labels:\n    team: platform\nspec:\n  imports: [catalog/naming]\n",
+	})
+
+	result, prov, err := ParseStackWithProvenance(filepath.Join(dir, "stack.yaml"), filepath.Join(dir, "catalog"), DefaultOptions())
+	if err != nil {
+		t.Fatalf("ParseStackWithProvenance failed: %v", err)
+	}
+
+	vars := lookupValue(result, []string{"spec", "components", "terraform", "vpc", "vars"}).(map[string]interface{})
+	if vars["name"] != "platform-vpc" {
+		t.Errorf("Expected platform-vpc, got %v", vars["name"])
+	}
+
+	p, ok := prov.Lookup("spec", "components", "terraform", "vpc", "vars", "name")
+	if !ok || p.Value != "platform-vpc" {
+		t.Errorf("Expected provenance with the evaluated value, got %+v", p)
+	}
+}
+
+func TestTemplateTagRangeOverSiblings(t *testing.T) {
+	input := `
+spec:
+  components:
+    terraform:
+      vpc:
+        vars:
+          region: us-east-1
+          zone: !template "{{ .vars.region }}a"
+          summary: !template "{{ range $key, $value := .vars }}{{ if ne $key \"summary\" }}{{ $key }}={{ $value }};{{ end }}{{ end }}"
+`
+	result, err := NewEngine(nil, DefaultOptions()).Decode([]byte(input), "stack.yaml")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	// The template sees its evaluated siblings, and is not a cycle of its own
+	vars := lookupValue(result, []string{"spec", "components", "terraform", "vpc", "vars"}).(map[string]interface{})
+	if expected := "region=us-east-1;zone=us-east-1a;"; vars["summary"] != expected {
+		t.Errorf("Expected %q, got %v", expected, vars["summary"])
+	}
+}
+
+func TestTemplateTagErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Cycle",
+			input:    "spec:\n  components:\n    terraform:\n      vpc:\n        vars:\n          a: !template \"{{ .vars.b }}\"\n          b: !template \"{{ .vars.a }}\"\n",
+			expected: "template cycle: spec.components.terraform.vpc.vars.a -> spec.components.terraform.vpc.vars.b -> spec.components.terraform.vpc.vars.a",
+		},
+		{
+			name:     "Self reference",
+			input:    "metadata:\n  name: !template \"{{ .name }}\"\n",
+			expected: "stack.yaml:2:9: template cycle: metadata.name -> metadata.name",
+		},
+		{
+			name:     "Missing key",
+			input:    "metadata:\n  labels: {}\nname: !template \"{{ .labels.missing }}\"\n",
+			expected: "stack.yaml:3:7: !template: ",
+		},
+		{
+			name:     "Vars outside a component",
+			input:    "name: !template \"{{ .vars.name }}\"\n",
+			expected: "!template: ",
+		},
+		{
+			name:     "Syntax error",
+			input:    "name: !template \"{{ .name \"\n",
+			expected: "stack.yaml:1:7: !template: ",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(nil, DefaultOptions()).Decode([]byte(tc.input), "stack.yaml")
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %q", tc.expected, err.Error())
+			}
+		})
+	}
+}